@@ -0,0 +1,118 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ActiveSchema holds the schema currently being served and allows it to be replaced without
+// interrupting requests. Requests that loaded the previous schema continue to use it until they
+// finish, so there's no need to drain them before swapping.
+type ActiveSchema struct {
+	input graphql.SchemaConfig
+
+	// If non-nil, OnSwap is invoked after each successful swap with the fingerprints of the old and
+	// new variants.
+	OnSwap func(oldFingerprint, newFingerprint string)
+
+	mutex   sync.Mutex
+	current atomic.Value
+
+	// If non-nil, newSchema builds the schema for a config instead of PreprocessSchemaConfig and
+	// graphql.NewSchema, returning the preprocessed config along with it. See Rebuilder.
+	newSchema func(cfg *PreprocessorConfig) (graphql.SchemaConfig, graphql.Schema, error)
+
+	// If non-nil, swapped is invoked after each successful swap while the mutex is held. The old
+	// variant is nil if there was none.
+	swapped func(old, new *activeVariant)
+}
+
+type activeVariant struct {
+	schema      *graphql.Schema
+	config      *PreprocessorConfig
+	fingerprint string
+}
+
+// NewActiveSchema builds the variant of the input described by the config.
+func NewActiveSchema(input graphql.SchemaConfig, cfg *PreprocessorConfig) (*ActiveSchema, error) {
+	a := &ActiveSchema{
+		input: input,
+	}
+	variant, err := a.build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.current.Store(variant)
+	return a, nil
+}
+
+func (a *ActiveSchema) build(cfg *PreprocessorConfig) (variant *activeVariant, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to preprocess schema: %v", r)
+		}
+	}()
+	var config graphql.SchemaConfig
+	var schema graphql.Schema
+	if a.newSchema != nil {
+		config, schema, err = a.newSchema(cfg)
+	} else {
+		config = PreprocessSchemaConfig(a.input, cfg)
+		schema, err = graphql.NewSchema(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &activeVariant{
+		schema:      &schema,
+		config:      cfg,
+		fingerprint: fingerprintElements(schemaCoordinates(config)),
+	}, nil
+}
+
+func (a *ActiveSchema) load() *activeVariant {
+	return a.current.Load().(*activeVariant)
+}
+
+// Load returns the current schema. Each request should load it once and use it throughout.
+func (a *ActiveSchema) Load() *graphql.Schema {
+	return a.load().schema
+}
+
+// Swap builds the variant described by the config and, if successful, makes it current. If the
+// build fails, the current schema remains in place.
+func (a *ActiveSchema) Swap(cfg *PreprocessorConfig) error {
+	_, err := a.swap(cfg)
+	return err
+}
+
+// swap implements Swap, returning the new variant.
+func (a *ActiveSchema) swap(cfg *PreprocessorConfig) (*activeVariant, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	variant, err := a.build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	old, _ := a.current.Load().(*activeVariant)
+	a.current.Store(variant)
+	if a.swapped != nil {
+		a.swapped(old, variant)
+	}
+	if a.OnSwap != nil && old != nil {
+		a.OnSwap(old.fingerprint, variant.fingerprint)
+	}
+	return variant, nil
+}
+
+// Execute executes the request against the current schema using the config it was built with.
+// params.Schema is ignored.
+func (a *ActiveSchema) Execute(params graphql.Params) *graphql.Result {
+	variant := a.load()
+	params.Schema = *variant.schema
+	return Execute(variant.config, params)
+}
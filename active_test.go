@@ -0,0 +1,105 @@
+package graphqlapi
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func activeTestInput() graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"beta": &graphql.Field{
+			Type: graphql.Boolean,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return FlagsFromContext(p.Context).BetaFeaturesEnabled, nil
+			},
+		},
+		"secret": BetaField(&graphql.Field{Type: graphql.String}),
+	})
+}
+
+func TestActiveSchemaSwap(t *testing.T) {
+	active, err := NewActiveSchema(activeTestInput(), &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fingerprints [][2]string
+	active.OnSwap = func(oldFingerprint, newFingerprint string) {
+		fingerprints = append(fingerprints, [2]string{oldFingerprint, newFingerprint})
+	}
+	if _, ok := active.Load().QueryType().Fields()["secret"]; ok {
+		t.Error("the initial schema has Query.secret")
+	}
+	if err := active.Swap(&PreprocessorConfig{BetaFeaturesEnabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := active.Load().QueryType().Fields()["secret"]; !ok {
+		t.Error("the swapped schema doesn't have Query.secret")
+	}
+	if len(fingerprints) != 1 || fingerprints[0][0] == fingerprints[0][1] {
+		t.Errorf("unexpected swaps %q", fingerprints)
+	}
+
+	// A failed build leaves the current schema in place.
+	current := active.Load()
+	if err := active.Swap(&PreprocessorConfig{StageSuffixes: map[string]string{"beta": ""}}); err == nil {
+		t.Error("expected an error")
+	}
+	if active.Load() != current || len(fingerprints) != 1 {
+		t.Error("the failed swap replaced the schema")
+	}
+}
+
+func TestActiveSchemaSwapConcurrentWithExecution(t *testing.T) {
+	active, err := NewActiveSchema(activeTestInput(), &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			if err := active.Swap(&PreprocessorConfig{BetaFeaturesEnabled: i%2 == 0}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// The resolver's config and the schema's fields must belong to the same variant.
+				result := active.Execute(graphql.Params{
+					RequestString: `{ beta __type(name: "Query") { fields { name } } }`,
+				})
+				if len(result.Errors) > 0 {
+					t.Error(result.Errors)
+					return
+				}
+				data := result.Data.(map[string]interface{})
+				hasSecret := false
+				for _, field := range data["__type"].(map[string]interface{})["fields"].([]interface{}) {
+					if field.(map[string]interface{})["name"] == "secret" {
+						hasSecret = true
+					}
+				}
+				if data["beta"] != hasSecret {
+					t.Errorf("beta %v, but Query.secret present: %v", data["beta"], hasSecret)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
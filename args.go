@@ -0,0 +1,208 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Args provides typed access to a resolver's arguments. Errors describe the argument's type as
+// declared by the executing (i.e. preprocessed) schema.
+type Args struct {
+	params graphql.ResolveParams
+}
+
+func NewArgs(params graphql.ResolveParams) *Args {
+	return &Args{
+		params: params,
+	}
+}
+
+// Has returns true if the argument has a non-null value, either provided or defaulted.
+func (a *Args) Has(name string) bool {
+	return a.params.Args[name] != nil
+}
+
+func (a *Args) expectedType(name string) string {
+	var fields graphql.FieldDefinitionMap
+	switch parent := a.params.Info.ParentType.(type) {
+	case *graphql.Object:
+		fields = parent.Fields()
+	case *graphql.Interface:
+		fields = parent.Fields()
+	}
+	if def, ok := fields[a.params.Info.FieldName]; ok {
+		for _, arg := range def.Args {
+			if arg.Name() == name {
+				return arg.Type.String()
+			}
+		}
+	}
+	return "unknown"
+}
+
+func (a *Args) value(name string) (interface{}, error) {
+	v := a.params.Args[name]
+	if v == nil {
+		return nil, fmt.Errorf("argument %v of type %v has no value", name, a.expectedType(name))
+	}
+	return v, nil
+}
+
+func (a *Args) typeError(name string, v interface{}) error {
+	return fmt.Errorf("argument %v has a value of type %T, but the schema declares it as %v", name, v, a.expectedType(name))
+}
+
+func (a *Args) Int(name string) (int, error) {
+	v, err := a.value(name)
+	if err != nil {
+		return 0, err
+	}
+	if i, ok := v.(int); ok {
+		return i, nil
+	}
+	return 0, a.typeError(name, v)
+}
+
+func (a *Args) String(name string) (string, error) {
+	v, err := a.value(name)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return "", a.typeError(name, v)
+}
+
+func (a *Args) Bool(name string) (bool, error) {
+	v, err := a.value(name)
+	if err != nil {
+		return false, err
+	}
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, a.typeError(name, v)
+}
+
+// Time returns a DateTime argument. RFC 3339 strings are also accepted, e.g. for defaults.
+func (a *Args) Time(name string) (time.Time, error) {
+	v, err := a.value(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		return *v, nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("argument %v: %v", name, err)
+		}
+		return t, nil
+	}
+	return time.Time{}, a.typeError(name, v)
+}
+
+// Enum stores an enum argument's value in the variable pointed to by target. The enum's value must
+// be assignable or convertible to the target's type.
+func (a *Args) Enum(name string, target interface{}) error {
+	v, err := a.value(name)
+	if err != nil {
+		return err
+	}
+	return a.assign(name, reflect.ValueOf(v), target)
+}
+
+// Input stores an input object argument in the struct pointed to by target. Input fields are
+// matched to struct fields by their json tag or, without one, case-insensitively by name.
+func (a *Args) Input(name string, target interface{}) error {
+	v, err := a.value(name)
+	if err != nil {
+		return err
+	}
+	return a.assign(name, reflect.ValueOf(v), target)
+}
+
+func (a *Args) assign(name string, v reflect.Value, target interface{}) error {
+	dest := reflect.ValueOf(target)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() {
+		return fmt.Errorf("target for argument %v must be a non-nil pointer", name)
+	}
+	if err := assignValue(v, dest.Elem()); err != nil {
+		return fmt.Errorf("argument %v of type %v: %v", name, a.expectedType(name), err)
+	}
+	return nil
+}
+
+func assignValue(v reflect.Value, dest reflect.Value) error {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Ptr && v.Type() != dest.Type() {
+		elem := reflect.New(dest.Type().Elem())
+		if err := assignValue(v, elem.Elem()); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	}
+	switch {
+	case v.Type().AssignableTo(dest.Type()):
+		dest.Set(v)
+	case v.Kind() == reflect.Map && dest.Kind() == reflect.Struct:
+		fields := structFieldsByInputName(dest.Type())
+		for _, key := range v.MapKeys() {
+			i, ok := fields[strings.ToLower(fmt.Sprint(key.Interface()))]
+			if !ok {
+				continue
+			}
+			if err := assignValue(v.MapIndex(key), dest.Field(i)); err != nil {
+				return fmt.Errorf("%v: %v", key.Interface(), err)
+			}
+		}
+	case v.Kind() == reflect.Slice && dest.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(dest.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := assignValue(v.Index(i), slice.Index(i)); err != nil {
+				return fmt.Errorf("[%v]: %v", i, err)
+			}
+		}
+		dest.Set(slice)
+	case v.Type().ConvertibleTo(dest.Type()) && v.Kind() != reflect.String && dest.Kind() != reflect.String:
+		dest.Set(v.Convert(dest.Type()))
+	case v.Kind() == reflect.String && dest.Kind() == reflect.String:
+		dest.SetString(v.String())
+	default:
+		return fmt.Errorf("cannot store a %v in a %v", v.Type(), dest.Type())
+	}
+	return nil
+}
+
+func structFieldsByInputName(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields[strings.ToLower(name)] = i
+	}
+	return fields
+}
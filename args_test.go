@@ -0,0 +1,164 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+type argsTestRange struct {
+	Min float64
+	Max *float64
+}
+
+type argsTestFilter struct {
+	Limit int      `json:"limit"`
+	Tags  []string `json:"tags"`
+	Range *argsTestRange
+	Owner string `json:"-"`
+}
+
+type argsTestStatus string
+
+func argsTestSchema(t *testing.T, resolve func(args *Args)) graphql.Schema {
+	rangeType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Range",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"min": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"max": &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"limit": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"tags":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"range": &graphql.InputObjectFieldConfig{Type: rangeType},
+			"owner": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	status := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"OPEN":   &graphql.EnumValueConfig{Value: "open"},
+			"CLOSED": &graphql.EnumValueConfig{Value: "closed"},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"search": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"first":    &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+				"query":    &graphql.ArgumentConfig{Type: graphql.String},
+				"archived": &graphql.ArgumentConfig{Type: graphql.Boolean},
+				"after":    &graphql.ArgumentConfig{Type: graphql.DateTime},
+				"status":   &graphql.ArgumentConfig{Type: status},
+				"filter":   &graphql.ArgumentConfig{Type: filter},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				resolve(NewArgs(p))
+				return "ok", nil
+			},
+		},
+	})
+	preprocessed, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(preprocessed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func argsTestQuery(t *testing.T, schema graphql.Schema, query string, variables map[string]interface{}) {
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  query,
+		VariableValues: variables,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("%v: %v", query, result.Errors)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	var args *Args
+	schema := argsTestSchema(t, func(a *Args) { args = a })
+
+	argsTestQuery(t, schema, `{
+		search(query: "widgets", archived: true, after: "2018-01-02T03:04:05Z", status: CLOSED, filter: {
+			limit: 5, tags: ["a", "b"], range: {min: 1, max: 2.5}, owner: "me"
+		})
+	}`, nil)
+	if first, err := args.Int("first"); err != nil || first != 10 {
+		t.Errorf("first: %v, %v", first, err)
+	}
+	if query, err := args.String("query"); err != nil || query != "widgets" {
+		t.Errorf("query: %v, %v", query, err)
+	}
+	if archived, err := args.Bool("archived"); err != nil || !archived {
+		t.Errorf("archived: %v, %v", archived, err)
+	}
+	if after, err := args.Time("after"); err != nil || !after.Equal(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("after: %v, %v", after, err)
+	}
+	var status argsTestStatus
+	if err := args.Enum("status", &status); err != nil || status != "closed" {
+		t.Errorf("status: %v, %v", status, err)
+	}
+	var filter argsTestFilter
+	if err := args.Input("filter", &filter); err != nil {
+		t.Fatal(err)
+	}
+	max := 2.5
+	expected := argsTestFilter{Limit: 5, Tags: []string{"a", "b"}, Range: &argsTestRange{Min: 1, Max: &max}}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+
+	// Type mismatches are errors rather than panics, and describe the schema's type.
+	if _, err := args.Int("query"); err == nil || err.Error() != "argument query has a value of type string, but the schema declares it as String" {
+		t.Errorf("unexpected error %v", err)
+	}
+	var n int
+	if err := args.Input("filter", &n); err == nil || !strings.Contains(err.Error(), "argument filter of type Filter") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := args.Input("filter", filter); err == nil || !strings.Contains(err.Error(), "must be a non-nil pointer") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestArgsMissingAndNull(t *testing.T) {
+	var args *Args
+	schema := argsTestSchema(t, func(a *Args) { args = a })
+
+	argsTestQuery(t, schema, `query($query: String, $filter: Filter) { search(query: $query, filter: $filter) }`, map[string]interface{}{
+		"query":  nil,
+		"filter": map[string]interface{}{"limit": 1, "range": nil},
+	})
+	for _, name := range []string{"query", "archived", "after", "status"} {
+		if args.Has(name) {
+			t.Errorf("%v has a value", name)
+		}
+	}
+	if _, err := args.String("query"); err == nil || err.Error() != "argument query of type String has no value" {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := args.Time("after"); err == nil || err.Error() != "argument after of type DateTime has no value" {
+		t.Errorf("unexpected error %v", err)
+	}
+	// graphql-go drops null input fields, so they're left unset like omitted ones.
+	var filter argsTestFilter
+	if err := args.Input("filter", &filter); err != nil {
+		t.Fatal(err)
+	}
+	if filter.Limit != 1 || filter.Range != nil || filter.Tags != nil {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+}
@@ -0,0 +1,133 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/lexer"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Signature is a hex-encoded HMAC-SHA256 of an artifact.
+type Signature string
+
+var ErrInvalidSignature = errors.New("invalid artifact signature")
+
+// SignatureExtension is appended to an artifact's path to get the path of its signature.
+const SignatureExtension = ".sig"
+
+func SignArtifact(data []byte, key []byte) (Signature, error) {
+	if len(key) == 0 {
+		return "", fmt.Errorf("a signing key is required")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return Signature(hex.EncodeToString(mac.Sum(nil))), nil
+}
+
+func VerifyArtifact(data []byte, sig Signature, key []byte) error {
+	expected, err := SignArtifact(data, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(string(sig)))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// CanonicalSDL returns a canonical form of the given SDL that only depends on its tokens, so
+// differences in whitespace, commas, or comments don't affect it. Descriptions are preserved.
+func CanonicalSDL(sdl []byte) ([]byte, error) {
+	lex := lexer.Lex(source.NewSource(&source.Source{
+		Body: sdl,
+		Name: "SDL",
+	}))
+	var buf bytes.Buffer
+	for {
+		token, err := lex(0)
+		if err != nil {
+			return nil, err
+		}
+		if token.Kind == lexer.EOF {
+			break
+		}
+		fmt.Fprintf(&buf, "%v %q\n", token.Kind, token.Value)
+	}
+	return buf.Bytes(), nil
+}
+
+// SignSDL signs the canonical form of the given SDL.
+func SignSDL(sdl []byte, key []byte) (Signature, error) {
+	canonical, err := CanonicalSDL(sdl)
+	if err != nil {
+		return "", err
+	}
+	return SignArtifact(canonical, key)
+}
+
+// VerifySDL verifies a signature created by SignSDL. Reformatting the SDL doesn't invalidate the
+// signature.
+func VerifySDL(sdl []byte, sig Signature, key []byte) error {
+	canonical, err := CanonicalSDL(sdl)
+	if err != nil {
+		return err
+	}
+	return VerifyArtifact(canonical, sig, key)
+}
+
+func isSDLPath(path string) bool {
+	switch filepath.Ext(path) {
+	case ".graphql", ".graphqls":
+		return true
+	}
+	return false
+}
+
+// WriteSignedArtifact writes the artifact to the given path and its signature to a sidecar file.
+// Artifacts with a .graphql or .graphqls extension are signed via SignSDL.
+func WriteSignedArtifact(path string, data []byte, key []byte) error {
+	var sig Signature
+	var err error
+	if isSDLPath(path) {
+		sig, err = SignSDL(data, key)
+	} else {
+		sig, err = SignArtifact(data, key)
+	}
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+SignatureExtension, []byte(sig+"\n"), 0644)
+}
+
+// LoadSignedArtifact reads an artifact written by WriteSignedArtifact, returning an error if its
+// signature can't be verified.
+func LoadSignedArtifact(path string, key []byte) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ioutil.ReadFile(path + SignatureExtension)
+	if err != nil {
+		return nil, err
+	}
+	if isSDLPath(path) {
+		err = VerifySDL(data, Signature(sig), key)
+	} else {
+		err = VerifyArtifact(data, Signature(sig), key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,84 @@
+package graphqlapi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const artifactsTestSDL = `type Query {
+	# The widget with the given ID.
+	widget(id: ID!): Widget
+}
+
+"A widget."
+type Widget {
+	id: ID
+	name: String
+}
+`
+
+func TestSignedSDL(t *testing.T) {
+	key := []byte("secret")
+	sig, err := SignSDL([]byte(artifactsTestSDL), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reformatted := `type Query { widget(id: ID!): Widget }
+"A widget." type Widget { id: ID, name: String }`
+	if err := VerifySDL([]byte(reformatted), sig, key); err != nil {
+		t.Errorf("reformatted SDL: %v", err)
+	}
+
+	for name, tampered := range map[string]string{
+		"type":        strings.Replace(artifactsTestSDL, "name: String", "name: Int", 1),
+		"description": strings.Replace(artifactsTestSDL, "A widget.", "A gadget.", 1),
+	} {
+		if err := VerifySDL([]byte(tampered), sig, key); err != ErrInvalidSignature {
+			t.Errorf("tampered %v: expected an invalid signature, got %v", name, err)
+		}
+	}
+	if err := VerifySDL([]byte(artifactsTestSDL), sig, []byte("other")); err != ErrInvalidSignature {
+		t.Errorf("wrong key: expected an invalid signature, got %v", err)
+	}
+}
+
+func TestSignedArtifactFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := []byte("secret")
+	sdlPath := filepath.Join(dir, "schema.graphql")
+	jsonPath := filepath.Join(dir, "schema.json")
+	if err := WriteSignedArtifact(sdlPath, []byte(artifactsTestSDL), key); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSignedArtifact(jsonPath, []byte(`{"types":[]}`), key); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{sdlPath, jsonPath} {
+		if _, err := LoadSignedArtifact(path, key); err != nil {
+			t.Errorf("%v: %v", path, err)
+		}
+	}
+
+	// Only SDL is canonicalized before verification.
+	if err := ioutil.WriteFile(sdlPath, []byte(strings.Replace(artifactsTestSDL, "\t", "    ", -1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSignedArtifact(sdlPath, key); err != nil {
+		t.Errorf("reformatted SDL: %v", err)
+	}
+	if err := ioutil.WriteFile(jsonPath, []byte(`{"types": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSignedArtifact(jsonPath, key); err == nil || !strings.Contains(err.Error(), ErrInvalidSignature.Error()) {
+		t.Errorf("reformatted JSON: expected an invalid signature, got %v", err)
+	}
+}
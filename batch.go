@@ -0,0 +1,210 @@
+package graphqlapi
+
+import (
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// PreprocessSchemaConfigs is like calling PreprocessSchemaConfigE for each config, but types that
+// are preprocessed identically under every config are built once and shared by the results. A
+// type is shared only if nothing in its transitive closure is conditional: no conditionals,
+// fallbacks, scalar variants, conditional enum values, PreprocessableTypes, or policies matching
+// it or its fields. Interfaces also require each of their implementations to be shared, and root
+// types are never shared.
+//
+// Like SchemaCache, this assumes that the configs differ only in the state that distinguishes
+// variants: their environment, beta and API version settings, roles, and flags. Hooks invoked
+// while preprocessing a shared type are only invoked for the first config that reaches it. The
+// resolvers of shared types add the executed variant's flags to the context, identifying it by its
+// query type. The results' thunks are evaluated before returning.
+func PreprocessSchemaConfigs(input graphql.SchemaConfig, configs []*PreprocessorConfig) ([]graphql.SchemaConfig, error) {
+	checkInputLimits(input, nil)
+	shareable := shareableTypes(input, configs)
+	sharing := newTypeSharing()
+	var results []graphql.SchemaConfig
+	for _, config := range configs {
+		result, err := sharing.preprocess(input, config, shareable)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// typeSharing holds the types shared by variants of an input, along with the configs of the
+// variants, keyed by their query types. It's safe for concurrent use.
+type typeSharing struct {
+	mutex    sync.RWMutex
+	types    map[string]graphql.Type
+	variants map[*graphql.Object]*PreprocessorConfig
+}
+
+func newTypeSharing() *typeSharing {
+	return &typeSharing{
+		types:    map[string]graphql.Type{},
+		variants: map[*graphql.Object]*PreprocessorConfig{},
+	}
+}
+
+// preprocess preprocesses the input like PreprocessSchemaConfigE, reusing shared types and sharing
+// the shareable types it builds.
+func (s *typeSharing) preprocess(input graphql.SchemaConfig, config *PreprocessorConfig, shareable map[string]bool) (result graphql.SchemaConfig, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = graphql.SchemaConfig{}, newPreprocessingError(nil, r)
+		}
+	}()
+	p := newPreprocessor(config, nil)
+	p.sharing = s
+	s.mutex.RLock()
+	for key, t := range s.types {
+		if shareable[key] {
+			p.PreprocessedTypes[key] = t
+		}
+	}
+	s.mutex.RUnlock()
+
+	result = p.preprocessSchemaConfig(input)
+	for _, t := range schemaTypes(result) {
+		if err := t.Error(); err != nil {
+			return graphql.SchemaConfig{}, &PreprocessingError{
+				Path: []string{t.Name()},
+				Err:  err,
+			}
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, t := range p.PreprocessedTypes {
+		if _, ok := s.types[key]; !ok && t != nil && shareable[key] {
+			s.types[key] = t
+		}
+	}
+	if result.Query != nil {
+		s.variants[result.Query] = config
+	}
+	return result, nil
+}
+
+func (s *typeSharing) lookup(key string) graphql.Type {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.types[key]
+}
+
+func (s *typeSharing) variantConfig(query *graphql.Object) *PreprocessorConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.variants[query]
+}
+
+// forget stops tracking the variant with the given query type.
+func (s *typeSharing) forget(query *graphql.Object) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.variants, query)
+}
+
+// shareableTypes returns the names of the input's types whose preprocessing doesn't depend on any
+// condition. See PreprocessSchemaConfigs.
+func shareableTypes(input graphql.SchemaConfig, configs []*PreprocessorConfig) map[string]bool {
+	matchesPolicy := func(coordinate string) bool {
+		for _, config := range configs {
+			for _, policy := range config.Policies {
+				if matchCoordinate(policy.CoordinatePattern, coordinate) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	types := schemaTypes(input)
+	shareable := map[string]bool{}
+	references := map[string][]string{}
+	for _, t := range types {
+		name := t.Name()
+		ok := !matchesPolicy(name)
+		reference := func(t graphql.Type) {
+			if hasConditionals(t) {
+				ok = false
+			} else {
+				references[name] = append(references[name], namedType(t).Name())
+			}
+		}
+		referenceFields := func(fields graphql.FieldDefinitionMap) {
+			for fieldName, def := range fields {
+				ok = ok && !matchesPolicy(name+"."+fieldName)
+				reference(def.Type)
+				for _, arg := range def.Args {
+					reference(arg.Type)
+				}
+			}
+		}
+		switch t := t.(type) {
+		case *graphql.Object:
+			for _, iface := range t.Interfaces() {
+				reference(iface)
+			}
+			referenceFields(t.Fields())
+		case *graphql.Interface:
+			referenceFields(t.Fields())
+			for _, other := range types {
+				if obj, isObject := other.(*graphql.Object); isObject && implements(obj, t) {
+					reference(obj)
+				}
+			}
+		case *graphql.Union:
+			for _, obj := range t.Types() {
+				reference(obj)
+			}
+		case *graphql.InputObject:
+			for _, f := range t.Fields() {
+				reference(f.Type)
+			}
+		case *graphql.Enum:
+			for _, value := range t.Values() {
+				if _, isConditional := value.Value.(ConditionalValue); isConditional {
+					ok = false
+				}
+			}
+		case *graphql.Scalar:
+		default:
+			ok = false
+		}
+		shareable[name] = ok
+	}
+	for _, obj := range []*graphql.Object{input.Query, input.Mutation, input.Subscription} {
+		if obj != nil {
+			shareable[obj.Name()] = false
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for name, ok := range shareable {
+			if !ok {
+				continue
+			}
+			for _, reference := range references[name] {
+				if !shareable[reference] {
+					shareable[name], changed = false, true
+					break
+				}
+			}
+		}
+	}
+	return shareable
+}
+
+func implements(obj *graphql.Object, iface *graphql.Interface) bool {
+	for _, implemented := range obj.Interfaces() {
+		if implemented == iface {
+			return true
+		}
+	}
+	return false
+}
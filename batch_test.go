@@ -0,0 +1,120 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// batchTestInput returns an input with a chain of unconditional objects and a gated widget.
+func batchTestInput(objects int) graphql.SchemaConfig {
+	var next graphql.Output = graphql.String
+	for i := objects - 1; i >= 0; i-- {
+		next = graphql.NewObject(graphql.ObjectConfig{
+			Name: fmt.Sprintf("Object%v", i),
+			Fields: graphql.Fields{
+				"id":   &graphql.Field{Type: graphql.ID},
+				"next": &graphql.Field{Type: next},
+			},
+		})
+	}
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.ID},
+			"beta":     &graphql.Field{Type: Beta(graphql.String)},
+			"internal": &graphql.Field{Type: Internal(graphql.String)},
+		},
+	})
+	return testInput(graphql.Fields{
+		"first": &graphql.Field{Type: next},
+		"widget": &graphql.Field{
+			Type: widget,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1"}, nil
+			},
+		},
+		"beta": &graphql.Field{
+			Type: graphql.Boolean,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return FlagsFromContext(p.Context).IsEnabled("beta"), nil
+			},
+		},
+	})
+}
+
+func batchTestConfigs() []*PreprocessorConfig {
+	var configs []*PreprocessorConfig
+	for _, beta := range []bool{false, true} {
+		for _, internal := range []bool{false, true} {
+			configs = append(configs, &PreprocessorConfig{BetaFeaturesEnabled: beta, InternalFeaturesEnabled: internal})
+		}
+	}
+	return configs
+}
+
+func TestPreprocessSchemaConfigs(t *testing.T) {
+	input := batchTestInput(10)
+	configs := batchTestConfigs()
+	results, err := PreprocessSchemaConfigs(input, configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(configs) {
+		t.Fatalf("%v results for %v configs", len(results), len(configs))
+	}
+
+	for i, result := range results {
+		expected, err := PreprocessSchemaConfigE(input, configs[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equal, differences := SchemaConfigsEqual(result, expected); !equal {
+			t.Errorf("config %v: the result differs from independent preprocessing: %v", i, differences)
+		}
+
+		// Condition-free types are shared, but the roots and gated types aren't.
+		fields := result.Query.Fields()
+		if fields["first"].Type != results[0].Query.Fields()["first"].Type {
+			t.Errorf("config %v: Object0 isn't shared", i)
+		}
+		if i > 0 && (result.Query == results[0].Query || fields["widget"].Type == results[0].Query.Fields()["widget"].Type) {
+			t.Errorf("config %v: a conditional type is shared", i)
+		}
+
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ beta widget { id } }"})
+		data := map[string]interface{}{"beta": configs[i].BetaFeaturesEnabled, "widget": map[string]interface{}{"id": "1"}}
+		if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, data) {
+			t.Errorf("config %v: unexpected response %v", i, response)
+		}
+	}
+}
+
+func BenchmarkPreprocessSchemaConfigs(b *testing.B) {
+	input := batchTestInput(200)
+	configs := batchTestConfigs()
+	b.Run("Batched", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := PreprocessSchemaConfigs(input, configs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Independent", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, config := range configs {
+				if _, err := PreprocessSchemaConfigE(input, config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type VariantComparison struct {
+	Input   graphql.SchemaConfig
+	A, B    *PreprocessorConfig
+	Queries []string
+
+	// If non-nil, these are invoked before each execution to create the root object and context.
+	RootObject func() map[string]interface{}
+	Context    func() context.Context
+
+	// ExpectedToDiffer lists field coordinates (e.g. "Query.widget") whose values may differ
+	// between the variants. Differences within their subtrees are ignored too.
+	ExpectedToDiffer []string
+}
+
+type VariantDifference struct {
+	Query string
+
+	// Path is the response path of the differing value, or "errors" if the variants produced
+	// different errors.
+	Path string
+
+	A, B interface{}
+}
+
+func (d VariantDifference) String() string {
+	return fmt.Sprintf("%v: %v vs %v", d.Path, d.A, d.B)
+}
+
+// CompareVariants executes each query against the variants built from A and B and reports values
+// that differ.
+func CompareVariants(c VariantComparison) ([]VariantDifference, error) {
+	schemaA, err := graphql.NewSchema(PreprocessSchemaConfig(c.Input, c.A))
+	if err != nil {
+		return nil, err
+	}
+	schemaB, err := graphql.NewSchema(PreprocessSchemaConfig(c.Input, c.B))
+	if err != nil {
+		return nil, err
+	}
+
+	expectedToDiffer := map[string]bool{}
+	for _, coordinate := range c.ExpectedToDiffer {
+		expectedToDiffer[coordinate] = true
+	}
+
+	var differences []VariantDifference
+	for _, query := range c.Queries {
+		document, err := parser.Parse(parser.ParseParams{Source: query})
+		if err != nil {
+			return nil, err
+		}
+		coordinates := responseCoordinates(document, &schemaA)
+
+		execute := func(schema graphql.Schema) *graphql.Result {
+			params := graphql.Params{
+				Schema:        schema,
+				RequestString: query,
+			}
+			if c.RootObject != nil {
+				params.RootObject = c.RootObject()
+			}
+			if c.Context != nil {
+				params.Context = c.Context()
+			}
+			return graphql.Do(params)
+		}
+		a, b := execute(schemaA), execute(schemaB)
+
+		if errorsA, errorsB := errorMessages(a), errorMessages(b); !reflect.DeepEqual(errorsA, errorsB) {
+			differences = append(differences, VariantDifference{
+				Query: query,
+				Path:  "errors",
+				A:     errorsA,
+				B:     errorsB,
+			})
+		}
+
+		diffValues("", "", a.Data, b.Data, func(path, keyPath string, a, b interface{}) {
+			for _, coordinate := range coordinates[keyPath] {
+				if expectedToDiffer[coordinate] {
+					return
+				}
+			}
+			differences = append(differences, VariantDifference{
+				Query: query,
+				Path:  path,
+				A:     a,
+				B:     b,
+			})
+		}, func(keyPath string) bool {
+			for _, coordinate := range coordinates[keyPath] {
+				if expectedToDiffer[coordinate] {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return differences, nil
+}
+
+func errorMessages(result *graphql.Result) []string {
+	messages := []string{}
+	for _, err := range result.Errors {
+		messages = append(messages, err.Message)
+	}
+	sort.Strings(messages)
+	return messages
+}
+
+// diffValues reports differing leaves of two response trees. keyPath is the path without list
+// indices, which is what responseCoordinates maps to field coordinates.
+func diffValues(path, keyPath string, a, b interface{}, report func(path, keyPath string, a, b interface{}), skip func(keyPath string) bool) {
+	if keyPath != "" && skip(keyPath) {
+		return
+	}
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			keys := map[string]bool{}
+			for k := range a {
+				keys[k] = true
+			}
+			for k := range b {
+				keys[k] = true
+			}
+			sorted := make([]string, 0, len(keys))
+			for k := range keys {
+				sorted = append(sorted, k)
+			}
+			sort.Strings(sorted)
+			for _, k := range sorted {
+				diffValues(joinPath(path, k), joinPath(keyPath, k), a[k], b[k], report, skip)
+			}
+			return
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok && len(a) == len(b) {
+			for i := range a {
+				diffValues(fmt.Sprintf("%v[%v]", path, i), keyPath, a[i], b[i], report, skip)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(a, b) {
+		report(path, keyPath, a, b)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// responseCoordinates maps dot-separated response key paths to the coordinates of the fields that
+// may produce them.
+func responseCoordinates(document *ast.Document, schema *graphql.Schema) map[string][]string {
+	coordinates := map[string][]string{}
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var visit func(keyPath string, parent graphql.Type, selectionSet *ast.SelectionSet, visited map[string]bool)
+	visit = func(keyPath string, parent graphql.Type, selectionSet *ast.SelectionSet, visited map[string]bool) {
+		if selectionSet == nil || parent == nil {
+			return
+		}
+		for _, selection := range selectionSet.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				name := selection.Name.Value
+				key := name
+				if selection.Alias != nil {
+					key = selection.Alias.Value
+				}
+				var fields graphql.FieldDefinitionMap
+				switch parent := parent.(type) {
+				case *graphql.Object:
+					fields = parent.Fields()
+				case *graphql.Interface:
+					fields = parent.Fields()
+				}
+				path := joinPath(keyPath, key)
+				coordinates[path] = append(coordinates[path], parent.Name()+"."+name)
+				if def, ok := fields[name]; ok {
+					visit(path, namedType(def.Type), selection.SelectionSet, visited)
+				}
+			case *ast.InlineFragment:
+				t := parent
+				if selection.TypeCondition != nil {
+					t = schema.Type(selection.TypeCondition.Name.Value)
+				}
+				visit(keyPath, t, selection.SelectionSet, visited)
+			case *ast.FragmentSpread:
+				name := selection.Name.Value
+				if fragment, ok := fragments[name]; ok && !visited[name] {
+					visited[name] = true
+					visit(keyPath, schema.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet, visited)
+					delete(visited, name)
+				}
+			}
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		var root *graphql.Object
+		switch strings.ToLower(operation.Operation) {
+		case "query":
+			root = schema.QueryType()
+		case "mutation":
+			root = schema.MutationType()
+		case "subscription":
+			root = schema.SubscriptionType()
+		}
+		if root != nil {
+			visit("", root, operation.SelectionSet, map[string]bool{})
+		}
+	}
+	return coordinates
+}
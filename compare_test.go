@@ -0,0 +1,110 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestCompareVariants(t *testing.T) {
+	beta := func(p graphql.ResolveParams) bool {
+		return FlagsFromContext(p.Context).IsEnabled("beta")
+	}
+	label := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Label",
+		Fields: graphql.Fields{
+			"text": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if beta(p) {
+						return "new", nil
+					}
+					return "old", nil
+				},
+			},
+		},
+	})
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+			"price": &graphql.Field{
+				Type: graphql.Float,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if beta(p) && p.Source.(map[string]interface{})["id"] == "2" {
+						return 2.5, nil
+					}
+					return 2.0, nil
+				},
+			},
+			"label": &graphql.Field{
+				Type: label,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					return struct{}{}, nil
+				},
+			},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"widgets": &graphql.Field{
+			Type: graphql.NewList(widget),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return []interface{}{
+					map[string]interface{}{"id": "1"},
+					map[string]interface{}{"id": "2"},
+				}, nil
+			},
+		},
+	})
+
+	differences, err := CompareVariants(VariantComparison{
+		Input: input,
+		A:     &PreprocessorConfig{},
+		B:     &PreprocessorConfig{BetaFeaturesEnabled: true},
+		Queries: []string{
+			`{ widgets { id price label { text } } }`,
+			`{ items: widgets { ...W } } fragment W on Widget { cost: price }`,
+		},
+		ExpectedToDiffer: []string{"Widget.label"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, difference := range differences {
+		paths = append(paths, difference.String())
+	}
+	expected := []string{
+		"widgets[1].price: 2 vs 2.5",
+		"items[1].cost: 2 vs 2.5",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("unexpected differences %q", paths)
+	}
+}
+
+func TestCompareVariantsErrors(t *testing.T) {
+	input := testInput(graphql.Fields{
+		"id":     &graphql.Field{Type: graphql.ID},
+		"secret": BetaField(&graphql.Field{Type: graphql.String}),
+	})
+	differences, err := CompareVariants(VariantComparison{
+		Input:   input,
+		A:       &PreprocessorConfig{},
+		B:       &PreprocessorConfig{BetaFeaturesEnabled: true},
+		Queries: []string{`{ id }`, `{ secret }`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Validation fails in A, so its data is missing too.
+	if len(differences) != 2 || differences[0].Path != "errors" || differences[1].Path != "" {
+		t.Fatalf("unexpected differences %v", differences)
+	}
+	for _, difference := range differences {
+		if difference.Query != `{ secret }` {
+			t.Errorf("unexpected difference for %v", difference.Query)
+		}
+	}
+}
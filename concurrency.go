@@ -0,0 +1,55 @@
+package graphqlapi
+
+import (
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// inputThunks holds a *sync.Once for each object, interface, and input object whose thunks have
+// been evaluated by evaluateThunks. graphql-go caches the result of each thunk without
+// synchronization, so goroutines preprocessing the same input would otherwise race. Each type is
+// guarded separately, and only once it's reached, so preprocessing stays lazy and goroutines only
+// wait on each other when they reach the same unevaluated type.
+var inputThunks sync.Map
+
+// evaluateThunks evaluates the thunks of an object, interface, or input object exactly once across
+// goroutines. Preprocessing calls it before reading the fields or interfaces of an input type, and
+// afterwards they can be read concurrently. Other types have no thunks and are ignored.
+func evaluateThunks(t graphql.Type) {
+	switch t.(type) {
+	case *graphql.Object, *graphql.Interface, *graphql.InputObject:
+	default:
+		return
+	}
+	once, ok := inputThunks.Load(t)
+	if !ok {
+		once, _ = inputThunks.LoadOrStore(t, new(sync.Once))
+	}
+	once.(*sync.Once).Do(func() {
+		switch t := t.(type) {
+		case *graphql.Object:
+			t.Interfaces()
+			t.Fields()
+		case *graphql.Interface:
+			t.Fields()
+		case *graphql.InputObject:
+			t.Fields()
+		}
+	})
+}
+
+// checkInputLimits walks every type reachable from the input, including types only referenced by
+// directive arguments, enforcing the config's limits or the defaults if it's nil. It's used before
+// analyses that walk the whole input anyway, so that runaway input types fail like preprocessing
+// them would.
+func checkInputLimits(input graphql.SchemaConfig, config *PreprocessorConfig) {
+	types := append([]graphql.Type(nil), input.Types...)
+	for _, d := range input.Directives {
+		for _, arg := range d.Args {
+			types = append(types, arg.Type)
+		}
+	}
+	input.Types = types
+	walkSchemaTypes(input, walkLimits(config))
+}
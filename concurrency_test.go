@@ -0,0 +1,104 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// concurrencyTestInput returns a recursive input whose thunks haven't been evaluated.
+func concurrencyTestInput() graphql.SchemaConfig {
+	var user *graphql.Object
+	user = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":      &graphql.Field{Type: graphql.ID},
+				"friends": &graphql.Field{Type: graphql.NewList(user)},
+				"email":   &graphql.Field{Type: Beta(graphql.String)},
+			}
+		}),
+	})
+	return testInput(graphql.Fields{
+		"user": &graphql.Field{
+			Type: user,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1", "email": "a@example.com"}, nil
+			},
+		},
+	})
+}
+
+func TestConcurrentPreprocessing(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		input := concurrencyTestInput()
+		shared := NewPreprocessor(&PreprocessorConfig{BetaFeaturesEnabled: true})
+		errs := make(chan error, 8)
+		var wg sync.WaitGroup
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func(beta bool) {
+				defer wg.Done()
+				var result graphql.SchemaConfig
+				if beta {
+					result = shared.Preprocess(input)
+					if shared.Report() == nil {
+						errs <- fmt.Errorf("the report is nil")
+						return
+					}
+				} else {
+					var err error
+					if result, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{}); err != nil {
+						errs <- err
+						return
+					}
+				}
+				schema, err := graphql.NewSchema(result)
+				if err != nil {
+					errs <- err
+					return
+				}
+				response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ user { id friends { id } } }"})
+				if len(response.Errors) > 0 {
+					errs <- fmt.Errorf("beta %v: %v", beta, response.Errors)
+					return
+				}
+				if _, ok := result.Query.Fields()["user"].Type.(*graphql.Object).Fields()["email"]; ok != beta {
+					errs <- fmt.Errorf("beta %v: User.email present: %v", beta, ok)
+				}
+			}(j%2 == 0)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+	}
+}
+
+func TestInputThunksAreLazy(t *testing.T) {
+	evaluated := false
+	unreachable := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Unreachable",
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			evaluated = true
+			return graphql.InputObjectConfigFieldMap{
+				"id": &graphql.InputObjectFieldConfig{Type: graphql.ID},
+			}
+		}),
+	})
+	input := concurrencyTestInput()
+	input.Types = []graphql.Type{unreachable}
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{PruneUnreachable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graphql.NewSchema(result); err != nil {
+		t.Fatal(err)
+	}
+	if evaluated {
+		t.Errorf("the thunk of an unreachable type was evaluated")
+	}
+}
@@ -0,0 +1,226 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ConditionRegistry maps names to conditions so that they can be referenced by name, e.g. via
+// Conditional.ConditionName or Policy.ConditionName. In addition to registered conditions, the
+// following names are built in:
+//
+//   - "alpha", "beta", "experimental", and "internal" are true if the flag of the same name is
+//     enabled.
+//   - "flag:<name>" is true if the named flag is enabled.
+//   - "stage:<stage>" is true if the named release stage, e.g. "beta", is enabled.
+//   - "audience:<role>" is true if the schema's audience has the role. See HasRole.
+type ConditionRegistry struct {
+	conditions map[string]func(*PreprocessorConfig) bool
+}
+
+func NewConditionRegistry() *ConditionRegistry {
+	return &ConditionRegistry{
+		conditions: map[string]func(*PreprocessorConfig) bool{},
+	}
+}
+
+// Register adds a named condition, replacing any existing condition with the same name.
+func (r *ConditionRegistry) Register(name string, condition func(*PreprocessorConfig) bool) {
+	r.conditions[name] = condition
+}
+
+// Lookup returns the named condition. It's safe to call on a nil registry, in which case only the
+// built-in conditions are available.
+func (r *ConditionRegistry) Lookup(name string) (func(*PreprocessorConfig) bool, bool) {
+	if r != nil {
+		if condition, ok := r.conditions[name]; ok {
+			return condition, true
+		}
+	}
+	if _, ok := stages[name]; ok {
+		return func(cfg *PreprocessorConfig) bool {
+			return cfg.IsEnabled(name)
+		}, true
+	}
+	if flag := strings.TrimPrefix(name, "flag:"); flag != name && flag != "" {
+		return func(cfg *PreprocessorConfig) bool {
+			return cfg.IsEnabled(flag)
+		}, true
+	}
+	if stage := strings.TrimPrefix(name, "stage:"); stage != name {
+		if _, ok := stages[stage]; ok {
+			return func(cfg *PreprocessorConfig) bool {
+				return cfg.IsEnabled(stage)
+			}, true
+		}
+	}
+	if role := strings.TrimPrefix(name, "audience:"); role != name && role != "" {
+		return requireRole(role), true
+	}
+	return nil, false
+}
+
+// evaluateCondition evaluates either the given condition or, if it's nil, the named condition.
+// The referrer describes the element being evaluated for error messages.
+func (p *preprocessor) evaluateCondition(referrer string, condition func(*PreprocessorConfig) bool, name string) bool {
+	if condition == nil {
+		var ok bool
+		if condition, ok = p.Config.Conditions.Lookup(name); !ok {
+			panic(fmt.Errorf("%v references unknown condition %q", referrer, name))
+		}
+	}
+	return p.callCondition(referrer, func() bool {
+		return condition(p.Config)
+	})
+}
+
+// callCondition invokes a condition, handling any panic according to the config's
+// FailClosedOnConditionPanic option.
+func (p *preprocessor) callCondition(referrer string, condition func() bool) (enabled bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("condition for %v panicked: %v", referrer, r)
+			if !p.Config.FailClosedOnConditionPanic {
+				panic(err)
+			}
+			p.warn(err)
+			enabled = false
+		}
+	}()
+	return condition()
+}
+
+// AllOf returns a condition that's true if every given condition is true. Every condition is
+// evaluated so that the flags read by the result don't depend on the config.
+func AllOf(conditions ...func(*PreprocessorConfig) bool) func(*PreprocessorConfig) bool {
+	return func(cfg *PreprocessorConfig) bool {
+		result := true
+		for _, condition := range conditions {
+			if !condition(cfg) {
+				result = false
+			}
+		}
+		return result
+	}
+}
+
+// AnyOf returns a condition that's true if any of the given conditions is true. Like AllOf, it
+// evaluates every condition.
+func AnyOf(conditions ...func(*PreprocessorConfig) bool) func(*PreprocessorConfig) bool {
+	return func(cfg *PreprocessorConfig) bool {
+		result := false
+		for _, condition := range conditions {
+			if condition(cfg) {
+				result = true
+			}
+		}
+		return result
+	}
+}
+
+// Not returns a condition that's true if the given condition is false.
+func Not(condition func(*PreprocessorConfig) bool) func(*PreprocessorConfig) bool {
+	return func(cfg *PreprocessorConfig) bool {
+		return !condition(cfg)
+	}
+}
+
+// ConditionContext describes the element a condition is evaluated for. See
+// Conditional.ConditionWithContext.
+type ConditionContext struct {
+	// The name and kind of the named type within the conditional, e.g. "Widget" and "object".
+	// For enum values, these describe the enum.
+	Name string
+	Kind string
+
+	// The coordinate of the field, argument, input field, or enum value being gated, e.g.
+	// "Query.widget" or "Query.widget(id:)", and the coordinate of its parent, e.g. "Query" or
+	// "Query.widget". Both are empty if the conditional isn't the type of such an element, e.g.
+	// if it's in SchemaConfig.Types.
+	Coordinate string
+	Parent     string
+}
+
+func newConditionContext(t graphql.Type, coordinate string) ConditionContext {
+	named := namedType(unwrapConditionals(t))
+	return ConditionContext{
+		Name:       named.Name(),
+		Kind:       typeKind(named),
+		Coordinate: coordinate,
+		Parent:     parentCoordinate(coordinate),
+	}
+}
+
+// typeKind returns the kind of a named type as it's described in reports, e.g. "object".
+func typeKind(t graphql.Type) string {
+	switch t.(type) {
+	case *graphql.Object:
+		return "object"
+	case *graphql.Interface:
+		return "interface"
+	case *graphql.Union:
+		return "union"
+	case *graphql.InputObject:
+		return "input"
+	case *graphql.Enum:
+		return "enum"
+	case *graphql.Scalar, *ScalarVariants:
+		return "scalar"
+	}
+	return ""
+}
+
+// parentCoordinate returns the coordinate of the element containing the given one, e.g. "Query"
+// for "Query.widget" and "Query.widget" for "Query.widget(id:)".
+func parentCoordinate(coordinate string) string {
+	if i := strings.LastIndex(coordinate, "("); i >= 0 {
+		return coordinate[:i]
+	}
+	if i := strings.LastIndex(coordinate, "."); i >= 0 {
+		return coordinate[:i]
+	}
+	return ""
+}
+
+// conditionFor returns the conditional's condition, binding ConditionWithContext to the context.
+func (c *Conditional) conditionFor(ctx ConditionContext) func(*PreprocessorConfig) bool {
+	if c.Condition != nil || c.ConditionWithContext == nil {
+		return c.Condition
+	}
+	return func(cfg *PreprocessorConfig) bool {
+		return c.ConditionWithContext(cfg, ctx)
+	}
+}
+
+// conditionAt returns the condition of a conditional that's the type, or part of the type, of the
+// element at the coordinate.
+func (p *preprocessor) conditionAt(c *Conditional, coordinate string) func(*PreprocessorConfig) bool {
+	return c.conditionFor(newConditionContext(c.OfType, coordinate))
+}
+
+// at sets the coordinate whose type is being preprocessed and returns a function that restores
+// the previous one.
+func (p *preprocessor) at(coordinate string) func() {
+	previous := p.coordinate
+	p.coordinate = coordinate
+	return func() {
+		p.coordinate = previous
+	}
+}
+
+// preprocessTypeAt preprocesses the type of the element at the coordinate, returning the element's
+// deprecation if a conditional in the type was disabled with DeprecateWhenDisabled. If the type is
+// removed, p.removedWithinNonNull records whether that happened within a non-null wrapper.
+func (p *preprocessor) preprocessTypeAt(coordinate string, t graphql.Type) (graphql.Type, *Deprecation, bool) {
+	defer p.at(coordinate)()
+	previous, previousRemoval := p.deprecation, p.removalWithinNonNull
+	p.deprecation, p.removalWithinNonNull = nil, false
+	defer func() {
+		p.removedWithinNonNull = p.removalWithinNonNull
+		p.deprecation, p.removalWithinNonNull = previous, previousRemoval
+	}()
+	result, ok := p.preprocessType(t)
+	return result, p.deprecation, ok
+}
@@ -0,0 +1,319 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestConditionRegistryBuiltIns(t *testing.T) {
+	var registry *ConditionRegistry
+	for name, expected := range map[string]bool{
+		"beta":             true,
+		"flag:payments":    true,
+		"flag:refunds":     false,
+		"stage:beta":       true,
+		"stage:alpha":      false,
+		"audience:admin":   true,
+		"audience:support": false,
+	} {
+		condition, ok := registry.Lookup(name)
+		if !ok {
+			t.Errorf("%v isn't built in", name)
+			continue
+		}
+		cfg := &PreprocessorConfig{
+			BetaFeaturesEnabled: true,
+			Flags:               map[string]bool{"payments": true},
+			Roles:               []string{"admin"},
+		}
+		if enabled := condition(cfg); enabled != expected {
+			t.Errorf("%v is %v", name, enabled)
+		}
+	}
+	for _, name := range []string{"stage:", "stage:payments", "audience:", "flag:", "payments"} {
+		if _, ok := registry.Lookup(name); ok {
+			t.Errorf("%v shouldn't be built in", name)
+		}
+	}
+}
+
+func TestConditionNames(t *testing.T) {
+	invoice := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Invoice",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.ID},
+		"invoice": &graphql.Field{Type: &Conditional{OfType: invoice, Suffix: "Billing", ConditionName: "billing"}},
+		"refunds": &graphql.Field{Type: graphql.Int},
+	})
+	policies := []Policy{{Name: "refunds", CoordinatePattern: "Query.refunds", ConditionName: "flag:refunds"}}
+
+	conditions := NewConditionRegistry()
+	conditions.Register("billing", func(cfg *PreprocessorConfig) bool {
+		return cfg.HasRole("finance")
+	})
+	for _, enabled := range []bool{false, true} {
+		config := &PreprocessorConfig{
+			Conditions: conditions,
+			Policies:   policies,
+			Flags:      map[string]bool{"refunds": enabled},
+		}
+		if enabled {
+			config.Roles = []string{"finance"}
+		}
+		result, err := PreprocessSchemaConfigE(input, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := result.Query.Fields()
+		if _, ok := fields["invoice"]; ok != enabled {
+			t.Errorf("enabled %v: Query.invoice present: %v", enabled, ok)
+		}
+		if _, ok := fields["refunds"]; ok != enabled {
+			t.Errorf("enabled %v: Query.refunds present: %v", enabled, ok)
+		}
+	}
+
+	_, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Policies: policies})
+	if err == nil || !strings.Contains(err.Error(), `references unknown condition "billing"`) {
+		t.Errorf("expected an unknown condition error, got %v", err)
+	}
+	_, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		Conditions: conditions,
+		Policies:   []Policy{{Name: "refunds", CoordinatePattern: "Query.refunds", ConditionName: "refunds"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `policy refunds references unknown condition "refunds"`) {
+		t.Errorf("expected an unknown condition error, got %v", err)
+	}
+}
+
+func TestPanickingConditions(t *testing.T) {
+	panics := func(cfg *PreprocessorConfig) bool {
+		var enabled map[string]bool
+		enabled["payments"] = true
+		return true
+	}
+	_, file, line, _ := runtime.Caller(0)
+	input := testInput(graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.ID},
+		"balance": &graphql.Field{Type: NewConditional(graphql.Float, "Payments", panics)},
+	})
+	callsite := fmt.Sprintf("%v:%v", file, line+3)
+
+	_, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{})
+	if err == nil || !strings.Contains(err.Error(), callsite) || !strings.Contains(err.Error(), "panicked: assignment to entry in nil map") {
+		t.Errorf("expected an error naming %v, got %v", callsite, err)
+	}
+
+	var warnings []error
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		FailClosedOnConditionPanic: true,
+		OnWarning:                  func(err error) { warnings = append(warnings, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Query.Fields()["balance"]; ok {
+		t.Error("the field with a panicking condition was kept")
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), callsite) {
+		t.Errorf("expected a warning naming %v, got %v", callsite, warnings)
+	}
+
+	// Policies and enum values are covered too.
+	input.Query = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	input.Types = []graphql.Type{
+		graphql.NewEnum(graphql.EnumConfig{
+			Name: "Currency",
+			Values: graphql.EnumValueConfigMap{
+				"USD": &graphql.EnumValueConfig{Value: "usd"},
+				"EUR": NewConditionalEnumValue(&graphql.EnumValueConfig{Value: "eur"}, panics),
+			},
+		}),
+	}
+	_, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{})
+	if err == nil || !strings.Contains(err.Error(), "Currency.EUR") {
+		t.Errorf("expected an error naming Currency.EUR, got %v", err)
+	}
+	_, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		Policies: []Policy{{Name: "payments", CoordinatePattern: "Query.*", Condition: panics}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "policy payments") {
+		t.Errorf("expected an error naming the policy, got %v", err)
+	}
+}
+
+func TestConditionCombinators(t *testing.T) {
+	flag := func(name string) func(*PreprocessorConfig) bool {
+		return func(cfg *PreprocessorConfig) bool { return cfg.IsEnabled(name) }
+	}
+	beta := flag("beta")
+	// Enabled with both a and b, or without c.
+	nested := AnyOf(AllOf(flag("a"), flag("b")), Not(flag("c")))
+
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	color := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED":  &graphql.EnumValueConfig{Value: "red"},
+			"BLUE": NewConditionalEnumValue(&graphql.EnumValueConfig{Value: "blue"}, nested),
+			"GRAY": NewConditionalEnumValue(&graphql.EnumValueConfig{Value: "gray"}, Not(nested)),
+		},
+	})
+	legacy := NewConditional(widget, "Legacy", Not(beta))
+	legacy.RenameWhenEnabled = true
+	input := testInput(graphql.Fields{
+		"color":        &graphql.Field{Type: color},
+		"nested":       &graphql.Field{Type: NewConditional(graphql.String, "Nested", nested)},
+		"widget":       &graphql.Field{Type: Beta(widget)},
+		"legacyWidget": &graphql.Field{Type: legacy},
+	})
+
+	for _, tc := range []struct {
+		flags    map[string]bool
+		nested   bool
+		expected string
+	}{
+		{map[string]bool{}, true, "legacyWidget"},
+		{map[string]bool{"c": true}, false, "legacyWidget"},
+		{map[string]bool{"a": true, "c": true}, false, "legacyWidget"},
+		{map[string]bool{"a": true, "b": true, "c": true}, true, "legacyWidget"},
+		{map[string]bool{"beta": true, "c": true}, false, "widget"},
+	} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Flags: tc.flags})
+		if err != nil {
+			t.Fatalf("flags %v: %v", tc.flags, err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("flags %v: %v", tc.flags, err)
+		}
+		fields := result.Query.Fields()
+		if _, ok := fields["nested"]; ok != tc.nested {
+			t.Errorf("flags %v: Query.nested present: %v", tc.flags, ok)
+		}
+		values := map[string]bool{}
+		for _, value := range fields["color"].Type.(*graphql.Enum).Values() {
+			values[value.Name] = true
+		}
+		if values["BLUE"] != tc.nested || values["GRAY"] == tc.nested || !values["RED"] {
+			t.Errorf("flags %v: unexpected values %v", tc.flags, values)
+		}
+
+		// Not(beta) and beta wrap the same type, and exactly one of them is kept.
+		_, hasWidget := fields["widget"]
+		_, hasLegacy := fields["legacyWidget"]
+		if hasWidget == hasLegacy || hasWidget != (tc.expected == "widget") {
+			t.Errorf("flags %v: Query.widget present: %v, Query.legacyWidget present: %v", tc.flags, hasWidget, hasLegacy)
+		}
+		if hasLegacy && fields["legacyWidget"].Type.Name() != "WidgetLegacy" {
+			t.Errorf("flags %v: Query.legacyWidget has type %v", tc.flags, fields["legacyWidget"].Type)
+		}
+	}
+}
+
+// conditionsTestInternal gates any element whose type or coordinate is named *Internal on the
+// internal flag.
+func conditionsTestInternal(t graphql.Type, contexts map[string]ConditionContext) *Conditional {
+	return NewConditionalWithContext(t, "Gated", func(cfg *PreprocessorConfig, ctx ConditionContext) bool {
+		contexts[ctx.Coordinate] = ctx
+		return cfg.IsEnabled("internal") || !strings.HasSuffix(ctx.Name, "Internal") && !strings.HasSuffix(ctx.Coordinate, "Internal")
+	})
+}
+
+func TestConditionWithContext(t *testing.T) {
+	contexts := map[string]ConditionContext{}
+	auditInternal := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AuditInternal",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	status := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"OPEN": &graphql.EnumValueConfig{Value: "open"},
+			"HELD_INTERNAL": NewConditionalEnumValueWithContext(&graphql.EnumValueConfig{Value: "held"}, func(cfg *PreprocessorConfig, ctx ConditionContext) bool {
+				contexts[ctx.Coordinate] = ctx
+				return cfg.IsEnabled("internal")
+			}),
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"status":        &graphql.InputObjectFieldConfig{Type: status},
+			"ownerInternal": &graphql.InputObjectFieldConfig{Type: conditionsTestInternal(graphql.String, contexts)},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"name":  &graphql.Field{Type: conditionsTestInternal(graphql.String, contexts)},
+		"audit": &graphql.Field{Type: conditionsTestInternal(auditInternal, contexts)},
+		"notesInternal": &graphql.Field{
+			Type: conditionsTestInternal(graphql.NewList(graphql.String), contexts),
+		},
+		"widgets": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: conditionsTestInternal(filter, contexts)},
+			},
+		},
+	})
+
+	for _, internal := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Flags: map[string]bool{"internal": internal}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []string
+		for name, field := range result.Query.Fields() {
+			kept = append(kept, "Query."+name)
+			for _, arg := range field.Args {
+				for name := range arg.Type.(*graphql.InputObject).Fields() {
+					kept = append(kept, "Filter."+name)
+				}
+				for _, value := range arg.Type.(*graphql.InputObject).Fields()["status"].Type.(*graphql.Enum).Values() {
+					kept = append(kept, "Status."+value.Name)
+				}
+			}
+		}
+		sort.Strings(kept)
+		expected := []string{"Filter.status", "Query.name", "Query.widgets", "Status.OPEN"}
+		if internal {
+			expected = []string{"Filter.ownerInternal", "Filter.status", "Query.audit", "Query.name", "Query.notesInternal", "Query.widgets", "Status.HELD_INTERNAL", "Status.OPEN"}
+		}
+		if !reflect.DeepEqual(kept, expected) {
+			t.Errorf("internal %v: unexpected elements %v", internal, kept)
+		}
+	}
+
+	expected := map[string]ConditionContext{
+		"Query.name":             {Name: "String", Kind: "scalar", Coordinate: "Query.name", Parent: "Query"},
+		"Query.audit":            {Name: "AuditInternal", Kind: "object", Coordinate: "Query.audit", Parent: "Query"},
+		"Query.notesInternal":    {Name: "String", Kind: "scalar", Coordinate: "Query.notesInternal", Parent: "Query"},
+		"Query.widgets(filter:)": {Name: "Filter", Kind: "input", Coordinate: "Query.widgets(filter:)", Parent: "Query.widgets"},
+		"Filter.ownerInternal":   {Name: "String", Kind: "scalar", Coordinate: "Filter.ownerInternal", Parent: "Filter"},
+		"Status.HELD_INTERNAL":   {Name: "Status", Kind: "enum", Coordinate: "Status.HELD_INTERNAL", Parent: "Status"},
+	}
+	if !reflect.DeepEqual(contexts, expected) {
+		t.Errorf("unexpected contexts %+v", contexts)
+	}
+}
@@ -0,0 +1,48 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// InterfaceFieldGateMismatches preprocesses the input and reports fields that are gated
+// differently on an interface and on an object implementing it. Each mismatch is described by a
+// human-readable string.
+func InterfaceFieldGateMismatches(input graphql.SchemaConfig, config *PreprocessorConfig) []string {
+	preprocessed := map[string]graphql.Type{}
+	for _, t := range schemaTypes(PreprocessSchemaConfig(input, config)) {
+		preprocessed[t.Name()] = t
+	}
+
+	var mismatches []string
+	for _, t := range schemaTypes(input) {
+		obj, ok := t.(*graphql.Object)
+		if !ok {
+			continue
+		}
+		newObj, ok := preprocessed[obj.Name()].(*graphql.Object)
+		if !ok {
+			continue
+		}
+		for _, iface := range obj.Interfaces() {
+			newIface, ok := preprocessed[iface.Name()].(*graphql.Interface)
+			if !ok {
+				continue
+			}
+			for _, name := range fieldNames(iface.Fields()) {
+				if _, ok := obj.Fields()[name]; !ok {
+					continue
+				}
+				_, ifaceHasField := newIface.Fields()[name]
+				_, objHasField := newObj.Fields()[name]
+				if ifaceHasField && !objHasField {
+					mismatches = append(mismatches, fmt.Sprintf("%v.%v is removed but %v.%v is kept", obj.Name(), name, iface.Name(), name))
+				} else if !ifaceHasField && objHasField {
+					mismatches = append(mismatches, fmt.Sprintf("%v.%v is kept but %v.%v is removed", obj.Name(), name, iface.Name(), name))
+				}
+			}
+		}
+	}
+	return mismatches
+}
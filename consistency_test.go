@@ -0,0 +1,67 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func interfaceGateTestInput() graphql.SchemaConfig {
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.ID},
+			"secret": BetaField(&graphql.Field{Type: graphql.String}),
+			"zeta":   BetaField(&graphql.Field{Type: graphql.String}),
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+			return nil
+		},
+	})
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.ID},
+			"secret": &graphql.Field{Type: graphql.String},
+			"zeta":   &graphql.Field{Type: graphql.String},
+		},
+	})
+	return testInput(graphql.Fields{
+		"node": &graphql.Field{Type: node},
+	}, widget)
+}
+
+func TestPropagateInlineInterfaceFieldGates(t *testing.T) {
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(interfaceGateTestInput(), &PreprocessorConfig{
+			BetaFeaturesEnabled:          beta,
+			PropagateInterfaceFieldGates: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		widget := result.Types[0].(*graphql.Object)
+		if _, ok := widget.Fields()["secret"]; ok != beta {
+			t.Errorf("beta %v: Widget.secret present: %v", beta, ok)
+		}
+	}
+}
+
+func TestInterfaceFieldGateMismatches(t *testing.T) {
+	mismatches := InterfaceFieldGateMismatches(interfaceGateTestInput(), &PreprocessorConfig{})
+	expected := []string{
+		"Widget.secret is kept but Node.secret is removed",
+		"Widget.zeta is kept but Node.zeta is removed",
+	}
+	if !reflect.DeepEqual(mismatches, expected) {
+		t.Errorf("unexpected mismatches: %q", mismatches)
+	}
+	if mismatches := InterfaceFieldGateMismatches(interfaceGateTestInput(), &PreprocessorConfig{BetaFeaturesEnabled: true}); len(mismatches) > 0 {
+		t.Errorf("unexpected mismatches with beta enabled: %q", mismatches)
+	}
+}
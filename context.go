@@ -0,0 +1,39 @@
+package graphqlapi
+
+import (
+	"context"
+	"net/http"
+)
+
+type flagsContextKey struct{}
+
+// WithFlags returns a copy of ctx that carries the given config. Resolvers and middleware should
+// use FlagsFromContext to retrieve it.
+func WithFlags(ctx context.Context, cfg *PreprocessorConfig) context.Context {
+	return context.WithValue(ctx, flagsContextKey{}, cfg)
+}
+
+// FlagsFromContext returns the config carried by ctx, or nil if there isn't one. Wrapped resolvers
+// are always given a context carrying a config: if the request didn't provide one, it's the config
+// the schema was preprocessed with.
+func FlagsFromContext(ctx context.Context) *PreprocessorConfig {
+	if ctx == nil {
+		return nil
+	}
+	cfg, _ := ctx.Value(flagsContextKey{}).(*PreprocessorConfig)
+	return cfg
+}
+
+// FlagsMiddleware returns net/http middleware that derives a config for each request and injects
+// it into the request's context. It's compatible with chi, and with echo via echo.WrapMiddleware.
+// If derive returns nil, the request is passed through unmodified.
+func FlagsMiddleware(derive func(r *http.Request) *PreprocessorConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg := derive(r); cfg != nil {
+				r = r.WithContext(WithFlags(r.Context(), cfg))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestWrappedResolverContext(t *testing.T) {
+	var seen *PreprocessorConfig
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					seen = FlagsFromContext(p.Context)
+					return "name", nil
+				},
+			},
+		},
+	})
+	config := &PreprocessorConfig{}
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve := result.Query.Fields()["name"].Resolve
+
+	ctx := context.Background()
+	if _, err := resolve(graphql.ResolveParams{Context: ctx}); err != nil {
+		t.Fatal(err)
+	}
+	if seen != config {
+		t.Fatalf("the resolver saw %p instead of %p", seen, config)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		resolve(graphql.ResolveParams{Context: ctx})
+	}); allocs > 0 {
+		t.Errorf("resolving with the same context allocated %v times", allocs)
+	}
+
+	injected := &PreprocessorConfig{BetaFeaturesEnabled: true}
+	resolve(graphql.ResolveParams{Context: WithFlags(ctx, injected)})
+	if seen != injected {
+		t.Errorf("the resolver didn't see the injected config")
+	}
+}
+
+func TestResolverFlagsFromContext(t *testing.T) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"loader": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if FlagsFromContext(p.Context).IsEnabled("beta") {
+						return "beta loader", nil
+					}
+					return "loader", nil
+				},
+			},
+		},
+	})
+	for beta, expected := range map[bool]string{false: "loader", true: "beta loader"} {
+		result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ loader }"})
+		if len(response.Errors) > 0 || response.Data.(map[string]interface{})["loader"] != expected {
+			t.Errorf("beta %v: unexpected result %v", beta, response)
+		}
+
+		// Resolvers called without a context still get the config.
+		if v, err := result.Query.Fields()["loader"].Resolve(graphql.ResolveParams{}); err != nil || v != expected {
+			t.Errorf("beta %v: resolving without a context returned %v, %v", beta, v, err)
+		}
+	}
+}
+
+func TestFlagsMiddlewareConcurrency(t *testing.T) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"role": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return FlagsFromContext(p.Context).Roles[0], nil
+				},
+			},
+		},
+	})
+	preprocessed, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(preprocessed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := FlagsMiddleware(func(r *http.Request) *PreprocessorConfig {
+		return &PreprocessorConfig{Roles: []string{r.Header.Get("Role")}}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Execute(FlagsFromContext(r.Context()), graphql.Params{
+			Schema:        schema,
+			RequestString: "{ role }",
+			Context:       r.Context(),
+		}))
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(role string) {
+			defer wg.Done()
+			r := httptest.NewRequest("POST", "/graphql", nil)
+			r.Header.Set("Role", role)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			var result struct {
+				Data struct {
+					Role string
+				}
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Data.Role != role {
+				t.Errorf("request for %v: %s", role, w.Body.Bytes())
+			}
+		}(fmt.Sprintf("role%v", i))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,119 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// normalizeDefault converts a default value to the shape graphql-go expects for the given
+// preprocessed input type. Structs become maps keyed by input field name. Map keys that don't
+// correspond to a field of the preprocessed type are dropped with a warning. Float and ID defaults
+// are coerced like variables of those types. Enum defaults that aren't values of the preprocessed
+// enum, e.g. because they were removed by conditionals, are cleared with a warning, or cause a
+// panic in strict mode. Cleared list elements and input fields are omitted.
+func (p *preprocessor) normalizeDefault(coordinate string, t graphql.Type, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		// graphql-go uses defaults as-is, so without this an int default for a Float argument
+		// reaches resolvers as an int, unlike the equivalent literal.
+		if t == graphql.Float || t == graphql.ID {
+			if v := t.ParseValue(value); v != nil {
+				return v
+			}
+		}
+	case *graphql.Enum:
+		for _, v := range t.Values() {
+			if reflect.DeepEqual(v.Value, value) {
+				return value
+			}
+		}
+		err := fmt.Errorf("the default value for %v isn't a value of %v in this variant", coordinate, t.Name())
+		if p.Config.Strict {
+			panic(err)
+		}
+		p.warn(err)
+		return nil
+	case *graphql.NonNull:
+		return p.normalizeDefault(coordinate, t.OfType, value)
+	case *graphql.List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return p.normalizeDefault(coordinate, t.OfType, value)
+		}
+		list := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			element := v.Index(i).Interface()
+			if normalized := p.normalizeDefault(coordinate, t.OfType, element); normalized != nil || element == nil {
+				list = append(list, normalized)
+			}
+		}
+		return list
+	case *graphql.InputObject:
+		fields := p.inputFieldTypes(t)
+		v := reflect.Indirect(reflect.ValueOf(value))
+		switch v.Kind() {
+		case reflect.Struct:
+			byName := structFieldsByInputName(v.Type())
+			m := map[string]interface{}{}
+			for name, field := range fields {
+				i, ok := byName[strings.ToLower(name)]
+				if !ok {
+					continue
+				}
+				fv := v.Field(i)
+				switch fv.Kind() {
+				case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+					if fv.IsNil() {
+						continue
+					}
+				}
+				// Optional fields are typically pointers, but graphql-go expects scalar values.
+				if fv.Kind() == reflect.Ptr {
+					fv = fv.Elem()
+				}
+				if normalized := p.normalizeDefault(t.Name()+"."+name, field, fv.Interface()); normalized != nil {
+					m[name] = normalized
+				}
+			}
+			return m
+		case reflect.Map:
+			m := map[string]interface{}{}
+			for _, key := range v.MapKeys() {
+				name := fmt.Sprint(key.Interface())
+				field, ok := fields[name]
+				if !ok {
+					p.warn(fmt.Errorf("the default value for %v references %v.%v, which doesn't exist in this variant", coordinate, t.Name(), name))
+					continue
+				}
+				element := v.MapIndex(key).Interface()
+				if normalized := p.normalizeDefault(t.Name()+"."+name, field, element); normalized != nil || element == nil {
+					m[name] = normalized
+				}
+			}
+			return m
+		}
+	}
+	return value
+}
+
+// inputFieldTypes returns the types of the preprocessed input object's fields by name. If the
+// object's fields are being defined, they're taken from the definition in progress.
+func (p *preprocessor) inputFieldTypes(t *graphql.InputObject) map[string]graphql.Input {
+	types := map[string]graphql.Input{}
+	if fields, ok := p.definingInputs[t.Name()]; ok {
+		for name, f := range fields {
+			types[name] = f.Type
+		}
+		return types
+	}
+	for name, f := range t.Fields() {
+		types[name] = f.Type
+	}
+	return types
+}
@@ -0,0 +1,274 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestNormalizeDefaultRecursiveInput(t *testing.T) {
+	var filter *graphql.InputObject
+	filter = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			return graphql.InputObjectConfigFieldMap{
+				"limit": &graphql.InputObjectFieldConfig{
+					Type: graphql.Float,
+				},
+				"next": &graphql.InputObjectFieldConfig{
+					Type: filter,
+					DefaultValue: map[string]interface{}{
+						"limit": 1,
+						"next": map[string]interface{}{
+							"limit": 2,
+						},
+					},
+				},
+			}
+		}),
+	})
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"widgets": &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{
+						Type: filter,
+					},
+				},
+			},
+		},
+	})
+
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	preprocessed := result.Query.Fields()["widgets"].Args[0].Type.(*graphql.InputObject)
+	next := preprocessed.Fields()["next"]
+	if next.Type != preprocessed {
+		t.Fatalf("next has type %v", next.Type)
+	}
+	value := next.DefaultValue.(map[string]interface{})
+	if limit, ok := value["limit"].(float64); !ok || limit != 1 {
+		t.Errorf("limit wasn't normalized: %#v", value["limit"])
+	}
+	if limit, ok := value["next"].(map[string]interface{})["limit"].(float64); !ok || limit != 2 {
+		t.Errorf("nested limit wasn't normalized: %#v", value["next"])
+	}
+}
+
+type defaultsTestFilter struct {
+	Limit  int      `json:"limit"`
+	Tags   []string `json:"tags"`
+	Secret *string
+}
+
+func TestNormalizeDefaultsInExecution(t *testing.T) {
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"limit":  &graphql.InputObjectFieldConfig{Type: graphql.Float},
+			"tags":   &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"secret": &graphql.InputObjectFieldConfig{Type: Beta(graphql.String)},
+		},
+	})
+	secret := "s"
+	var args map[string]interface{}
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"widgets": &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"byStruct": &graphql.ArgumentConfig{
+						Type:         filter,
+						DefaultValue: defaultsTestFilter{Limit: 5, Tags: []string{"a"}, Secret: &secret},
+					},
+					"byMap": &graphql.ArgumentConfig{
+						Type:         filter,
+						DefaultValue: map[string]interface{}{"limit": 5, "secret": "s"},
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					args = p.Args
+					return "ok", nil
+				},
+			},
+		},
+	})
+
+	for _, beta := range []bool{false, true} {
+		var warnings []error
+		result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{
+			BetaFeaturesEnabled: beta,
+			OnWarning:           func(err error) { warnings = append(warnings, err) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ widgets }"}); len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+
+		byStruct := map[string]interface{}{"limit": 5.0, "tags": []interface{}{"a"}}
+		byMap := map[string]interface{}{"limit": 5.0}
+		if beta {
+			byStruct["secret"] = "s"
+			byMap["secret"] = "s"
+		}
+		if expected := map[string]interface{}{"byStruct": byStruct, "byMap": byMap}; !reflect.DeepEqual(args, expected) {
+			t.Errorf("beta %v: unexpected args %#v", beta, args)
+		}
+		if beta && len(warnings) > 0 {
+			t.Errorf("unexpected warnings %v", warnings)
+		} else if !beta && (len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "references Filter.secret, which doesn't exist in this variant")) {
+			t.Errorf("expected a warning about Filter.secret, got %v", warnings)
+		}
+	}
+}
+
+func TestScalarLiteralsAndDefaults(t *testing.T) {
+	var args map[string]interface{}
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"scalars": &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"float":         &graphql.ArgumentConfig{Type: graphql.Float},
+					"id":            &graphql.ArgumentConfig{Type: graphql.ID},
+					"at":            &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"defaultFloat":  &graphql.ArgumentConfig{Type: graphql.Float, DefaultValue: 1},
+					"defaultFloats": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.Float), DefaultValue: []interface{}{1, 2}},
+					"defaultID":     &graphql.ArgumentConfig{Type: graphql.ID, DefaultValue: 12},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					args = p.Args
+					return "ok", nil
+				},
+			},
+		},
+	})
+
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ scalars(float: 1, id: 12, at: "2017-01-02T03:04:05Z") }`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	expected := map[string]interface{}{
+		"float":         1.0,
+		"id":            "12",
+		"at":            time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC),
+		"defaultFloat":  1.0,
+		"defaultFloats": []interface{}{1.0, 2.0},
+		"defaultID":     "12",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected args %#v", args)
+	}
+}
+
+func TestRemovedEnumValueDefaults(t *testing.T) {
+	color := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED":  &graphql.EnumValueConfig{Value: "red"},
+			"BLUE": BetaEnum(&graphql.EnumValueConfig{Value: "blue"}),
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"color": &graphql.InputObjectFieldConfig{Type: color, DefaultValue: "blue"},
+		},
+	})
+	var args map[string]interface{}
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"paint": &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"color":  &graphql.ArgumentConfig{Type: color, DefaultValue: "blue"},
+					"colors": &graphql.ArgumentConfig{Type: graphql.NewList(color), DefaultValue: []string{"red", "blue"}},
+					"filter": &graphql.ArgumentConfig{Type: filter, DefaultValue: map[string]interface{}{"color": "blue"}},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					args = p.Args
+					return "ok", nil
+				},
+			},
+		},
+	})
+	input := graphql.SchemaConfig{Query: query}
+
+	for _, beta := range []bool{false, true} {
+		var warnings []string
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			BetaFeaturesEnabled: beta,
+			OnWarning:           func(err error) { warnings = append(warnings, err.Error()) },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ paint }"}); len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+
+		expected := map[string]interface{}{
+			"color":  "blue",
+			"colors": []interface{}{"red", "blue"},
+			"filter": map[string]interface{}{"color": "blue"},
+		}
+		var expectedWarnings []string
+		if !beta {
+			expected = map[string]interface{}{
+				"colors": []interface{}{"red"},
+				"filter": map[string]interface{}{},
+			}
+			// Filter.color is reported for its own default and for the one in Query.paint(filter:).
+			expectedWarnings = []string{
+				"the default value for Filter.color isn't a value of Color in this variant",
+				"the default value for Filter.color isn't a value of Color in this variant",
+				"the default value for Query.paint(color:) isn't a value of Color in this variant",
+				"the default value for Query.paint(colors:) isn't a value of Color in this variant",
+			}
+		}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("beta %v: unexpected args %#v", beta, args)
+		}
+		sort.Strings(warnings)
+		if !reflect.DeepEqual(warnings, expectedWarnings) {
+			t.Errorf("beta %v: unexpected warnings %q", beta, warnings)
+		}
+	}
+
+	_, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "isn't a value of Color in this variant") {
+		t.Errorf("expected a strict error, got %v", err)
+	}
+}
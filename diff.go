@@ -0,0 +1,259 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// SchemaElement is a type, field, argument, input field, or enum value.
+type SchemaElement struct {
+	Coordinate string
+
+	// The element's kind for types and enum values (e.g. "object" or "enum value"), or its type
+	// for fields, arguments, and input fields.
+	Type string
+}
+
+// SchemaElementChange is an element present under both configs with different types.
+type SchemaElementChange struct {
+	Coordinate string
+	A, B       string
+}
+
+// SchemaDiff describes how the schema preprocessed under one config differs from the schema
+// preprocessed under another. Elements are sorted by coordinate.
+type SchemaDiff struct {
+	Added   []SchemaElement
+	Removed []SchemaElement
+	Changed []SchemaElementChange
+}
+
+func (d SchemaDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d SchemaDiff) String() string {
+	var lines []string
+	for _, e := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %v: %v", e.Coordinate, e.Type))
+	}
+	for _, e := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %v: %v", e.Coordinate, e.Type))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %v: %v -> %v", c.Coordinate, c.A, c.B))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffSchemaConfigs preprocesses the input under both configs and returns the elements added,
+// removed, or changed by going from a to b.
+func DiffSchemaConfigs(input graphql.SchemaConfig, a, b *PreprocessorConfig) (SchemaDiff, error) {
+	var diff SchemaDiff
+	resultA, err := PreprocessSchemaConfigE(input, a)
+	if err != nil {
+		return diff, err
+	}
+	resultB, err := PreprocessSchemaConfigE(input, b)
+	if err != nil {
+		return diff, err
+	}
+	elementsA, elementsB := schemaCoordinates(resultA), schemaCoordinates(resultB)
+	added, removed, changed := diffElements(elementsA, elementsB)
+	for _, coordinate := range added {
+		diff.Added = append(diff.Added, SchemaElement{
+			Coordinate: coordinate,
+			Type:       elementsB[coordinate],
+		})
+	}
+	for _, coordinate := range removed {
+		diff.Removed = append(diff.Removed, SchemaElement{
+			Coordinate: coordinate,
+			Type:       elementsA[coordinate],
+		})
+	}
+	for _, coordinate := range changed {
+		diff.Changed = append(diff.Changed, SchemaElementChange{
+			Coordinate: coordinate,
+			A:          elementsA[coordinate],
+			B:          elementsB[coordinate],
+		})
+	}
+	return diff, nil
+}
+
+// SchemaConfigsEqual reports whether the configs describe the same schema, along with readable
+// differences such as "Query.widget: type Widget vs WidgetV2". Thunks are forced. Types, fields,
+// arguments, input fields, enum values, and directives are compared by coordinate, including their
+// types, defaults, descriptions, and deprecations. Resolvers and other functions are ignored.
+func SchemaConfigsEqual(a, b graphql.SchemaConfig) (bool, []string) {
+	detailsA, detailsB := schemaDetails(a), schemaDetails(b)
+	coordinates := make([]string, 0, len(detailsA)+len(detailsB))
+	for coordinate := range detailsA {
+		coordinates = append(coordinates, coordinate)
+	}
+	for coordinate := range detailsB {
+		if _, ok := detailsA[coordinate]; !ok {
+			coordinates = append(coordinates, coordinate)
+		}
+	}
+	sort.Strings(coordinates)
+
+	var differences []string
+	for _, coordinate := range coordinates {
+		attributesA, inA := detailsA[coordinate]
+		attributesB, inB := detailsB[coordinate]
+		switch {
+		case !inB:
+			differences = append(differences, coordinate+": only in a")
+		case !inA:
+			differences = append(differences, coordinate+": only in b")
+		default:
+			names := make([]string, 0, len(attributesA)+len(attributesB))
+			for name := range attributesA {
+				names = append(names, name)
+			}
+			for name := range attributesB {
+				if _, ok := attributesA[name]; !ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				valueA, inA := attributesA[name]
+				valueB, inB := attributesB[name]
+				if !inA {
+					valueA = "none"
+				}
+				if !inB {
+					valueB = "none"
+				}
+				if valueA != valueB {
+					differences = append(differences, fmt.Sprintf("%v: %v %v vs %v", coordinate, name, valueA, valueB))
+				}
+			}
+		}
+	}
+	return len(differences) == 0, differences
+}
+
+// schemaDetails maps the coordinates of the config's elements to their comparable attributes.
+func schemaDetails(config graphql.SchemaConfig) map[string]map[string]string {
+	details := map[string]map[string]string{}
+	// Attributes that are empty are omitted, so that they compare equal to missing ones.
+	add := func(coordinate string, attributes map[string]string) {
+		for name, value := range attributes {
+			if value == "" || value == `""` {
+				delete(attributes, name)
+			}
+		}
+		details[coordinate] = attributes
+	}
+	addArgs := func(parent string, args []*graphql.Argument) {
+		for _, arg := range args {
+			add(parent+"("+arg.Name()+":)", map[string]string{
+				"type":        arg.Type.String(),
+				"default":     defaultLiteral(arg.Type, arg.DefaultValue),
+				"description": stringLiteral(arg.Description()),
+			})
+		}
+	}
+	addFields := func(parent string, fields graphql.FieldDefinitionMap) {
+		for name, def := range fields {
+			add(parent+"."+name, map[string]string{
+				"type":        def.Type.String(),
+				"description": stringLiteral(def.Description),
+				"deprecation": stringLiteral(def.DeprecationReason),
+			})
+			addArgs(parent+"."+name, def.Args)
+		}
+	}
+
+	for _, root := range []struct {
+		operation string
+		obj       *graphql.Object
+	}{
+		{"query", config.Query},
+		{"mutation", config.Mutation},
+		{"subscription", config.Subscription},
+	} {
+		if root.obj != nil {
+			add("schema."+root.operation, map[string]string{
+				"type": root.obj.Name(),
+			})
+		}
+	}
+	for _, d := range config.Directives {
+		locations := append([]string(nil), d.Locations...)
+		sort.Strings(locations)
+		add("@"+d.Name, map[string]string{
+			"kind":        "directive",
+			"locations":   strings.Join(locations, " | "),
+			"description": stringLiteral(d.Description),
+		})
+		addArgs("@"+d.Name, d.Args)
+	}
+	for _, t := range schemaTypes(config) {
+		attributes := map[string]string{
+			"description": stringLiteral(t.Description()),
+		}
+		if err := t.Error(); err != nil {
+			attributes["error"] = err.Error()
+		}
+		switch t := t.(type) {
+		case *graphql.Object:
+			var names []string
+			for _, iface := range t.Interfaces() {
+				names = append(names, iface.Name())
+			}
+			sort.Strings(names)
+			attributes["kind"] = "object"
+			attributes["interfaces"] = strings.Join(names, " & ")
+			attributes["description"] = stringLiteral(t.PrivateDescription) // t.Description() always returns ""
+			addFields(t.Name(), t.Fields())
+		case *graphql.Interface:
+			attributes["kind"] = "interface"
+			addFields(t.Name(), t.Fields())
+		case *graphql.Union:
+			var names []string
+			for _, obj := range t.Types() {
+				names = append(names, obj.Name())
+			}
+			sort.Strings(names)
+			attributes["kind"] = "union"
+			attributes["members"] = strings.Join(names, " | ")
+		case *graphql.InputObject:
+			attributes["kind"] = "input"
+			for name, f := range t.Fields() {
+				add(t.Name()+"."+name, map[string]string{
+					"type":        f.Type.String(),
+					"default":     defaultLiteral(f.Type, f.DefaultValue),
+					"description": stringLiteral(f.Description()),
+				})
+			}
+		case *graphql.Enum:
+			attributes["kind"] = "enum"
+			for _, value := range t.Values() {
+				add(t.Name()+"."+value.Name, map[string]string{
+					"value":       fmt.Sprint(value.Value),
+					"description": stringLiteral(value.Description),
+					"deprecation": stringLiteral(value.DeprecationReason),
+				})
+			}
+		case *graphql.Scalar, *ScalarVariants:
+			attributes["kind"] = "scalar"
+		}
+		add(t.Name(), attributes)
+	}
+	return details
+}
+
+func defaultLiteral(t graphql.Type, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return valueLiteral(t, value)
+}
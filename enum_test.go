@@ -0,0 +1,290 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func enumTestInput(values graphql.EnumValueConfigMap) graphql.SchemaConfig {
+	// Enums with errors can't be used by fields, since graphql-go fails the object instead.
+	return testInput(graphql.Fields{
+		"name": &graphql.Field{Type: graphql.String},
+	}, graphql.NewEnum(graphql.EnumConfig{
+		Name:   "Color",
+		Values: values,
+	}))
+}
+
+func TestMalformedEnums(t *testing.T) {
+	for name, tc := range map[string]struct {
+		values   graphql.EnumValueConfigMap
+		expected string
+		kept     []string
+	}{
+		"nil value": {
+			values: graphql.EnumValueConfigMap{
+				"RED":  &graphql.EnumValueConfig{Value: 0},
+				"BLUE": nil,
+			},
+			expected: "invalid enum Color",
+		},
+		"invalid name": {
+			values: graphql.EnumValueConfigMap{
+				"RED":   &graphql.EnumValueConfig{Value: 0},
+				"BLUE!": &graphql.EnumValueConfig{Value: 1},
+			},
+			expected: "invalid enum Color",
+		},
+		"nil conditional config": {
+			values: graphql.EnumValueConfigMap{
+				"RED":  &graphql.EnumValueConfig{Value: 0},
+				"BLUE": &graphql.EnumValueConfig{Value: &conditionalEnum{Condition: func(*PreprocessorConfig) bool { return true }}},
+			},
+			expected: "conditional value BLUE with a nil config",
+			kept:     []string{"RED"},
+		},
+		"same internal value": {
+			values: graphql.EnumValueConfigMap{
+				"RED":     &graphql.EnumValueConfig{Value: 0},
+				"CRIMSON": BetaEnum(&graphql.EnumValueConfig{Value: 0}),
+			},
+			expected: "values CRIMSON and RED with the same internal value 0",
+			kept:     []string{"CRIMSON"},
+		},
+	} {
+		config := &PreprocessorConfig{BetaFeaturesEnabled: true, Strict: true}
+		if _, err := PreprocessSchemaConfigE(enumTestInput(tc.values), config); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%v: expected a strict error containing %q, got %v", name, tc.expected, err)
+		}
+
+		var warnings []error
+		config = &PreprocessorConfig{
+			BetaFeaturesEnabled: true,
+			OnWarning:           func(err error) { warnings = append(warnings, err) },
+		}
+		result, err := PreprocessSchemaConfigE(enumTestInput(tc.values), config)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), tc.expected) {
+			t.Errorf("%v: expected a warning containing %q, got %v", name, tc.expected, warnings)
+		}
+		if len(tc.kept) == 0 {
+			if len(result.Types) > 0 {
+				t.Errorf("%v: expected the enum to be removed", name)
+			}
+			continue
+		}
+		if len(result.Types) != 1 {
+			t.Fatalf("%v: the enum was removed", name)
+		}
+		var kept []string
+		for _, value := range result.Types[0].(*graphql.Enum).Values() {
+			kept = append(kept, value.Name)
+		}
+		if strings.Join(kept, ",") != strings.Join(tc.kept, ",") {
+			t.Errorf("%v: unexpected values %v", name, kept)
+		}
+	}
+}
+
+// tierValue is a user-defined conditional enum value.
+type tierValue struct {
+	value *graphql.EnumValueConfig
+	tier  string
+}
+
+func (v tierValue) Underlying() *graphql.EnumValueConfig {
+	return v.value
+}
+
+func (v tierValue) Enabled(cfg *PreprocessorConfig) bool {
+	return cfg.IsEnabled("tier:" + v.tier)
+}
+
+func TestUserDefinedConditionalValue(t *testing.T) {
+	input := enumTestInput(graphql.EnumValueConfigMap{
+		"RED":  &graphql.EnumValueConfig{Value: 0},
+		"GOLD": &graphql.EnumValueConfig{Value: tierValue{value: &graphql.EnumValueConfig{Value: 1, Description: "gold"}, tier: "premium"}},
+	})
+
+	// External tools can find conditional values without knowing their implementation.
+	for _, value := range input.Types[0].(*graphql.Enum).Values() {
+		if conditional, ok := value.Value.(ConditionalValue); ok {
+			if value.Name != "GOLD" || conditional.Underlying().Description != "gold" {
+				t.Errorf("unexpected conditional value %v", value.Name)
+			}
+		}
+	}
+
+	for _, premium := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			Flags: map[string]bool{"tier:premium": premium},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := map[string]interface{}{}
+		for _, value := range result.Types[0].(*graphql.Enum).Values() {
+			values[value.Name] = value.Value
+		}
+		if value, ok := values["GOLD"]; ok != premium || ok && value != 1 {
+			t.Errorf("premium %v: unexpected values %v", premium, values)
+		}
+	}
+}
+
+func TestDisabledEnumValueDeprecation(t *testing.T) {
+	color := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED":   &graphql.EnumValueConfig{Value: "red"},
+			"BLUE":  BetaEnum(&graphql.EnumValueConfig{Value: "blue", Description: "Calm."}),
+			"GREEN": BetaEnum(&graphql.EnumValueConfig{Value: "green", DeprecationReason: "Use BLUE."}),
+		},
+	})
+	input := testInput(graphql.Fields{
+		"echo": &graphql.Field{
+			Type: color,
+			Args: graphql.FieldConfigArgument{
+				"color": &graphql.ArgumentConfig{Type: color},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Args["color"], nil
+			},
+		},
+	})
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		DisabledEnumValueDeprecationReason: "Disabled in this variant.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := graphql.Do(graphql.Params{
+		Schema: schema,
+		RequestString: `{
+			__type(name: "Color") { enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason } }
+			echo(color: BLUE)
+		}`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	data := response.Data.(map[string]interface{})
+	if data["echo"] != "BLUE" {
+		t.Errorf("the deprecated value didn't coerce: %v", data["echo"])
+	}
+	values := map[string]interface{}{}
+	for _, value := range data["__type"].(map[string]interface{})["enumValues"].([]interface{}) {
+		value := value.(map[string]interface{})
+		values[value["name"].(string)] = value
+	}
+	expected := map[string]interface{}{
+		"RED":   map[string]interface{}{"name": "RED", "description": "", "isDeprecated": false, "deprecationReason": ""},
+		"BLUE":  map[string]interface{}{"name": "BLUE", "description": "Calm.", "isDeprecated": true, "deprecationReason": "Disabled in this variant."},
+		"GREEN": map[string]interface{}{"name": "GREEN", "description": "", "isDeprecated": true, "deprecationReason": "Use BLUE."},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	sdl, err := SchemaConfigToSDL(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`BLUE @deprecated(reason: "Disabled in this variant.")`,
+		`GREEN @deprecated(reason: "Use BLUE.")`,
+	} {
+		if !strings.Contains(sdl, line) {
+			t.Errorf("the SDL doesn't contain %v:\n%v", line, sdl)
+		}
+	}
+}
+
+func TestEnumWithoutValues(t *testing.T) {
+	tier := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Tier",
+		Values: graphql.EnumValueConfigMap{
+			"GOLD":     BetaEnum(&graphql.EnumValueConfig{Value: "gold"}),
+			"PLATINUM": BetaEnum(&graphql.EnumValueConfig{Value: "platinum"}),
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"tier": &graphql.InputObjectFieldConfig{Type: tier},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"members": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"tier": &graphql.ArgumentConfig{Type: tier},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return fmt.Sprintf("members %v", p.Args["tier"]), nil
+			},
+		},
+		"search": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: filter},
+			},
+		},
+		"tier": &graphql.Field{Type: tier},
+	})
+
+	for _, beta := range []bool{false, true} {
+		p := NewPreprocessor(&PreprocessorConfig{BetaFeaturesEnabled: beta})
+		result := p.Preprocess(input)
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		fields := result.Query.Fields()
+		if _, ok := fields["tier"]; ok != beta {
+			t.Errorf("beta %v: Query.tier present: %v", beta, ok)
+		}
+		if args := len(fields["members"].Args); args != map[bool]int{false: 0, true: 1}[beta] {
+			t.Errorf("beta %v: Query.members has %v arguments", beta, args)
+		}
+		preprocessedFilter := fields["search"].Args[0].Type.(*graphql.InputObject)
+		if _, ok := preprocessedFilter.Fields()["tier"]; ok != beta {
+			t.Errorf("beta %v: Filter.tier present: %v", beta, ok)
+		}
+
+		request := "{ members }"
+		expected := "members <nil>"
+		if beta {
+			request = "{ members(tier: GOLD) }"
+			expected = "members gold"
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: request})
+		if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, map[string]interface{}{"members": expected}) {
+			t.Errorf("beta %v: unexpected response %v", beta, response)
+		}
+
+		if !beta {
+			causes := map[string]string{}
+			for _, removal := range p.Report().Removals {
+				causes[removal.Coordinate] = removal.Cause
+			}
+			for _, coordinate := range []string{"Query.tier", "Query.members(tier:)", "Filter.tier"} {
+				if causes[coordinate] != "no enum values" {
+					t.Errorf("%v was removed with cause %q", coordinate, causes[coordinate])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// PreprocessingError describes a failure to preprocess a schema.
+type PreprocessingError struct {
+	// The types and fields being preprocessed when the failure occurred, outermost first. Fields
+	// are identified by their coordinates, e.g. "Query.widget".
+	Path []string
+
+	Err error
+}
+
+func (e *PreprocessingError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v (while preprocessing %v)", e.Err, pathString(e.Path))
+}
+
+func (e *PreprocessingError) Unwrap() error {
+	return e.Err
+}
+
+// newPreprocessingError annotates a recovered panic with the path. Panics that are already
+// annotated are returned as is, since they carry the innermost path.
+func newPreprocessingError(path []string, r interface{}) *PreprocessingError {
+	if err, ok := r.(*PreprocessingError); ok {
+		return err
+	}
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	return &PreprocessingError{
+		Path: append([]string(nil), path...),
+		Err:  err,
+	}
+}
+
+// PreprocessSchemaConfigE is like PreprocessSchemaConfig, but returns an error instead of
+// panicking. The result's thunks are evaluated before it returns, so that errors that would
+// otherwise occur lazily in graphql.NewSchema are returned here.
+func PreprocessSchemaConfigE(input graphql.SchemaConfig, config *PreprocessorConfig) (result graphql.SchemaConfig, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = graphql.SchemaConfig{}, newPreprocessingError(nil, r)
+		}
+	}()
+	result = PreprocessSchemaConfig(input, config)
+	for _, t := range schemaTypes(result) {
+		if err := t.Error(); err != nil {
+			return graphql.SchemaConfig{}, &PreprocessingError{
+				Path: []string{t.Name()},
+				Err:  err,
+			}
+		}
+	}
+	return result, nil
+}
+
+// invalidType carries an error through graphql-go, which reports the errors of field types when
+// an object or interface's fields are defined.
+type invalidType struct {
+	err error
+}
+
+func (t *invalidType) Name() string        { return "Invalid" }
+func (t *invalidType) Description() string { return "" }
+func (t *invalidType) String() string      { return "Invalid" }
+func (t *invalidType) Error() error        { return t.err }
+
+// invalidFields returns fields that fail to define with the given error, so that the error is
+// returned by graphql.NewSchema rather than panicking within it.
+func invalidFields(err error) graphql.Fields {
+	return graphql.Fields{
+		"invalid": &graphql.Field{
+			Type: &invalidType{err},
+		},
+	}
+}
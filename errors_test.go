@@ -0,0 +1,60 @@
+package graphqlapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestInvalidTypeErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		field    *graphql.Field
+		expected string
+	}{
+		"object": {
+			field: &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Broken",
+				Fields: graphql.Fields{
+					"bad-name": &graphql.Field{Type: graphql.String},
+				},
+			})},
+			expected: `invalid object Broken: Names must match /^[_a-zA-Z][_a-zA-Z0-9]*$/ but "bad-name" does not.`,
+		},
+		"interface": {
+			field: &graphql.Field{Type: graphql.NewInterface(graphql.InterfaceConfig{
+				Name: "Broken",
+				Fields: graphql.Fields{
+					"bad-name": &graphql.Field{Type: graphql.String},
+				},
+			})},
+			expected: `invalid interface Broken: Names must match /^[_a-zA-Z][_a-zA-Z0-9]*$/ but "bad-name" does not.`,
+		},
+		"input object": {
+			field: &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: graphql.NewInputObject(graphql.InputObjectConfig{
+						Name:   "Broken",
+						Fields: graphql.InputObjectConfigFieldMap{},
+					})},
+				},
+			},
+			expected: "invalid input object Broken: Broken fields must be an object with field names as keys or a function which return such an object.",
+		},
+	} {
+		input := testInput(graphql.Fields{
+			"broken": tc.field,
+		})
+		if _, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{}); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%v: expected an error containing %q, got %v", name, tc.expected, err)
+		}
+
+		// Without the error-returning entry point, graphql-go reports object and interface errors.
+		if name != "input object" {
+			if _, err := graphql.NewSchema(PreprocessSchemaConfig(input, &PreprocessorConfig{})); err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("%v: expected a schema error containing %q, got %v", name, tc.expected, err)
+			}
+		}
+	}
+}
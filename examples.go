@@ -0,0 +1,70 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/graphql-go/graphql"
+)
+
+// serializeExample serializes an example value for a field of the given preprocessed type, or
+// returns an error if it isn't valid for the type. Only leaf types and lists of them are supported.
+func serializeExample(t graphql.Type, value interface{}) (interface{}, error) {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		if value == nil {
+			return nil, fmt.Errorf("null isn't valid for %v", t)
+		}
+		return serializeExample(t.OfType, value)
+	case *graphql.List:
+		if value == nil {
+			return nil, nil
+		}
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("%T isn't valid for %v", value, t)
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			element, err := serializeExample(t.OfType, v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list[i] = element
+		}
+		return list, nil
+	case graphql.Leaf:
+		if value == nil {
+			return nil, nil
+		}
+		if serialized := t.Serialize(value); serialized != nil {
+			return serialized, nil
+		}
+		return nil, fmt.Errorf("%#v isn't valid for %v", value, t)
+	}
+	return nil, fmt.Errorf("examples aren't supported for fields of type %v", t)
+}
+
+// applyExample validates the field's example, if it has one, and appends it to the description if
+// the config calls for it.
+func (p *preprocessor) applyExample(coordinate string, f *graphql.Field) {
+	example, ok := p.Config.Examples[coordinate]
+	if !ok {
+		return
+	}
+	serialized, err := serializeExample(f.Type, example)
+	if err != nil {
+		panic(fmt.Errorf("invalid example for %v: %v", coordinate, err))
+	}
+	if p.Config.ExamplesInDescriptions {
+		buf, err := json.Marshal(serialized)
+		if err != nil {
+			panic(fmt.Errorf("invalid example for %v: %v", coordinate, err))
+		}
+		if f.Description != "" {
+			f.Description += "\n\n"
+		}
+		f.Description += "Example: " + string(buf)
+	}
+}
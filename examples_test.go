@@ -0,0 +1,91 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func examplesTestInput() graphql.SchemaConfig {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"count":    &graphql.Field{Type: graphql.Int},
+			"currency": BetaField(&graphql.Field{Type: graphql.String, Description: "The price's currency."}),
+			"tags":     &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+	return testInput(graphql.Fields{
+		"widget": &graphql.Field{Type: widget},
+	})
+}
+
+func TestExamplesOnGatedFields(t *testing.T) {
+	examples := map[string]interface{}{
+		"Widget.currency": "USD",
+		"Widget.tags":     []string{"new", "sale"},
+	}
+	for _, beta := range []bool{false, true} {
+		cfg := &PreprocessorConfig{
+			BetaFeaturesEnabled:    beta,
+			Examples:               examples,
+			ExamplesInDescriptions: true,
+		}
+		result, err := PreprocessSchemaConfigE(examplesTestInput(), cfg)
+		if err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		fields := result.Query.Fields()["widget"].Type.(*graphql.Object).Fields()
+		if description := fields["tags"].Description; description != `Example: ["new","sale"]` {
+			t.Errorf("beta %v: Widget.tags has description %q", beta, description)
+		}
+		if currency, ok := fields["currency"]; ok != beta {
+			t.Errorf("beta %v: Widget.currency present: %v", beta, ok)
+		} else if ok && currency.Description != "The price's currency.\n\nExample: \"USD\"" {
+			t.Errorf("Widget.currency has description %q", currency.Description)
+		}
+
+		mock, err := MockSchema(examplesTestInput(), cfg, MockGenerators{Seed: 1})
+		if err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		response := graphql.Do(graphql.Params{Schema: mock, RequestString: `{ widget { tags } }`})
+		if len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+		widget := response.Data.(map[string]interface{})["widget"].(map[string]interface{})
+		if !reflect.DeepEqual(widget["tags"], []interface{}{"new", "sale"}) {
+			t.Errorf("beta %v: the mock returned tags %v", beta, widget["tags"])
+		}
+		response = graphql.Do(graphql.Params{Schema: mock, RequestString: `{ widget { currency } }`})
+		if !beta {
+			if len(response.Errors) == 0 {
+				t.Error("the mock without beta has Widget.currency")
+			}
+			continue
+		}
+		if len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+		if currency := response.Data.(map[string]interface{})["widget"].(map[string]interface{})["currency"]; currency != "USD" {
+			t.Errorf("the mock returned currency %v", currency)
+		}
+	}
+}
+
+func TestStaleExamples(t *testing.T) {
+	for coordinate, example := range map[string]interface{}{
+		"Widget.count": "many",
+		"Widget.tags":  []interface{}{"new", nil},
+		"Query.widget": map[string]interface{}{"count": 1},
+	} {
+		_, err := PreprocessSchemaConfigE(examplesTestInput(), &PreprocessorConfig{
+			Examples: map[string]interface{}{coordinate: example},
+		})
+		if err == nil || !strings.Contains(err.Error(), "invalid example for "+coordinate) {
+			t.Errorf("%v: expected an invalid example error, got %v", coordinate, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package graphqlapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Execute is like graphql.Do, but validates the request against graphql.SpecifiedRules plus any
+// rules returned by config.ValidationRules. params.Schema should be built from the config. Unlike
+// graphql.Do, Execute doesn't invoke schema extensions. The config is made available to resolvers
+// via FlagsFromContext. Requests are checked against the config's AllowedOperations policy before
+// they're parsed. If the config's AddVariantExtensions option is set, the result's extensions
+// describe the variant.
+func Execute(config *PreprocessorConfig, params graphql.Params) *graphql.Result {
+	return execute(config, params, func(*ast.Document) graphql.Schema {
+		return params.Schema
+	}, VariantStats{})
+}
+
+// execute implements Execute, validating and executing the request against the schema returned by
+// schemaFor. The stats describe how the schema was selected, and the request's duration is added to
+// them.
+func execute(config *PreprocessorConfig, params graphql.Params, schemaFor func(*ast.Document) graphql.Schema, stats VariantStats) *graphql.Result {
+	start := time.Now()
+	result := executeRequest(config, params, schemaFor)
+	if config.AddVariantExtensions {
+		stats.Duration += time.Since(start)
+		AddResultExtensions(result, VariantExtensions(config, stats))
+	}
+	return result
+}
+
+func executeRequest(config *PreprocessorConfig, params graphql.Params, schemaFor func(*ast.Document) graphql.Schema) *graphql.Result {
+	if config.AllowedOperations != nil {
+		var rejection *graphql.Result
+		if params, rejection = config.AllowedOperations(config).apply(params); rejection != nil {
+			return rejection
+		}
+	}
+
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(params.RequestString),
+			Name: "GraphQL request",
+		}),
+	})
+	if err != nil {
+		return &graphql.Result{
+			Errors: gqlerrors.FormatErrors(err),
+		}
+	}
+
+	rules := graphql.SpecifiedRules
+	if config.ValidationRules != nil {
+		if extra := config.ValidationRules(config); len(extra) > 0 {
+			rules = append(append([]graphql.ValidationRuleFn(nil), rules...), extra...)
+		}
+	}
+	schema := schemaFor(document)
+	if result := graphql.ValidateDocument(&schema, document, rules); !result.IsValid {
+		return &graphql.Result{
+			Errors: result.Errors,
+		}
+	}
+
+	ctx := params.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		Root:          params.RootObject,
+		AST:           document,
+		OperationName: params.OperationName,
+		Args:          params.VariableValues,
+		Context:       WithFlags(ctx, config),
+	})
+}
@@ -0,0 +1,90 @@
+package graphqlapi
+
+import (
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// AddResultExtensions merges the given extensions into the result. Existing entries are never
+// overwritten.
+func AddResultExtensions(result *graphql.Result, extensions map[string]interface{}) {
+	if len(extensions) == 0 {
+		return
+	}
+	if result.Extensions == nil {
+		result.Extensions = make(map[string]interface{}, len(extensions))
+	}
+	for k, v := range extensions {
+		if _, ok := result.Extensions[k]; !ok {
+			result.Extensions[k] = v
+		}
+	}
+}
+
+// VariantStats describes how a request was served, for VariantExtensions.
+type VariantStats struct {
+	// Whether the schema was looked up in a SchemaCache, and if so, whether it was already cached.
+	UsedCache bool
+	CacheHit  bool
+
+	// How long the request took to serve, including selecting or building its schema.
+	Duration time.Duration
+}
+
+// VariantExtensions describes the schema variant built from the given config, suitable for
+// AddResultExtensions: its fingerprint, which matches SchemaProvenance's ConfigFingerprint, its
+// enabled flags, whether it was cached if a SchemaCache was used, and the request's duration.
+// Only the enabled flags that the config's DisclosedFlags discloses to the schema's audience are
+// listed. Flags set in the config and release stages are checked via IsEnabled. Features that are
+// on by default in some environments aren't known to the config, so they're checked if given.
+func VariantExtensions(config *PreprocessorConfig, stats VariantStats, features ...*FeatureFlag) map[string]interface{} {
+	disclosed := []string{}
+	for _, flag := range enabledFlags(config, features) {
+		if config.discloses(flag) {
+			disclosed = append(disclosed, flag)
+		}
+	}
+	variant := map[string]interface{}{
+		"fingerprint":  configFingerprint(config),
+		"enabledFlags": disclosed,
+		"durationMs":   float64(stats.Duration) / float64(time.Millisecond),
+	}
+	if stats.UsedCache {
+		variant["cacheHit"] = stats.CacheHit
+	}
+	return map[string]interface{}{
+		"variant": variant,
+	}
+}
+
+// discloses reports whether the flag may be listed in result extensions for the schema's audience.
+// See DisclosedFlags.
+func (c *PreprocessorConfig) discloses(flag string) bool {
+	role, ok := c.DisclosedFlags[flag]
+	return ok && (role == "" || c.hasRole(role))
+}
+
+// enabledFlags returns the sorted names of the enabled flags among the release stages, the flags
+// set in the config, and the given features.
+func enabledFlags(config *PreprocessorConfig, features []*FeatureFlag) []string {
+	enabled := map[string]bool{}
+	for flag := range stages {
+		enabled[flag] = config.IsEnabled(flag)
+	}
+	for flag := range config.Flags {
+		enabled[flag] = config.IsEnabled(flag)
+	}
+	for _, f := range features {
+		enabled[f.Name()] = f.Enabled(config)
+	}
+	var flags []string
+	for flag, ok := range enabled {
+		if ok {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
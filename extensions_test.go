@@ -0,0 +1,118 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestVariantExtensions(t *testing.T) {
+	payments := Feature("payments").DefaultOn(Staging)
+	config := &PreprocessorConfig{
+		Environment:         Staging,
+		BetaFeaturesEnabled: true,
+		Flags: map[string]bool{
+			"internal-tools": true,
+			"refunds":        false,
+			"undisclosed":    true,
+		},
+		DisclosedFlags: map[string]string{
+			"beta":           "",
+			"payments":       "",
+			"refunds":        "",
+			"internal-tools": "staff",
+		},
+	}
+	variant := func(stats VariantStats) map[string]interface{} {
+		return VariantExtensions(config, stats, payments)["variant"].(map[string]interface{})
+	}
+
+	// Flags that require a role are only disclosed to audiences that hold it, and unmapped flags
+	// are never disclosed.
+	if enabled := variant(VariantStats{})["enabledFlags"]; !reflect.DeepEqual(enabled, []string{"beta", "payments"}) {
+		t.Errorf("unexpected public flags: %v", enabled)
+	}
+	config.Roles = []string{"staff"}
+	if enabled := variant(VariantStats{})["enabledFlags"]; !reflect.DeepEqual(enabled, []string{"beta", "internal-tools", "payments"}) {
+		t.Errorf("unexpected staff flags: %v", enabled)
+	}
+	config.Environment = Production
+	if enabled := variant(VariantStats{})["enabledFlags"]; !reflect.DeepEqual(enabled, []string{"beta", "internal-tools"}) {
+		t.Errorf("unexpected staff flags in production: %v", enabled)
+	}
+
+	v := variant(VariantStats{Duration: 1500 * time.Microsecond})
+	if v["fingerprint"] != configFingerprint(config) || v["durationMs"] != 1.5 {
+		t.Errorf("unexpected variant: %v", v)
+	}
+	if _, ok := v["cacheHit"]; ok {
+		t.Errorf("a cache hit was reported without a cache: %v", v)
+	}
+	if v := variant(VariantStats{UsedCache: true}); v["cacheHit"] != false {
+		t.Errorf("unexpected variant: %v", v)
+	}
+}
+
+func TestExecuteVariantExtensions(t *testing.T) {
+	input := testInput(graphql.Fields{
+		"id": &graphql.Field{Type: graphql.ID},
+		"widget": &graphql.Field{
+			Type: Beta(graphql.String),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "widget", nil
+			},
+		},
+	})
+	config := &PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+		DisclosedFlags:      map[string]string{"beta": ""},
+	}
+	cache := NewSchemaCache(input)
+	schema, err := cache.Get(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := graphql.Params{Schema: schema, RequestString: "{ widget }"}
+
+	if result := Execute(config, params); result.Extensions != nil {
+		t.Errorf("extensions were added without opting in: %v", result.Extensions)
+	}
+
+	config.AddVariantExtensions = true
+	result := Execute(config, params)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	variant := result.Extensions["variant"].(map[string]interface{})
+	if variant["fingerprint"] != configFingerprint(config) || !reflect.DeepEqual(variant["enabledFlags"], []string{"beta"}) {
+		t.Errorf("unexpected variant: %v", variant)
+	}
+	if _, ok := variant["cacheHit"]; ok {
+		t.Errorf("a cache hit was reported without a cache: %v", variant)
+	}
+
+	alpha := &PreprocessorConfig{
+		AlphaFeaturesEnabled: true,
+		AddVariantExtensions: true,
+	}
+	for _, expected := range []bool{false, true} {
+		result := cache.Execute(alpha, graphql.Params{RequestString: "{ __typename }"})
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors)
+		}
+		if hit := result.Extensions["variant"].(map[string]interface{})["cacheHit"]; hit != expected {
+			t.Errorf("expected cacheHit %v, got %v", expected, hit)
+		}
+	}
+}
+
+func TestAddResultExtensions(t *testing.T) {
+	result := &graphql.Result{}
+	AddResultExtensions(result, map[string]interface{}{"a": 1})
+	AddResultExtensions(result, map[string]interface{}{"a": 2, "b": 3})
+	if !reflect.DeepEqual(result.Extensions, map[string]interface{}{"a": 1, "b": 3}) {
+		t.Errorf("unexpected extensions: %v", result.Extensions)
+	}
+}
@@ -0,0 +1,62 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// FallbackType is preprocessed into its flagged type if that survives preprocessing, and into its
+// fallback type otherwise. Resolvers of fields using it must return values appropriate for both.
+type FallbackType struct {
+	Flagged  graphql.Type
+	Fallback graphql.Type
+
+	callsite string
+}
+
+// Fallback returns a type that degrades to the fallback when the flagged type (typically a
+// conditional) is removed, e.g. Fallback(Beta(RichText), graphql.String). The fallback must be
+// valid in every position the flagged type is: output, input, or both.
+func Fallback(flagged, fallback graphql.Type) *FallbackType {
+	return &FallbackType{
+		Flagged:  flagged,
+		Fallback: fallback,
+		callsite: callsite(1),
+	}
+}
+
+func (f *FallbackType) Name() string {
+	return f.Flagged.Name()
+}
+
+func (f *FallbackType) Description() string {
+	return f.Flagged.Description()
+}
+
+func (f *FallbackType) String() string {
+	return f.Flagged.String() + "|" + f.Fallback.String()
+}
+
+func (f *FallbackType) Error() error {
+	if err := f.Flagged.Error(); err != nil {
+		return err
+	}
+	return f.Fallback.Error()
+}
+
+func (f *FallbackType) declaration() string {
+	return fmt.Sprintf("fallback %v (declared at %v)", f, f.callsite)
+}
+
+func (p *preprocessor) preprocessFallback(f *FallbackType) (graphql.Type, bool) {
+	flagged, fallback := namedType(unwrapConditionals(f.Flagged)), namedType(unwrapConditionals(f.Fallback))
+	if graphql.IsInputType(flagged) && !graphql.IsInputType(fallback) || graphql.IsOutputType(flagged) && !graphql.IsOutputType(fallback) {
+		panic(fmt.Errorf("%v has incompatible types %v and %v", f.declaration(), flagged, fallback))
+	}
+	if result, ok := p.preprocessType(f.Flagged); ok {
+		return result, true
+	}
+	p.substituted(f.Flagged.String(), f.Fallback.String())
+	return p.preprocessType(f.Fallback)
+}
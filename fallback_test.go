@@ -0,0 +1,81 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func fallbackTestInput(fallback graphql.Type) graphql.SchemaConfig {
+	richText := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RichText",
+		Fields: graphql.Fields{
+			"html": &graphql.Field{Type: graphql.String},
+		},
+	})
+	article := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: graphql.Fields{
+			"body": &graphql.Field{
+				Type: Fallback(Beta(richText), fallback),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if FlagsFromContext(p.Context).IsEnabled("beta") {
+						return map[string]interface{}{"html": "<p>Hi</p>"}, nil
+					}
+					return "Hi", nil
+				},
+			},
+		},
+	})
+	return testInput(graphql.Fields{
+		"article": &graphql.Field{
+			Type: article,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return struct{}{}, nil
+			},
+		},
+	})
+}
+
+func TestFallback(t *testing.T) {
+	for beta, tc := range map[bool]struct {
+		query    string
+		typeName string
+		body     interface{}
+	}{
+		false: {"{ article { body } }", "String", "Hi"},
+		true:  {"{ article { body { html } } }", "RichText", map[string]interface{}{"html": "<p>Hi</p>"}},
+	} {
+		result, err := PreprocessSchemaConfigE(fallbackTestInput(graphql.String), &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name := schema.Type("Article").(*graphql.Object).Fields()["body"].Type.Name(); name != tc.typeName {
+			t.Errorf("beta %v: Article.body has type %v", beta, name)
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: tc.query})
+		expected := map[string]interface{}{"article": map[string]interface{}{"body": tc.body}}
+		if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, expected) {
+			t.Errorf("beta %v: unexpected response %v", beta, response)
+		}
+	}
+}
+
+func TestFallbackIncompatibleTypes(t *testing.T) {
+	input := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RichTextInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"html": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	_, err := PreprocessSchemaConfigE(fallbackTestInput(input), &PreprocessorConfig{})
+	if err == nil || !strings.Contains(err.Error(), "has incompatible types RichText and RichTextInput") {
+		t.Errorf("expected an incompatible types error, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+type Environment string
+
+const (
+	Development Environment = "development"
+	Staging     Environment = "staging"
+	Production  Environment = "production"
+)
+
+// FeatureFlag describes a named flag along with the environments in which it's enabled by
+// default. See PreprocessorConfig.flagEnabled for precedence rules.
+type FeatureFlag struct {
+	name      string
+	defaultOn []Environment
+}
+
+// defaultStageSuffixes maps the names of the built-in release stage flags to the default suffixes
+// of the conditionals created by Alpha, Beta, Experimental, and Internal, e.g. "OrderBeta". See
+// PreprocessorConfig.StageSuffixes.
+var defaultStageSuffixes = map[string]string{
+	"alpha":        "Alpha",
+	"beta":         "Beta",
+	"experimental": "Experimental",
+	"internal":     "Internal",
+}
+
+// stages maps the names of the built-in release stage flags to the config fields that enable them.
+// The flags are independent, e.g. enabling beta doesn't enable alpha.
+var stages = map[string]func(*PreprocessorConfig) *bool{
+	"alpha": func(cfg *PreprocessorConfig) *bool {
+		return &cfg.AlphaFeaturesEnabled
+	},
+	"beta": func(cfg *PreprocessorConfig) *bool {
+		return &cfg.BetaFeaturesEnabled
+	},
+	"experimental": func(cfg *PreprocessorConfig) *bool {
+		return &cfg.ExperimentalFeaturesEnabled
+	},
+	"internal": func(cfg *PreprocessorConfig) *bool {
+		return &cfg.InternalFeaturesEnabled
+	},
+}
+
+// stageSuffix is the SuffixStrategy of the conditionals created for release stages. The suffix is
+// taken from the config, so that variants may name stages differently.
+type stageSuffix string
+
+func (s stageSuffix) Apply(baseName string, cfg *PreprocessorConfig) string {
+	return baseName + cfg.stageSuffix(string(s))
+}
+
+// stageSuffix returns the suffix of the named release stage. It's safe to call on a nil config.
+func (c *PreprocessorConfig) stageSuffix(flag string) string {
+	if c != nil {
+		if suffix, ok := c.StageSuffixes[flag]; ok {
+			return suffix
+		}
+	}
+	return defaultStageSuffixes[flag]
+}
+
+// validateStageSuffixes panics unless the config's release stage suffixes are known, can be
+// appended to names, and are distinct, since conditionals of the same type would otherwise be
+// named alike despite having different conditions.
+func (c *PreprocessorConfig) validateStageSuffixes() {
+	for flag := range c.StageSuffixes {
+		if _, ok := stages[flag]; !ok {
+			panic(fmt.Errorf("StageSuffixes has a suffix for %v, which isn't a release stage", flag))
+		}
+	}
+	seen := map[string]string{}
+	for _, flag := range []string{"alpha", "beta", "experimental", "internal"} {
+		suffix := c.stageSuffix(flag)
+		if suffix == "" || !nameRegexp.MatchString("_"+suffix) {
+			panic(fmt.Errorf("the suffix %q for release stage %v isn't valid in names", suffix, flag))
+		}
+		if other, ok := seen[suffix]; ok {
+			panic(fmt.Errorf("release stages %v and %v have the same suffix %q", other, flag, suffix))
+		}
+		seen[suffix] = flag
+	}
+}
+
+func newStage(flag string, ofType graphql.Type, callsite string) *Conditional {
+	c := Feature(flag).newType(ofType, callsite)
+	c.Suffix = ""
+	c.SuffixStrategy = stageSuffix(flag)
+	return c
+}
+
+// Alpha returns a conditional that's only present if alpha features are enabled.
+func Alpha(ofType graphql.Type) *Conditional {
+	return newStage("alpha", ofType, callsite(1))
+}
+
+// Experimental returns a conditional that's only present if experimental features are enabled.
+func Experimental(ofType graphql.Type) *Conditional {
+	return newStage("experimental", ofType, callsite(1))
+}
+
+// Internal returns a conditional that's only present if internal features are enabled.
+func Internal(ofType graphql.Type) *Conditional {
+	return newStage("internal", ofType, callsite(1))
+}
+
+func AlphaEnum(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return FlagEnum("alpha", value)
+}
+
+func ExperimentalEnum(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return FlagEnum("experimental", value)
+}
+
+func InternalEnum(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return FlagEnum("internal", value)
+}
+
+// Flag returns a conditional that's only present if the named flag is enabled.
+func Flag(name string, ofType graphql.Type) *Conditional {
+	return Feature(name).newType(ofType, callsite(1))
+}
+
+// FlagEnum returns an enum value that's only present if the named flag is enabled.
+func FlagEnum(name string, value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return Feature(name).EnumValue(value)
+}
+
+func Feature(name string) *FeatureFlag {
+	return &FeatureFlag{
+		name: name,
+	}
+}
+
+// DefaultOn returns a copy of the feature that's enabled by default in the given environments.
+func (f *FeatureFlag) DefaultOn(envs ...Environment) *FeatureFlag {
+	return &FeatureFlag{
+		name:      f.name,
+		defaultOn: append(append([]Environment(nil), f.defaultOn...), envs...),
+	}
+}
+
+func (f *FeatureFlag) Name() string {
+	return f.name
+}
+
+func (f *FeatureFlag) Enabled(cfg *PreprocessorConfig) bool {
+	return cfg.flagEnabled(f.name, f.defaultOn)
+}
+
+// Type returns a conditional that's only present if the feature is enabled.
+func (f *FeatureFlag) Type(ofType graphql.Type) *Conditional {
+	return f.newType(ofType, callsite(1))
+}
+
+func (f *FeatureFlag) newType(ofType graphql.Type, callsite string) *Conditional {
+	return &Conditional{
+		OfType:    ofType,
+		Suffix:    "_" + f.name,
+		Condition: f.Enabled,
+		callsite:  callsite,
+	}
+}
+
+// EnumValue returns an enum value that's only present if the feature is enabled.
+func (f *FeatureFlag) EnumValue(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return NewConditionalEnumValue(value, f.Enabled)
+}
@@ -0,0 +1,200 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func stageTestInput(wrap func(graphql.Type) *Conditional) (graphql.SchemaConfig, *graphql.Object) {
+	order := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	c := wrap(order)
+	c.RenameWhenEnabled = true
+	return testInput(graphql.Fields{
+		"order":     &graphql.Field{Type: order},
+		"betaOrder": &graphql.Field{Type: c},
+	}), order
+}
+
+func TestBetaName(t *testing.T) {
+	_, order := stageTestInput(Beta)
+	if name := Beta(order).Name(); name != "OrderBeta" {
+		t.Errorf("Beta(Order) is named %v", name)
+	}
+}
+
+func TestStageSuffixes(t *testing.T) {
+	input, _ := stageTestInput(Beta)
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := result.Query.Fields()["betaOrder"].Type.Name(); name != "OrderBeta" {
+		t.Errorf("betaOrder has type %v", name)
+	}
+
+	result, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+		StageSuffixes:       map[string]string{"beta": "Preview"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := result.Query.Fields()["betaOrder"].Type.Name(); name != "OrderPreview" {
+		t.Errorf("betaOrder has type %v", name)
+	}
+}
+
+func TestStageSuffixesValidation(t *testing.T) {
+	input, _ := stageTestInput(Alpha)
+	for suffix, expected := range map[string]string{
+		"β":     "isn't valid in names",
+		"":      "isn't valid in names",
+		"Alpha": "have the same suffix",
+	} {
+		_, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			StageSuffixes: map[string]string{"beta": suffix},
+		})
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("suffix %q: expected an error containing %q, got %v", suffix, expected, err)
+		}
+	}
+}
+
+func TestFeatureFlagPrecedence(t *testing.T) {
+	feature := Feature("payments").DefaultOn(Development, Staging)
+	for _, tc := range []struct {
+		flags       map[string]bool
+		environment Environment
+		expected    bool
+	}{
+		{nil, Development, true},
+		{nil, Staging, true},
+		{nil, Production, false},
+		{nil, "", false},
+		{map[string]bool{"payments": false}, Development, false},
+		{map[string]bool{"payments": true}, Production, true},
+		{map[string]bool{"refunds": true}, Production, false},
+		{map[string]bool{"refunds": false}, Staging, true},
+	} {
+		cfg := &PreprocessorConfig{Flags: tc.flags, Environment: tc.environment}
+		if enabled := feature.Enabled(cfg); enabled != tc.expected {
+			t.Errorf("flags %v in %q: enabled is %v", tc.flags, tc.environment, enabled)
+		}
+		// Without the feature's defaults, only development enables flags that aren't set.
+		explicit, ok := tc.flags["payments"]
+		if enabled := cfg.IsEnabled("payments"); enabled != (explicit || !ok && tc.environment == Development) {
+			t.Errorf("flags %v in %q: IsEnabled without defaults is %v", tc.flags, tc.environment, enabled)
+		}
+	}
+
+	// Every flag is enabled in development unless it's explicitly disabled, even if it's never
+	// declared with defaults.
+	development := &PreprocessorConfig{Environment: Development, Flags: map[string]bool{"refunds": false}}
+	if !development.IsEnabled("undeclared") || !development.IsEnabled("beta") || !Feature("undeclared").Enabled(development) {
+		t.Error("an undeclared flag is disabled in development")
+	}
+	if development.IsEnabled("refunds") {
+		t.Error("an explicitly disabled flag is enabled in development")
+	}
+	if (&PreprocessorConfig{Environment: Production}).IsEnabled("undeclared") {
+		t.Error("an undeclared flag is enabled in production")
+	}
+
+	// Explicit flags take precedence over the config's release stage fields.
+	if (&PreprocessorConfig{BetaFeaturesEnabled: true, Flags: map[string]bool{"beta": false}}).IsEnabled("beta") {
+		t.Error("an explicitly disabled beta flag is enabled")
+	}
+	if !(&PreprocessorConfig{BetaFeaturesEnabled: true}).IsEnabled("beta") {
+		t.Error("BetaFeaturesEnabled doesn't enable beta")
+	}
+}
+
+func TestFeatureFlagDefaultsInSchema(t *testing.T) {
+	feature := Feature("payments").DefaultOn(Development)
+	input := testInput(graphql.Fields{
+		"id":      &graphql.Field{Type: graphql.ID},
+		"balance": &graphql.Field{Type: feature.Type(graphql.Float)},
+		"refund":  &graphql.Field{Type: Flag("refunds", graphql.Float)},
+	})
+	for environment, expected := range map[Environment]bool{
+		Development: true,
+		Production:  false,
+	} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Environment: environment})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := result.Query.Fields()["balance"]; ok != expected {
+			t.Errorf("%v: Query.balance present: %v", environment, ok)
+		}
+		if _, ok := result.Query.Fields()["refund"]; ok != expected {
+			t.Errorf("%v: Query.refund present: %v", environment, ok)
+		}
+	}
+}
+
+func TestReleaseStages(t *testing.T) {
+	stage := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Stage",
+		Values: graphql.EnumValueConfigMap{
+			"GA":           &graphql.EnumValueConfig{Value: "ga"},
+			"ALPHA":        AlphaEnum(&graphql.EnumValueConfig{Value: "alpha"}),
+			"BETA":         BetaEnum(&graphql.EnumValueConfig{Value: "beta"}),
+			"EXPERIMENTAL": ExperimentalEnum(&graphql.EnumValueConfig{Value: "experimental"}),
+			"INTERNAL":     InternalEnum(&graphql.EnumValueConfig{Value: "internal"}),
+		},
+	})
+	input := testInput(graphql.Fields{
+		"stage":        &graphql.Field{Type: stage},
+		"alpha":        &graphql.Field{Type: Alpha(graphql.String)},
+		"beta":         &graphql.Field{Type: Beta(graphql.String)},
+		"experimental": &graphql.Field{Type: Experimental(graphql.String)},
+		"internal":     &graphql.Field{Type: Internal(graphql.String)},
+	})
+
+	for name, tc := range map[string]struct {
+		config   *PreprocessorConfig
+		expected []string
+	}{
+		"alpha": {
+			config:   &PreprocessorConfig{AlphaFeaturesEnabled: true},
+			expected: []string{"alpha", "stage", "ALPHA", "GA"},
+		},
+		"internal": {
+			config:   &PreprocessorConfig{InternalFeaturesEnabled: true},
+			expected: []string{"internal", "stage", "GA", "INTERNAL"},
+		},
+	} {
+		result, err := PreprocessSchemaConfigE(input, tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		var kept []string
+		for name := range result.Query.Fields() {
+			kept = append(kept, name)
+		}
+		sort.Strings(kept)
+		var values []string
+		for _, value := range result.Query.Fields()["stage"].Type.(*graphql.Enum).Values() {
+			values = append(values, value.Name)
+		}
+		sort.Strings(values)
+		if kept = append(kept, values...); !reflect.DeepEqual(kept, tc.expected) {
+			t.Errorf("%v: unexpected elements %v", name, kept)
+		}
+	}
+}
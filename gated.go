@@ -0,0 +1,239 @@
+package graphqlapi
+
+import (
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// GatedReference is a selection or fragment type condition in a document that refers to an element
+// removed from a variant.
+type GatedReference struct {
+	Line   int
+	Column int
+
+	// The coordinate of the removed field or type.
+	Coordinate string
+
+	// The flags read by the conditions that gate the element.
+	Flags []string
+}
+
+type gateAnalysis struct {
+	cfg        *PreprocessorConfig
+	types      map[string]graphql.Type
+	variant    map[string]string
+	flags      map[string]map[string]bool
+	source     *source.Source
+	references []GatedReference
+}
+
+// GatedReferences reports the field selections and fragment type conditions in the query that
+// refer to elements present in the input but removed from the variant described by cfg.
+func GatedReferences(input graphql.SchemaConfig, cfg *PreprocessorConfig, query string) ([]GatedReference, error) {
+	src := source.NewSource(&source.Source{
+		Body: []byte(query),
+		Name: "GraphQL request",
+	})
+	document, err := parser.Parse(parser.ParseParams{
+		Source: src,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a := &gateAnalysis{
+		cfg:     cfg,
+		types:   map[string]graphql.Type{},
+		variant: schemaCoordinates(PreprocessSchemaConfig(input, cfg)),
+		flags:   map[string]map[string]bool{},
+		source:  src,
+	}
+	for _, t := range schemaTypes(input) {
+		a.types[t.Name()] = t
+		switch t := t.(type) {
+		case *graphql.Object:
+			a.indexFields(t.Name(), t.Fields())
+		case *graphql.Interface:
+			a.indexFields(t.Name(), t.Fields())
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			var root *graphql.Object
+			switch definition.Operation {
+			case ast.OperationTypeQuery:
+				root = input.Query
+			case ast.OperationTypeMutation:
+				root = input.Mutation
+			case ast.OperationTypeSubscription:
+				root = input.Subscription
+			}
+			if root != nil {
+				a.visitSelectionSet(root, definition.SelectionSet)
+			}
+		case *ast.FragmentDefinition:
+			if t := a.typeCondition(definition.TypeCondition); t != nil {
+				a.visitSelectionSet(t, definition.SelectionSet)
+			}
+		}
+	}
+	return a.references, nil
+}
+
+// indexFields records the flags read by the conditionals wrapping each field's type.
+func (a *gateAnalysis) indexFields(parent string, fields graphql.FieldDefinitionMap) {
+	for name, def := range fields {
+		for t := def.Type; t != nil; {
+			switch wrapper := t.(type) {
+			case *graphql.List:
+				t = wrapper.OfType
+			case *graphql.NonNull:
+				t = wrapper.OfType
+			case *Conditional:
+				flags := conditionFlags(a.cfg, wrapper.conditionFor(newConditionContext(wrapper.OfType, parent+"."+name)), wrapper.ConditionName)
+				a.addFlags(parent+"."+name, flags)
+				a.addFlags(namedType(unwrapConditionals(wrapper.OfType)).Name(), flags)
+				t = wrapper.OfType
+			default:
+				t = nil
+			}
+		}
+	}
+}
+
+func (a *gateAnalysis) addFlags(coordinate string, flags []string) {
+	if a.flags[coordinate] == nil {
+		a.flags[coordinate] = map[string]bool{}
+	}
+	for _, flag := range flags {
+		a.flags[coordinate][flag] = true
+	}
+}
+
+// conditionFlags returns the flags read by a condition when evaluated with the given config.
+func conditionFlags(cfg *PreprocessorConfig, condition func(*PreprocessorConfig) bool, name string) []string {
+	if condition == nil {
+		var ok bool
+		if condition, ok = cfg.Conditions.Lookup(name); !ok {
+			return nil
+		}
+	}
+	c := *cfg
+	c.readFlags = newReadSet()
+	func() {
+		defer func() {
+			recover()
+		}()
+		condition(&c)
+	}()
+	return c.readFlags.sorted()
+}
+
+func unwrapConditionals(t graphql.Type) graphql.Type {
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.List:
+			t = wrapper.OfType
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		case *Conditional:
+			t = wrapper.OfType
+		case *FallbackType:
+			t = wrapper.Flagged
+		default:
+			return t
+		}
+	}
+}
+
+func (a *gateAnalysis) report(node ast.Node, coordinate string, related ...string) {
+	flags := map[string]bool{}
+	for _, c := range append([]string{coordinate}, related...) {
+		for flag := range a.flags[c] {
+			flags[flag] = true
+		}
+		for _, policy := range a.cfg.Policies {
+			if matchCoordinate(policy.CoordinatePattern, c) {
+				for _, flag := range conditionFlags(a.cfg, policy.Condition, policy.ConditionName) {
+					flags[flag] = true
+				}
+			}
+		}
+	}
+	reference := GatedReference{
+		Coordinate: coordinate,
+		Flags:      make([]string, 0, len(flags)),
+	}
+	for flag := range flags {
+		reference.Flags = append(reference.Flags, flag)
+	}
+	sort.Strings(reference.Flags)
+	if loc := node.GetLoc(); loc != nil {
+		l := location.GetLocation(a.source, loc.Start)
+		reference.Line, reference.Column = l.Line, l.Column
+	}
+	a.references = append(a.references, reference)
+}
+
+func (a *gateAnalysis) gated(coordinate string) bool {
+	_, ok := a.variant[coordinate]
+	return !ok
+}
+
+// typeCondition returns the named type, reporting it if it's gated.
+func (a *gateAnalysis) typeCondition(named *ast.Named) graphql.Type {
+	if named == nil || named.Name == nil {
+		return nil
+	}
+	t, ok := a.types[named.Name.Value]
+	if !ok {
+		return nil
+	}
+	if a.gated(t.Name()) {
+		a.report(named, t.Name())
+	}
+	return t
+}
+
+func (a *gateAnalysis) visitSelectionSet(parent graphql.Type, selectionSet *ast.SelectionSet) {
+	if selectionSet == nil {
+		return
+	}
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			var fields graphql.FieldDefinitionMap
+			switch parent := parent.(type) {
+			case *graphql.Object:
+				fields = parent.Fields()
+			case *graphql.Interface:
+				fields = parent.Fields()
+			}
+			def, ok := fields[selection.Name.Value]
+			if !ok {
+				continue
+			}
+			coordinate := parent.Name() + "." + def.Name
+			fieldType := namedType(unwrapConditionals(def.Type))
+			if a.gated(coordinate) && !a.gated(parent.Name()) {
+				a.report(selection, coordinate, fieldType.Name())
+			}
+			a.visitSelectionSet(fieldType, selection.SelectionSet)
+		case *ast.InlineFragment:
+			t := parent
+			if selection.TypeCondition != nil {
+				if t = a.typeCondition(selection.TypeCondition); t == nil {
+					continue
+				}
+			}
+			a.visitSelectionSet(t, selection.SelectionSet)
+		}
+	}
+}
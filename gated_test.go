@@ -0,0 +1,62 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestGatedReferences(t *testing.T) {
+	article := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: graphql.Fields{
+			"title":   &graphql.Field{Type: graphql.String},
+			"summary": BetaField(&graphql.Field{Type: graphql.String}),
+		},
+		IsTypeOf: func(graphql.IsTypeOfParams) bool { return true },
+	})
+	video := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Video",
+		Fields: graphql.Fields{
+			"title": &graphql.Field{Type: graphql.String},
+			"url":   &graphql.Field{Type: graphql.String},
+		},
+		IsTypeOf: func(graphql.IsTypeOfParams) bool { return true },
+	})
+	input := testInput(graphql.Fields{
+		"search": &graphql.Field{Type: graphql.NewList(graphql.NewUnion(graphql.UnionConfig{
+			Name:  "SearchResult",
+			Types: []*graphql.Object{article, video},
+		}))},
+	})
+	cfg := &PreprocessorConfig{
+		Policies: []Policy{{Name: "videos", CoordinatePattern: "Video", Condition: Feature("videos").Enabled}},
+	}
+	query := `{
+  search {
+    ... on Article { title summary }
+    ...VideoResult
+  }
+}
+
+fragment VideoResult on Video { title url }`
+
+	references, err := GatedReferences(input, cfg, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []GatedReference{
+		{Line: 3, Column: 28, Coordinate: "Article.summary", Flags: []string{"beta"}},
+		{Line: 8, Column: 25, Coordinate: "Video", Flags: []string{"videos"}},
+	}
+	if !reflect.DeepEqual(references, expected) {
+		t.Errorf("unexpected references %+v", references)
+	}
+
+	cfg.BetaFeaturesEnabled = true
+	cfg.Flags = map[string]bool{"videos": true}
+	if references, err := GatedReferences(input, cfg, query); err != nil || len(references) > 0 {
+		t.Errorf("unexpected references %+v, %v", references, err)
+	}
+}
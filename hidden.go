@@ -0,0 +1,126 @@
+package graphqlapi
+
+import (
+	"context"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+type hiddenFieldsRevealedContextKey struct{}
+
+// RevealHiddenFields returns a copy of ctx in which introspection includes fields hidden by
+// HidingSchema.
+func RevealHiddenFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hiddenFieldsRevealedContextKey{}, true)
+}
+
+// HidingSchema is a schema whose gated fields remain fully functional, but are hidden from
+// introspection. graphql-go's introspection types are shared by every schema in the process, so
+// rather than filtering them, introspection requests are executed against a public counterpart of
+// the schema that doesn't have the hidden fields.
+type HidingSchema struct {
+	// Schema includes the hidden fields, as if preprocessed with PreprocessorConfig.HideOnly.
+	Schema graphql.Schema
+
+	// Public is the schema without the hidden fields.
+	Public graphql.Schema
+
+	// The sorted coordinates of the hidden fields.
+	Hidden []string
+}
+
+// NewHidingSchema preprocesses the input under the config, hiding fields whose types are removed
+// by conditionals instead of removing them.
+func NewHidingSchema(input graphql.SchemaConfig, config *PreprocessorConfig) (_ *HidingSchema, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPreprocessingError(nil, r)
+		}
+	}()
+	full, public := *config, *config
+	full.HideOnly, public.HideOnly, public.SoftDisable = true, false, false
+
+	p := newPreprocessor(&full, nil)
+	result := p.preprocessSchemaConfig(input)
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		return nil, err
+	}
+	publicResult, err := PreprocessSchemaConfigE(input, &public)
+	if err != nil {
+		return nil, err
+	}
+	publicSchema, err := graphql.NewSchema(publicResult)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &HidingSchema{
+		Schema: schema,
+		Public: publicSchema,
+	}
+	for coordinate := range p.hidden {
+		s.Hidden = append(s.Hidden, coordinate)
+	}
+	sort.Strings(s.Hidden)
+	return s, nil
+}
+
+// Execute is like the package-level Execute, but requests that use introspection are executed
+// against Public unless their context was derived via RevealHiddenFields. Such requests can't
+// select hidden fields. Other requests are executed against Schema. params.Schema is ignored.
+func (s *HidingSchema) Execute(config *PreprocessorConfig, params graphql.Params) *graphql.Result {
+	return execute(config, params, func(document *ast.Document) graphql.Schema {
+		if params.Context != nil {
+			if revealed, _ := params.Context.Value(hiddenFieldsRevealedContextKey{}).(bool); revealed {
+				return s.Schema
+			}
+		}
+		if usesIntrospection(document) {
+			return s.Public
+		}
+		return s.Schema
+	}, VariantStats{})
+}
+
+// usesIntrospection returns whether any operation or fragment in the document selects __schema or
+// __type.
+func usesIntrospection(document *ast.Document) bool {
+	var visit func(set *ast.SelectionSet) bool
+	visit = func(set *ast.SelectionSet) bool {
+		if set == nil {
+			return false
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				if name := selection.Name.Value; name == "__schema" || name == "__type" {
+					return true
+				}
+				if visit(selection.SelectionSet) {
+					return true
+				}
+			case *ast.InlineFragment:
+				if visit(selection.SelectionSet) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if visit(definition.SelectionSet) {
+				return true
+			}
+		case *ast.FragmentDefinition:
+			if visit(definition.SelectionSet) {
+				return true
+			}
+		}
+	}
+	return false
+}
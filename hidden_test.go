@@ -0,0 +1,91 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func hidingTestInput() graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"public": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "public", nil
+			},
+		},
+		"secret": &graphql.Field{
+			Type: Flag("secret", graphql.String),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "secret", nil
+			},
+		},
+	})
+}
+
+func TestHidingSchema(t *testing.T) {
+	config := &PreprocessorConfig{}
+	s, err := NewHidingSchema(hidingTestInput(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Hidden) != 1 || s.Hidden[0] != "Query.secret" {
+		t.Fatalf("unexpected hidden fields: %v", s.Hidden)
+	}
+
+	result := s.Execute(config, graphql.Params{RequestString: `{ secret }`})
+	if len(result.Errors) > 0 || result.Data.(map[string]interface{})["secret"] != "secret" {
+		t.Fatalf("hidden field isn't callable: %v", result)
+	}
+
+	introspect := func(ctx context.Context) string {
+		result := s.Execute(config, graphql.Params{
+			RequestString: `{ __type(name: "Query") { fields { name } } }`,
+			Context:       ctx,
+		})
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors)
+		}
+		data, _ := json.Marshal(result.Data)
+		return string(data)
+	}
+	if data := introspect(context.Background()); strings.Contains(data, "secret") {
+		t.Errorf("introspection lists the hidden field: %v", data)
+	}
+	if data := introspect(RevealHiddenFields(context.Background())); !strings.Contains(data, "secret") {
+		t.Errorf("revealed introspection doesn't list the hidden field: %v", data)
+	}
+
+	// Other schemas are unaffected.
+	result = graphql.Do(graphql.Params{
+		Schema:        s.Schema,
+		RequestString: `{ __type(name: "Query") { fields { name } } }`,
+	})
+	if data, _ := json.Marshal(result.Data); !strings.Contains(string(data), "secret") {
+		t.Errorf("introspection of the full schema doesn't list the hidden field: %s", data)
+	}
+}
+
+func TestHidingSchemaConcurrentIntrospection(t *testing.T) {
+	config := &PreprocessorConfig{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			if _, err := NewHidingSchema(hidingTestInput(), config); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	s, err := NewHidingSchema(hidingTestInput(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		s.Execute(config, graphql.Params{RequestString: `{ __type(name: "Query") { fields { name } } }`})
+	}
+	<-done
+}
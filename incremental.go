@@ -0,0 +1,58 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+func (p *Preprocessor) preprocessor() *preprocessor {
+	if p.incremental == nil {
+		p.incremental = newPreprocessor(p.Config, nil)
+	}
+	return p.incremental
+}
+
+// Type preprocesses a single type, returning false if it's removed. Results are cached, so
+// preprocessing the same type again, directly or via another method, returns the same instance.
+// Unlike PreprocessSchemaConfig, the result's thunks are evaluated before it returns.
+func (p *Preprocessor) Type(t graphql.Type) (graphql.Type, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	result, ok := p.preprocessor().preprocessType(t)
+	if ok {
+		schemaTypes(graphql.SchemaConfig{
+			Types: []graphql.Type{result},
+		})
+	}
+	return result, ok
+}
+
+// Object preprocesses a single object like Type, returning nil if it's removed.
+func (p *Preprocessor) Object(obj *graphql.Object) *graphql.Object {
+	result, ok := p.Type(obj)
+	if !ok {
+		return nil
+	}
+	return result.(*graphql.Object)
+}
+
+// Field preprocesses a single field of the named parent type, returning false if it's removed. The
+// parent's name is used for the field's coordinate, e.g. when matching policies. The field's
+// types are cached like those returned by Type.
+func (p *Preprocessor) Field(parent string, def *graphql.FieldDefinition) (*graphql.Field, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	f, ok := p.preprocessor().preprocessField(parent, nil, def)
+	if !ok {
+		return nil, false
+	}
+	types := []graphql.Type{f.Type}
+	for _, arg := range f.Args {
+		types = append(types, arg.Type)
+	}
+	schemaTypes(graphql.SchemaConfig{
+		Types: types,
+	})
+	return f, true
+}
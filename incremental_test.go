@@ -0,0 +1,113 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestPreprocessorSharedCache(t *testing.T) {
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.ID},
+			"email": &graphql.Field{Type: Beta(graphql.String)},
+		},
+	})
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"viewer": &graphql.Field{Type: user},
+			"admin":  &graphql.Field{Type: Beta(user)},
+		},
+	})
+
+	for _, beta := range []bool{false, true} {
+		p := NewPreprocessor(&PreprocessorConfig{BetaFeaturesEnabled: beta})
+		byType, ok := p.Type(user)
+		if !ok {
+			t.Fatal("User was removed")
+		}
+		if byObject := p.Object(user); byObject != byType {
+			t.Errorf("beta %v: Object and Type returned different instances", beta)
+		}
+		field, ok := p.Field("Query", query.Fields()["viewer"])
+		if !ok || field.Type != byType {
+			t.Errorf("beta %v: Field and Type returned different instances", beta)
+		}
+		if viewer := p.Object(query).Fields()["viewer"]; viewer.Type != byType {
+			t.Errorf("beta %v: Query.viewer has a different instance", beta)
+		}
+		if _, ok := byType.(*graphql.Object).Fields()["email"]; ok != beta {
+			t.Errorf("beta %v: User.email present: %v", beta, ok)
+		}
+		if _, ok := p.Field("Query", query.Fields()["admin"]); ok != beta {
+			t.Errorf("beta %v: Query.admin kept: %v", beta, ok)
+		}
+	}
+}
+
+func TestPreprocessorTypeConcurrentWithExecution(t *testing.T) {
+	var widget *graphql.Object
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+			return widget
+		},
+	})
+	widget = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"node": &graphql.Field{
+				Type: node,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{"id": "1"}, nil
+				},
+			},
+		},
+	})
+
+	p := NewPreprocessor(&PreprocessorConfig{})
+	preprocessedWidget, _ := p.Type(widget)
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: p.Object(query),
+		Types: []graphql.Type{preprocessedWidget},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			p.Type(graphql.NewObject(graphql.ObjectConfig{
+				Name: fmt.Sprintf("Other%v", i),
+				Fields: graphql.Fields{
+					"id": &graphql.Field{Type: graphql.ID},
+				},
+			}))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		result := graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: `{ node { id ... on Widget { id } } }`,
+		})
+		if len(result.Errors) > 0 {
+			t.Fatal(result.Errors)
+		}
+	}
+	<-done
+}
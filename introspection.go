@@ -0,0 +1,370 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+type introspectionInputValue struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionField struct {
+	Name              string                    `json:"name"`
+	Description       string                    `json:"description"`
+	Args              []introspectionInputValue `json:"args"`
+	Type              introspectionTypeRef      `json:"type"`
+	DeprecationReason string                    `json:"deprecationReason"`
+}
+
+type introspectionEnumValue struct {
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	DeprecationReason string `json:"deprecationReason"`
+}
+
+type introspectionType struct {
+	Kind          string                    `json:"kind"`
+	Name          string                    `json:"name"`
+	Description   string                    `json:"description"`
+	Fields        []introspectionField      `json:"fields"`
+	InputFields   []introspectionInputValue `json:"inputFields"`
+	Interfaces    []introspectionTypeRef    `json:"interfaces"`
+	EnumValues    []introspectionEnumValue  `json:"enumValues"`
+	PossibleTypes []introspectionTypeRef    `json:"possibleTypes"`
+}
+
+type introspectionNamedRef struct {
+	Name string `json:"name"`
+}
+
+type introspectionSchema struct {
+	QueryType        *introspectionNamedRef  `json:"queryType"`
+	MutationType     *introspectionNamedRef  `json:"mutationType"`
+	SubscriptionType *introspectionNamedRef  `json:"subscriptionType"`
+	Types            []introspectionType     `json:"types"`
+	Directives       []introspectionNamedRef `json:"directives"`
+}
+
+var builtInScalars = map[string]*graphql.Scalar{
+	"String":   graphql.String,
+	"Int":      graphql.Int,
+	"Float":    graphql.Float,
+	"Boolean":  graphql.Boolean,
+	"ID":       graphql.ID,
+	"DateTime": graphql.DateTime,
+}
+
+type importer struct {
+	types     map[string]graphql.Type
+	onWarning func(err error)
+
+	// Gates by coordinate, for schemas built from SDL. See PreprocessSDL.
+	gates map[string]sdlGate
+}
+
+// FromIntrospection reconstructs a schema config from the JSON result of an introspection query.
+// Resolvers are left nil for the caller to attach. See FromIntrospectionWithWarnings.
+func FromIntrospection(data []byte) (graphql.SchemaConfig, error) {
+	return FromIntrospectionWithWarnings(data, nil)
+}
+
+// FromIntrospectionWithWarnings is like FromIntrospection, but invokes onWarning for anything that
+// can't be reconstructed faithfully. Custom scalars get stub coercion that passes values through,
+// and non-standard directives are dropped. Objects get an IsTypeOf function that matches map values
+// with a matching "__typename" entry.
+func FromIntrospectionWithWarnings(data []byte, onWarning func(err error)) (config graphql.SchemaConfig, err error) {
+	var envelope struct {
+		Data *struct {
+			Schema *introspectionSchema `json:"__schema"`
+		} `json:"data"`
+		Schema *introspectionSchema `json:"__schema"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return config, err
+	}
+	schema := envelope.Schema
+	if schema == nil && envelope.Data != nil {
+		schema = envelope.Data.Schema
+	}
+	if schema == nil {
+		return config, fmt.Errorf("no __schema found in introspection result")
+	}
+	return buildSchemaConfig(schema, nil, onWarning)
+}
+
+func buildSchemaConfig(schema *introspectionSchema, gates map[string]sdlGate, onWarning func(err error)) (config graphql.SchemaConfig, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid introspection result: %v", r)
+		}
+	}()
+
+	im := &importer{
+		types:     map[string]graphql.Type{},
+		onWarning: onWarning,
+		gates:     gates,
+	}
+	for name, scalar := range builtInScalars {
+		im.types[name] = scalar
+	}
+	for _, directive := range schema.Directives {
+		switch directive.Name {
+		case "skip", "include", "deprecated":
+		default:
+			im.warn(fmt.Errorf("directive @%v isn't supported and was dropped", directive.Name))
+		}
+	}
+
+	// Unions need their member objects to exist, so they're created last.
+	for _, kind := range []string{"SCALAR", "ENUM", "INPUT_OBJECT", "INTERFACE", "OBJECT", "UNION"} {
+		for _, t := range schema.Types {
+			if t.Kind == kind && !strings.HasPrefix(t.Name, "__") {
+				im.types[t.Name] = im.newType(t)
+			}
+		}
+	}
+
+	root := func(ref *introspectionNamedRef) *graphql.Object {
+		if ref == nil {
+			return nil
+		}
+		obj, ok := im.types[ref.Name].(*graphql.Object)
+		if !ok {
+			panic(fmt.Errorf("root type %v isn't an object", ref.Name))
+		}
+		return obj
+	}
+	config.Query = root(schema.QueryType)
+	config.Mutation = root(schema.MutationType)
+	config.Subscription = root(schema.SubscriptionType)
+	for _, t := range schema.Types {
+		if _, ok := builtInScalars[t.Name]; ok || strings.HasPrefix(t.Name, "__") {
+			continue
+		}
+		if obj, ok := im.types[t.Name].(*graphql.Object); ok && (obj == config.Query || obj == config.Mutation || obj == config.Subscription) {
+			continue
+		}
+		config.Types = append(config.Types, im.gate(t.Name, im.types[t.Name]))
+	}
+
+	// Force the thunks so that unknown type references are reported here.
+	schemaTypes(config)
+	return config, nil
+}
+
+func (im *importer) warn(err error) {
+	if im.onWarning != nil {
+		im.onWarning(err)
+	}
+}
+
+// gate wraps the type in a conditional if the element at the coordinate is gated.
+func (im *importer) gate(coordinate string, t graphql.Type) graphql.Type {
+	if gate, ok := im.gates[coordinate]; ok {
+		return gate.conditional(t)
+	}
+	return t
+}
+
+// typeRef returns the referenced type. References to gated types are wrapped in conditionals.
+func (im *importer) typeRef(ref *introspectionTypeRef) graphql.Type {
+	switch ref.Kind {
+	case "LIST":
+		return graphql.NewList(im.typeRef(ref.OfType))
+	case "NON_NULL":
+		return graphql.NewNonNull(im.typeRef(ref.OfType))
+	}
+	return im.gate(ref.Name, im.namedType(ref.Name))
+}
+
+func (im *importer) namedType(name string) graphql.Type {
+	t, ok := im.types[name]
+	if !ok {
+		panic(fmt.Errorf("unknown type %v", name))
+	}
+	return t
+}
+
+func (im *importer) fields(parent string, fields []introspectionField) graphql.Fields {
+	result := graphql.Fields{}
+	for _, f := range fields {
+		coordinate := parent + "." + f.Name
+		field := &graphql.Field{
+			Name:              f.Name,
+			Type:              im.gate(coordinate, im.typeRef(&f.Type)),
+			Description:       f.Description,
+			DeprecationReason: f.DeprecationReason,
+		}
+		if len(f.Args) > 0 {
+			field.Args = graphql.FieldConfigArgument{}
+			for _, arg := range f.Args {
+				field.Args[arg.Name] = &graphql.ArgumentConfig{
+					Type:         im.gate(coordinate+"("+arg.Name+":)", im.typeRef(&arg.Type)),
+					DefaultValue: im.defaultValue(arg.DefaultValue),
+					Description:  arg.Description,
+				}
+			}
+		}
+		result[f.Name] = field
+	}
+	return result
+}
+
+func (im *importer) newType(t introspectionType) graphql.Type {
+	switch t.Kind {
+	case "SCALAR":
+		if scalar, ok := builtInScalars[t.Name]; ok {
+			return scalar
+		}
+		im.warn(fmt.Errorf("custom scalar %v uses stub coercion", t.Name))
+		return graphql.NewScalar(graphql.ScalarConfig{
+			Name:        t.Name,
+			Description: t.Description,
+			Serialize:   func(value interface{}) interface{} { return value },
+			ParseValue:  func(value interface{}) interface{} { return value },
+			ParseLiteral: func(value ast.Value) interface{} {
+				return literalValue(value)
+			},
+		})
+	case "ENUM":
+		values := graphql.EnumValueConfigMap{}
+		for _, value := range t.EnumValues {
+			values[value.Name] = &graphql.EnumValueConfig{
+				Value:             value.Name,
+				Description:       value.Description,
+				DeprecationReason: value.DeprecationReason,
+			}
+			if gate, ok := im.gates[t.Name+"."+value.Name]; ok {
+				values[value.Name] = gate.enumValue(values[value.Name])
+			}
+		}
+		return graphql.NewEnum(graphql.EnumConfig{
+			Name:        t.Name,
+			Description: t.Description,
+			Values:      values,
+		})
+	case "INPUT_OBJECT":
+		return graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:        t.Name,
+			Description: t.Description,
+			Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+				fields := graphql.InputObjectConfigFieldMap{}
+				for _, f := range t.InputFields {
+					fields[f.Name] = &graphql.InputObjectFieldConfig{
+						Type:         im.gate(t.Name+"."+f.Name, im.typeRef(&f.Type)),
+						DefaultValue: im.defaultValue(f.DefaultValue),
+						Description:  f.Description,
+					}
+				}
+				return fields
+			}),
+		})
+	case "INTERFACE":
+		return graphql.NewInterface(graphql.InterfaceConfig{
+			Name:        t.Name,
+			Description: t.Description,
+			Fields: graphql.FieldsThunk(func() graphql.Fields {
+				return im.fields(t.Name, t.Fields)
+			}),
+		})
+	case "OBJECT":
+		name := t.Name
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name:        t.Name,
+			Description: t.Description,
+			Interfaces: graphql.InterfacesThunk(func() []*graphql.Interface {
+				var ifaces []*graphql.Interface
+				for _, ref := range t.Interfaces {
+					ifaces = append(ifaces, im.namedType(ref.Name).(*graphql.Interface))
+				}
+				return ifaces
+			}),
+			Fields: graphql.FieldsThunk(func() graphql.Fields {
+				return im.fields(t.Name, t.Fields)
+			}),
+			IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+				m, ok := p.Value.(map[string]interface{})
+				return ok && m["__typename"] == name
+			},
+		})
+	case "UNION":
+		config := graphql.UnionConfig{
+			Name:        t.Name,
+			Description: t.Description,
+		}
+		for _, ref := range t.PossibleTypes {
+			config.Types = append(config.Types, im.namedType(ref.Name).(*graphql.Object))
+		}
+		return graphql.NewUnion(config)
+	}
+	panic(fmt.Errorf("type %v has unknown kind %v", t.Name, t.Kind))
+}
+
+// defaultValue converts a default value literal to the shape graphql-go uses for coerced values.
+func (im *importer) defaultValue(literal *string) interface{} {
+	if literal == nil {
+		return nil
+	}
+	// The parser doesn't expose value parsing, so the literal is parsed as an argument.
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte("{f(v: " + *literal + ")}"),
+			Name: "default value",
+		}),
+	})
+	if err != nil {
+		im.warn(fmt.Errorf("unable to parse default value %v: %v", *literal, err))
+		return nil
+	}
+	value := document.Definitions[0].(*ast.OperationDefinition).SelectionSet.Selections[0].(*ast.Field).Arguments[0].Value
+	return literalValue(value)
+}
+
+func literalValue(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		if n, err := strconv.Atoi(value.Value); err == nil {
+			return n
+		}
+	case *ast.FloatValue:
+		if f, err := strconv.ParseFloat(value.Value, 64); err == nil {
+			return f
+		}
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.ListValue:
+		list := make([]interface{}, len(value.Values))
+		for i, v := range value.Values {
+			list[i] = literalValue(v)
+		}
+		return list
+	case *ast.ObjectValue:
+		object := map[string]interface{}{}
+		for _, f := range value.Fields {
+			object[f.Name.Value] = literalValue(f.Value)
+		}
+		return object
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/testutil"
+)
+
+func introspectionTestInput() graphql.SchemaConfig {
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        "Node",
+		Description: "An object with an ID.",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object { return nil },
+	})
+	color := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED":   &graphql.EnumValueConfig{Value: "red", Description: "Warm."},
+			"BLUE":  &graphql.EnumValueConfig{Value: "blue"},
+			"GREEN": &graphql.EnumValueConfig{Value: "green", DeprecationReason: "Use BLUE."},
+		},
+	})
+	url := graphql.NewScalar(graphql.ScalarConfig{
+		Name:         "URL",
+		Serialize:    func(value interface{}) interface{} { return value },
+		ParseValue:   func(value interface{}) interface{} { return value },
+		ParseLiteral: func(ast.Value) interface{} { return nil },
+	})
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"color":    &graphql.Field{Type: color},
+			"homepage": &graphql.Field{Type: url, DeprecationReason: "No longer maintained."},
+			"tags":     &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+	gadget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Gadget",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String, Description: "The gadget's name."},
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "WidgetFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			// graphql-go introspects enum defaults by their internal values, so they can't round trip.
+			"color": &graphql.InputObjectFieldConfig{Type: color},
+			"tags":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+			"limit": &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: 10},
+		},
+	})
+	return graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"node": &graphql.Field{Type: node, Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				}},
+				"widgets": &graphql.Field{Type: graphql.NewList(widget), Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: filter},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 5, Description: "The page size."},
+				}},
+				"thing": &graphql.Field{Type: graphql.NewUnion(graphql.UnionConfig{
+					Name:        "Thing",
+					Types:       []*graphql.Object{widget, gadget},
+					ResolveType: func(graphql.ResolveTypeParams) *graphql.Object { return nil },
+				})},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"paint": &graphql.Field{Type: widget, Args: graphql.FieldConfigArgument{
+					"color": &graphql.ArgumentConfig{Type: graphql.NewNonNull(color)},
+				}},
+			},
+		}),
+		Directives: append(graphql.SpecifiedDirectives, graphql.NewDirective(graphql.DirectiveConfig{
+			Name:      "cached",
+			Locations: []string{graphql.DirectiveLocationField},
+		})),
+	}
+}
+
+func TestFromIntrospectionRoundTrip(t *testing.T) {
+	input := introspectionTestInput()
+	schema, err := graphql.NewSchema(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{Schema: schema, RequestString: testutil.IntrospectionQuery})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []string
+	imported, err := FromIntrospectionWithWarnings(data, func(err error) {
+		warnings = append(warnings, err.Error())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "@cached") || !strings.Contains(warnings[1], "URL") {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+
+	// Directives aren't imported, so they're excluded from the comparison.
+	input.Directives = nil
+	for _, beta := range []bool{false, true} {
+		config := &PreprocessorConfig{BetaFeaturesEnabled: beta}
+		original, err := PreprocessSchemaConfigE(input, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTripped, err := PreprocessSchemaConfigE(imported, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := graphql.NewSchema(roundTripped); err != nil {
+			t.Fatal(err)
+		}
+		expected, err := SchemaConfigToSDL(original)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sdl, err := SchemaConfigToSDL(roundTripped)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sdl != expected {
+			t.Errorf("the imported schema differs:\n%v\n%v", expected, sdl)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultMaxPreprocessingDepth = 1000
+	defaultMaxPreprocessedTypes  = 100000
+)
+
+func limit(configured, defaultLimit int) int {
+	if configured == 0 {
+		return defaultLimit
+	}
+	return configured
+}
+
+// enter pushes a type or field onto the path being preprocessed, enforcing the depth limit. The
+// returned function pops it, and must be deferred so that panics can be annotated with the path.
+func (p *preprocessor) enter(element string) func() {
+	p.path = append(p.path, element)
+	if max := limit(p.Config.MaxPreprocessingDepth, defaultMaxPreprocessingDepth); max > 0 && len(p.path) > max {
+		panic(&PreprocessingError{
+			Path: append([]string(nil), p.path...),
+			Err:  fmt.Errorf("preprocessing exceeded the maximum depth of %v", max),
+		})
+	}
+	return func() {
+		r := recover()
+		if r != nil {
+			r = newPreprocessingError(p.path, r)
+		}
+		p.path = p.path[:len(p.path)-1]
+		if r != nil {
+			panic(r)
+		}
+	}
+}
+
+// pathSnapshot returns a copy of the path being preprocessed, to be restored by resume.
+func (p *preprocessor) pathSnapshot() []string {
+	return append([]string(nil), p.path...)
+}
+
+// resume restores a path captured by pathSnapshot while a thunk of a preprocessed type is
+// evaluated, so that the depth limit and error paths span the types the thunk's type was reached
+// through. The returned function restores the current path, and must be deferred.
+func (p *preprocessor) resume(path []string) func() {
+	current := p.path
+	p.path = append([]string(nil), path...)
+	return func() {
+		p.path = current
+	}
+}
+
+func (p *preprocessor) checkTypeCount() {
+	if max := limit(p.Config.MaxPreprocessedTypes, defaultMaxPreprocessedTypes); max > 0 && len(p.OriginalTypes) > max {
+		panic(fmt.Errorf("preprocessing exceeded the maximum of %v types", max))
+	}
+}
+
+// walkLimits returns a walkSchemaTypes hook that enforces the config's limits on the types being
+// walked, so that forcing the thunks of runaway input types fails like preprocessing them would. If
+// the config is nil, the defaults are used.
+func walkLimits(config *PreprocessorConfig) func(graphql.Type, int) func() {
+	maxDepth, maxTypes := defaultMaxPreprocessingDepth, defaultMaxPreprocessedTypes
+	if config != nil {
+		maxDepth = limit(config.MaxPreprocessingDepth, maxDepth)
+		maxTypes = limit(config.MaxPreprocessedTypes, maxTypes)
+	}
+	var path []string
+	return func(t graphql.Type, count int) func() {
+		path = append(path, t.Name())
+		var err error
+		if maxDepth > 0 && len(path) > maxDepth {
+			err = fmt.Errorf("preprocessing exceeded the maximum depth of %v", maxDepth)
+		} else if maxTypes > 0 && count > maxTypes {
+			err = fmt.Errorf("preprocessing exceeded the maximum of %v types", maxTypes)
+		}
+		if err != nil {
+			panic(&PreprocessingError{
+				Path: append([]string(nil), path...),
+				Err:  err,
+			})
+		}
+		return func() {
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+func pathString(path []string) string {
+	if len(path) > 20 {
+		return strings.Join(path[:10], " -> ") + " -> ... -> " + strings.Join(path[len(path)-10:], " -> ")
+	}
+	return strings.Join(path, " -> ")
+}
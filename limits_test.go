@@ -0,0 +1,89 @@
+package graphqlapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// limitsTestChain generates a chain of objects from Link<i> to Link<n>. If n is negative, the
+// chain is endless, as a buggy thunk might generate.
+func limitsTestChain(i, n int) graphql.SchemaConfig {
+	var link func(i int) *graphql.Object
+	link = func(i int) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: fmt.Sprintf("Link%v", i),
+			Fields: graphql.FieldsThunk(func() graphql.Fields {
+				if i == n {
+					return graphql.Fields{"end": &graphql.Field{Type: graphql.Boolean}}
+				}
+				return graphql.Fields{"next": &graphql.Field{Type: link(i + 1)}}
+			}),
+		})
+	}
+	return testInput(graphql.Fields{
+		"chain": &graphql.Field{Type: link(i)},
+	})
+}
+
+// limitsTestWide generates a query with n fields of distinct types.
+func limitsTestWide(n int) graphql.SchemaConfig {
+	fields := graphql.Fields{}
+	for i := 0; i < n; i++ {
+		fields[fmt.Sprintf("f%v", i)] = &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+			Name: fmt.Sprintf("Wide%v", i),
+			Fields: graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+			},
+		})}
+	}
+	return testInput(fields)
+}
+
+func limitsTestError(t *testing.T, input graphql.SchemaConfig, config *PreprocessorConfig, expected string) []string {
+	_, err := PreprocessSchemaConfigE(input, config)
+	var perr *PreprocessingError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a preprocessing error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected an error containing %q, got %v", expected, err)
+	}
+	return perr.Path
+}
+
+func TestPreprocessingDepthLimit(t *testing.T) {
+	// The endless chain is caught while the thunks of its preprocessed types are evaluated, since
+	// each thunk resumes the path its type was reached through.
+	path := limitsTestError(t, limitsTestChain(0, -1), &PreprocessorConfig{MaxPreprocessingDepth: 50}, "maximum depth of 50")
+	if len(path) != 51 || path[0] != "Query" || path[2] != "Link0" || path[49] != "Link23.next" || path[50] != "Link24" {
+		t.Errorf("unexpected path %q", path)
+	}
+
+	path = limitsTestError(t, limitsTestChain(0, 30), &PreprocessorConfig{MaxPreprocessingDepth: 20}, "maximum depth of 20")
+	if len(path) != 21 || path[20] != "Link9" {
+		t.Errorf("unexpected path %q", path)
+	}
+	if _, err := PreprocessSchemaConfigE(limitsTestChain(0, 30), &PreprocessorConfig{MaxPreprocessingDepth: -1}); err != nil {
+		t.Error(err)
+	}
+	if _, err := PreprocessSchemaConfigE(limitsTestChain(0, 30), &PreprocessorConfig{}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPreprocessedTypesLimit(t *testing.T) {
+	path := limitsTestError(t, limitsTestWide(20), &PreprocessorConfig{MaxPreprocessedTypes: 10}, "maximum of 10 types")
+	if len(path) != 3 || path[0] != "Query" || !strings.HasPrefix(path[2], "Wide") {
+		t.Errorf("unexpected path %q", path)
+	}
+	if _, err := PreprocessSchemaConfigE(limitsTestWide(20), &PreprocessorConfig{MaxPreprocessedTypes: -1}); err != nil {
+		t.Error(err)
+	}
+	if _, err := PreprocessSchemaConfigE(limitsTestWide(20), &PreprocessorConfig{}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,142 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+const defaultMaxCombinations = 1024
+
+type MatrixOptions struct {
+	// Base is copied for each combination before flags are applied. If nil, the zero config is
+	// used.
+	Base *PreprocessorConfig
+
+	// SetFlag enables or disables the named flag on the config. If nil, the flag is set in the
+	// config's Flags.
+	SetFlag func(cfg *PreprocessorConfig, flag string, enabled bool) error
+
+	// If non-empty, only these combinations are checked. Each subset lists the enabled flags and
+	// all other flags are disabled.
+	Subsets [][]string
+
+	// MaxCombinations limits the number of combinations enumerated when Subsets is empty. If zero,
+	// a default of 1024 is used.
+	MaxCombinations int
+
+	// MaxFindings limits the number of findings reported per combination. If zero, all findings
+	// are reported.
+	MaxFindings int
+}
+
+type CombinationReport struct {
+	Enabled  []string
+	Valid    bool
+	Findings []string
+}
+
+func setFlag(cfg *PreprocessorConfig, flag string, enabled bool) error {
+	if field, ok := stages[flag]; ok {
+		*field(cfg) = enabled
+	}
+	cfg.Flags[flag] = enabled
+	return nil
+}
+
+// ValidateCombinations preprocesses the input under combinations of the given flags and reports
+// which combinations produce invalid schemas. Reports are ordered by combination, treating the
+// first flag as the least significant bit.
+func ValidateCombinations(input graphql.SchemaConfig, flags []string, opts MatrixOptions) ([]CombinationReport, error) {
+	set := opts.SetFlag
+	if set == nil {
+		set = setFlag
+	}
+
+	var combinations [][]string
+	if len(opts.Subsets) > 0 {
+		combinations = opts.Subsets
+	} else {
+		max := opts.MaxCombinations
+		if max == 0 {
+			max = defaultMaxCombinations
+		}
+		if len(flags) >= 31 || 1<<uint(len(flags)) > max {
+			return nil, fmt.Errorf("%v flags produce more than %v combinations", len(flags), max)
+		}
+		for i := 0; i < 1<<uint(len(flags)); i++ {
+			enabled := []string{}
+			for j, flag := range flags {
+				if i&(1<<uint(j)) != 0 {
+					enabled = append(enabled, flag)
+				}
+			}
+			combinations = append(combinations, enabled)
+		}
+	}
+
+	var configs []*PreprocessorConfig
+	for _, enabled := range combinations {
+		cfg := &PreprocessorConfig{}
+		if opts.Base != nil {
+			*cfg = *opts.Base
+		}
+		cfg.Flags = make(map[string]bool, len(cfg.Flags))
+		if opts.Base != nil {
+			for flag, enabled := range opts.Base.Flags {
+				cfg.Flags[flag] = enabled
+			}
+		}
+		isEnabled := map[string]bool{}
+		for _, flag := range enabled {
+			isEnabled[flag] = true
+		}
+		for _, flag := range flags {
+			if err := set(cfg, flag, isEnabled[flag]); err != nil {
+				return nil, err
+			}
+		}
+		for _, flag := range enabled {
+			if err := set(cfg, flag, true); err != nil {
+				return nil, err
+			}
+		}
+		configs = append(configs, cfg)
+	}
+
+	// Types that don't depend on any condition are preprocessed once and shared by every
+	// combination, as with PreprocessSchemaConfigs.
+	checkInputLimits(input, opts.Base)
+	shareable := shareableTypes(input, configs)
+	sharing := newTypeSharing()
+	var reports []CombinationReport
+	for i, cfg := range configs {
+		findings := validateVariant(input, cfg, sharing, shareable)
+		if opts.MaxFindings > 0 && len(findings) > opts.MaxFindings {
+			findings = findings[:opts.MaxFindings]
+		}
+		reports = append(reports, CombinationReport{
+			Enabled:  combinations[i],
+			Valid:    len(findings) == 0,
+			Findings: findings,
+		})
+	}
+	return reports, nil
+}
+
+func validateVariant(input graphql.SchemaConfig, config *PreprocessorConfig, sharing *typeSharing, shareable map[string]bool) (findings []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			findings = append(findings, fmt.Sprint(r))
+		}
+	}()
+	result, err := sharing.preprocess(input, config, shareable)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer sharing.forget(result.Query)
+	if _, err := graphql.NewSchema(result); err != nil {
+		findings = append(findings, err.Error())
+	}
+	return findings
+}
@@ -0,0 +1,70 @@
+package graphqlapi
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestValidateCombinations(t *testing.T) {
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+			"x":  &graphql.Field{Type: Flag("a", graphql.String)},
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+			return nil
+		},
+	})
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+			"x":  &graphql.Field{Type: Flag("b", graphql.String)},
+		},
+	})
+	gadget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Gadget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"node":   &graphql.Field{Type: node},
+		"gadget": &graphql.Field{Type: gadget},
+	}, widget)
+
+	gadgets := 0
+	reports, err := ValidateCombinations(input, []string{"a", "b"}, MatrixOptions{
+		Base: &PreprocessorConfig{
+			TypeVisitors: []func(original, preprocessed graphql.Type) graphql.Type{
+				func(original, preprocessed graphql.Type) graphql.Type {
+					if original == gadget {
+						gadgets++
+					}
+					return preprocessed
+				},
+			},
+		},
+		MaxFindings: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 4 {
+		t.Fatalf("%v reports", len(reports))
+	}
+	for i, report := range reports {
+		if expected := i != 1; report.Valid != expected {
+			t.Errorf("combination %v: valid is %v: %v", report.Enabled, report.Valid, report.Findings)
+		}
+		if !report.Valid && len(report.Findings) != 1 {
+			t.Errorf("combination %v has %v findings", report.Enabled, len(report.Findings))
+		}
+	}
+	if gadgets != 1 {
+		t.Errorf("the unaffected type was preprocessed %v times", gadgets)
+	}
+}
@@ -0,0 +1,47 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// FieldInfo describes the field that resolver middleware is being applied to.
+type FieldInfo struct {
+	// ParentType is the name of the object or interface that the field belongs to.
+	ParentType string
+
+	// FieldName is the name of the field.
+	FieldName string
+
+	// Conditional is true if the field's type is conditional, i.e. the field is only present in
+	// some variants of the schema.
+	Conditional bool
+}
+
+// applyResolverMiddleware applies the config's ResolverMiddleware to a wrapped resolver, with the
+// first middleware outermost. If the field has no resolver, next is nil and middleware returning
+// nil is skipped.
+func (p *preprocessor) applyResolverMiddleware(field FieldInfo, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	middleware := p.Config.ResolverMiddleware
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if next := middleware[i](resolve, field); next != nil {
+			resolve = next
+		}
+	}
+	return resolve
+}
+
+// hasConditionals returns whether the type or any type it wraps is conditional.
+func hasConditionals(t graphql.Type) bool {
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.List:
+			t = wrapper.OfType
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		case *Conditional, *FallbackType:
+			return true
+		default:
+			return false
+		}
+	}
+}
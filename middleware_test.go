@@ -0,0 +1,80 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	layer := func(name string) func(graphql.FieldResolveFn) graphql.FieldResolveFn {
+		return func(next graphql.FieldResolveFn) graphql.FieldResolveFn {
+			return func(p graphql.ResolveParams) (interface{}, error) {
+				calls = append(calls, name)
+				return next(p)
+			}
+		}
+	}
+	resolve := func(graphql.ResolveParams) (interface{}, error) {
+		calls = append(calls, "resolver")
+		return 1, nil
+	}
+	stats := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AdminStats",
+		Fields: graphql.Fields{
+			"users":  &graphql.Field{Type: graphql.Int, Resolve: resolve},
+			"orders": &graphql.Field{Type: graphql.Int, Resolve: resolve},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"stats": &graphql.Field{
+			Type: stats,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return struct{}{}, nil
+			},
+		},
+	})
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		ResolverMiddleware: []func(graphql.FieldResolveFn, FieldInfo) graphql.FieldResolveFn{
+			func(next graphql.FieldResolveFn, field FieldInfo) graphql.FieldResolveFn {
+				if field.ParentType != "AdminStats" {
+					return next
+				}
+				return layer("global")(next)
+			},
+			func(next graphql.FieldResolveFn, field FieldInfo) graphql.FieldResolveFn {
+				if field.ParentType+"."+field.FieldName != "AdminStats.users" {
+					return next
+				}
+				return layer("AdminStats.users")(next)
+			},
+		},
+		TypeMiddleware: map[string][]func(graphql.FieldResolveFn) graphql.FieldResolveFn{
+			"AdminStats": {layer("AdminStats 1"), layer("AdminStats 2")},
+			"Admin*":     {layer("Admin*")},
+			"Billing*":   {layer("Billing*")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for query, expected := range map[string][]string{
+		"{ stats { users } }":  {"global", "AdminStats.users", "Admin*", "AdminStats 1", "AdminStats 2", "resolver"},
+		"{ stats { orders } }": {"global", "Admin*", "AdminStats 1", "AdminStats 2", "resolver"},
+	} {
+		calls = nil
+		if response := graphql.Do(graphql.Params{Schema: schema, RequestString: query}); len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("%v: unexpected calls %v", query, calls)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+type MockGenerators struct {
+	// Seed makes generated data deterministic. Two executions of the same query against mocks with
+	// the same seed produce the same response.
+	Seed int64
+
+	// Scalars overrides the generator for scalars with the given names.
+	Scalars map[string]func(r *rand.Rand) interface{}
+
+	// Coordinates overrides the resolver for the given field coordinates (e.g. "Query.widget").
+	Coordinates map[string]graphql.FieldResolveFn
+
+	// ListLength is the number of elements generated for lists. If zero, two elements are
+	// generated.
+	ListLength int
+}
+
+type mockObject struct {
+	Object *graphql.Object
+}
+
+var defaultMockScalars = map[string]func(r *rand.Rand) interface{}{
+	"String":   func(r *rand.Rand) interface{} { return fmt.Sprintf("string%v", r.Intn(1000)) },
+	"ID":       func(r *rand.Rand) interface{} { return fmt.Sprintf("%v", r.Intn(1000000)) },
+	"Int":      func(r *rand.Rand) interface{} { return r.Intn(1000) },
+	"Float":    func(r *rand.Rand) interface{} { return float64(r.Intn(100000)) / 100 },
+	"Boolean":  func(r *rand.Rand) interface{} { return r.Intn(2) == 1 },
+	"DateTime": func(r *rand.Rand) interface{} { return time.Unix(r.Int63n(2000000000), 0).UTC() },
+}
+
+// MockSchema preprocesses the input and builds a schema whose resolvers return generated data
+// appropriate for each field's preprocessed type. Fields with examples in the config return them.
+// The config's PassthroughTypes are ignored, so the input's types are never modified.
+func MockSchema(input graphql.SchemaConfig, cfg *PreprocessorConfig, gen MockGenerators) (graphql.Schema, error) {
+	// Passthrough types would be returned as-is, and the resolvers of the caller's objects
+	// replaced below. Preprocessing them makes every object modified here a copy.
+	copied := *cfg
+	copied.PassthroughTypes = nil
+	config := PreprocessSchemaConfig(input, &copied)
+	types := schemaTypes(config)
+
+	implementations := map[string][]*graphql.Object{}
+	for _, t := range types {
+		if obj, ok := t.(*graphql.Object); ok {
+			for _, iface := range obj.Interfaces() {
+				implementations[iface.Name()] = append(implementations[iface.Name()], obj)
+			}
+		}
+	}
+
+	for _, objects := range implementations {
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].Name() < objects[j].Name()
+		})
+	}
+
+	m := &mocker{
+		gen:             gen,
+		examples:        cfg.Examples,
+		implementations: implementations,
+	}
+	for _, t := range types {
+		switch t := t.(type) {
+		case *graphql.Object:
+			t.IsTypeOf = nil
+			for name, def := range t.Fields() {
+				def.Resolve = m.resolver(t.Name()+"."+name, def.Type)
+			}
+		case *graphql.Interface:
+			t.ResolveType = resolveMockObject
+		case *graphql.Union:
+			t.ResolveType = resolveMockObject
+		}
+	}
+	return graphql.NewSchema(config)
+}
+
+func resolveMockObject(params graphql.ResolveTypeParams) *graphql.Object {
+	if obj, ok := params.Value.(mockObject); ok {
+		return obj.Object
+	}
+	return nil
+}
+
+type mocker struct {
+	gen             MockGenerators
+	examples        map[string]interface{}
+	implementations map[string][]*graphql.Object
+}
+
+func (m *mocker) resolver(coordinate string, t graphql.Type) graphql.FieldResolveFn {
+	if resolve, ok := m.gen.Coordinates[coordinate]; ok {
+		return resolve
+	}
+	if example, ok := m.examples[coordinate]; ok {
+		return func(params graphql.ResolveParams) (interface{}, error) {
+			return example, nil
+		}
+	}
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		h := fnv.New64a()
+		if params.Info.Path != nil {
+			fmt.Fprint(h, params.Info.Path.AsArray()...)
+		}
+		r := rand.New(rand.NewSource(m.gen.Seed ^ int64(h.Sum64())))
+		return m.value(r, t)
+	}
+}
+
+func (m *mocker) value(r *rand.Rand, t graphql.Type) (interface{}, error) {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return m.value(r, t.OfType)
+	case *graphql.List:
+		n := m.gen.ListLength
+		if n == 0 {
+			n = 2
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			v, err := m.value(r, t.OfType)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case *graphql.Scalar:
+		if gen, ok := m.gen.Scalars[t.Name()]; ok {
+			return gen(r), nil
+		}
+		if gen, ok := defaultMockScalars[t.Name()]; ok {
+			return gen(r), nil
+		}
+		return nil, fmt.Errorf("no mock generator for scalar %v", t.Name())
+	case *graphql.Enum:
+		values := append([]*graphql.EnumValueDefinition(nil), t.Values()...)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("enum %v has no values", t.Name())
+		}
+		sort.Slice(values, func(i, j int) bool {
+			return values[i].Name < values[j].Name
+		})
+		return values[r.Intn(len(values))].Value, nil
+	case *graphql.Object:
+		return mockObject{t}, nil
+	case *graphql.Interface:
+		return m.abstractValue(r, t.Name(), m.implementations[t.Name()])
+	case *graphql.Union:
+		return m.abstractValue(r, t.Name(), t.Types())
+	}
+	return nil, fmt.Errorf("cannot mock type %v", t)
+}
+
+func (m *mocker) abstractValue(r *rand.Rand, name string, possibleTypes []*graphql.Object) (interface{}, error) {
+	if len(possibleTypes) == 0 {
+		return nil, fmt.Errorf("%v has no possible types", name)
+	}
+	return mockObject{possibleTypes[r.Intn(len(possibleTypes))]}, nil
+}
@@ -0,0 +1,53 @@
+package graphqlapi
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestMockSchemaLeavesPassthroughTypesAlone(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					return "real", nil
+				},
+			},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"widget": &graphql.Field{
+			Type: widget,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return struct{}{}, nil
+			},
+		},
+	})
+	cfg := &PreprocessorConfig{
+		PassthroughTypes: []string{"Widget"},
+	}
+
+	mock, err := MockSchema(input, cfg, MockGenerators{Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := graphql.Do(graphql.Params{Schema: mock, RequestString: `{ widget { name } }`})
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	if name := result.Data.(map[string]interface{})["widget"].(map[string]interface{})["name"]; name == "real" {
+		t.Errorf("the mock used the real resolver")
+	}
+
+	schema, err := graphql.NewSchema(PreprocessSchemaConfig(input, cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = graphql.Do(graphql.Params{Schema: schema, RequestString: `{ widget { name } }`})
+	if name := result.Data.(map[string]interface{})["widget"].(map[string]interface{})["name"]; name != "real" {
+		t.Errorf("the real schema resolved %v", name)
+	}
+}
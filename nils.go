@@ -0,0 +1,100 @@
+package graphqlapi
+
+import (
+	"reflect"
+
+	"github.com/graphql-go/graphql"
+)
+
+// NormalizeTypedNil is the default NilNormalization. graphql-go interprets typed nil as non-null,
+// which is messy and error-prone, so typed-nil pointers are converted to untyped nils.
+func NormalizeTypedNil(v interface{}) interface{} {
+	if v != nil {
+		if value := reflect.ValueOf(v); value.Kind() == reflect.Ptr && value.IsNil() {
+			return nil
+		}
+	}
+	return v
+}
+
+// KeepTypedNil is a NilNormalization that returns results as is.
+func KeepTypedNil(v interface{}) interface{} {
+	return v
+}
+
+func isNilPointer(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// normalizeNilElementsOf replaces typed-nil pointer elements of a slice or typed-nil pointer values
+// of a map with untyped nils. The value is returned as is if there's nothing to replace.
+func normalizeNilElementsOf(v interface{}, returnType graphql.Output) interface{} {
+	if nonNull, ok := returnType.(*graphql.NonNull); ok {
+		returnType = nonNull.OfType
+	}
+	if list, ok := returnType.(*graphql.List); ok {
+		if _, ok := list.OfType.(*graphql.NonNull); ok {
+			return v
+		}
+	}
+
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		switch value.Type().Elem().Kind() {
+		case reflect.Ptr, reflect.Interface:
+		default:
+			return v
+		}
+		var result []interface{}
+		for i := 0; i < value.Len(); i++ {
+			if !isNilPointer(value.Index(i)) {
+				continue
+			}
+			if result == nil {
+				result = make([]interface{}, value.Len())
+				for j := range result {
+					result[j] = value.Index(j).Interface()
+				}
+			}
+			result[i] = nil
+		}
+		if result == nil {
+			return v
+		}
+		return result
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		switch value.Type().Elem().Kind() {
+		case reflect.Ptr, reflect.Interface:
+		default:
+			return v
+		}
+		var result map[string]interface{}
+		for _, key := range value.MapKeys() {
+			if !isNilPointer(value.MapIndex(key)) {
+				continue
+			}
+			if result == nil {
+				result = make(map[string]interface{}, value.Len())
+				for _, k := range value.MapKeys() {
+					result[k.String()] = value.MapIndex(k).Interface()
+				}
+			}
+			result[key.String()] = nil
+		}
+		if result == nil {
+			return v
+		}
+		return result
+	}
+	return v
+}
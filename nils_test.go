@@ -0,0 +1,191 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type nilsTestThing struct {
+	Name string `json:"name"`
+}
+
+func nilsTestInput(things []*nilsTestThing, byKey map[string]*nilsTestThing) graphql.SchemaConfig {
+	thing := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Thing",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	pair := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Pair",
+		Fields: graphql.Fields{
+			"first":  &graphql.Field{Type: thing},
+			"second": &graphql.Field{Type: thing},
+		},
+	})
+	return testInput(graphql.Fields{
+		"things": &graphql.Field{
+			Type: graphql.NewList(thing),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return things, nil
+			},
+		},
+		"pair": &graphql.Field{
+			Type: pair,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return byKey, nil
+			},
+		},
+	})
+}
+
+func TestNormalizeNilElements(t *testing.T) {
+	a, b := &nilsTestThing{Name: "a"}, &nilsTestThing{Name: "b"}
+	things := []*nilsTestThing{a, nil}
+	byKey := map[string]*nilsTestThing{"first": b, "second": nil}
+	input := nilsTestInput(things, byKey)
+	for _, normalize := range []bool{false, true} {
+		result := PreprocessSchemaConfig(input, &PreprocessorConfig{NormalizeNilElements: normalize})
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolve := func(name string) interface{} {
+			v, err := result.Query.Fields()[name].Resolve(graphql.ResolveParams{
+				Info: graphql.ResolveInfo{FieldName: name, ParentType: result.Query, ReturnType: result.Query.Fields()[name].Type, Schema: schema},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		}
+
+		var expectedThings, expectedByKey interface{} = things, byKey
+		if normalize {
+			expectedThings = []interface{}{a, nil}
+			expectedByKey = map[string]interface{}{"first": b, "second": nil}
+		}
+		if v := resolve("things"); !reflect.DeepEqual(v, expectedThings) {
+			t.Errorf("normalize %v: Query.things resolved to %#v", normalize, v)
+		}
+		if v := resolve("pair"); !reflect.DeepEqual(v, expectedByKey) {
+			t.Errorf("normalize %v: Query.pair resolved to %#v", normalize, v)
+		}
+
+		response := graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: `{ things { name } pair { first { name } second { name } } }`,
+		})
+		if data, _ := json.Marshal(response.Data); len(response.Errors) > 0 || string(data) != `{"pair":{"first":{"name":"b"},"second":null},"things":[{"name":"a"},null]}` {
+			t.Errorf("normalize %v: unexpected result %s %v", normalize, data, response.Errors)
+		}
+	}
+}
+
+func TestNormalizeNilElementsOf(t *testing.T) {
+	thing := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Thing",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	a := &nilsTestThing{Name: "a"}
+	things := []*nilsTestThing{a, nil}
+	if result := normalizeNilElementsOf(things, graphql.NewNonNull(graphql.NewList(thing))); !reflect.DeepEqual(result, []interface{}{a, nil}) {
+		t.Errorf("unexpected result %#v", result)
+	}
+	// Elements of non-null list types are left for graphql-go to report.
+	if result := normalizeNilElementsOf(things, graphql.NewList(graphql.NewNonNull(thing))); !reflect.DeepEqual(result, things) {
+		t.Errorf("unexpected result %#v", result)
+	}
+	// Values without typed-nil elements are returned as is.
+	for _, v := range []interface{}{[]*nilsTestThing{a}, []string{"a"}, map[string]*nilsTestThing{"a": a}, map[int]*nilsTestThing{1: nil}, a} {
+		if result := normalizeNilElementsOf(v, graphql.NewList(thing)); !reflect.DeepEqual(result, v) {
+			t.Errorf("%#v was replaced with %#v", v, result)
+		}
+	}
+}
+
+func BenchmarkNormalizeNilElements(b *testing.B) {
+	things := make([]*nilsTestThing, 10000)
+	for i := range things {
+		if i%10 != 0 {
+			things[i] = &nilsTestThing{Name: "thing"}
+		}
+	}
+	for _, normalize := range []bool{false, true} {
+		name := "Off"
+		if normalize {
+			name = "On"
+		}
+		b.Run(name, func(b *testing.B) {
+			schema, err := graphql.NewSchema(PreprocessSchemaConfig(nilsTestInput(things, nil), &PreprocessorConfig{NormalizeNilElements: normalize}))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				graphql.Do(graphql.Params{Schema: schema, RequestString: `{ things { name } }`})
+			}
+		})
+	}
+}
+
+func TestNilNormalizationModes(t *testing.T) {
+	var missing *nilsTestThing
+	input := testInput(graphql.Fields{
+		"thing": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Thing",
+				Fields: graphql.Fields{
+					"name": &graphql.Field{Type: graphql.String},
+				},
+			}),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return missing, nil
+			},
+		},
+	})
+	placeholder := &nilsTestThing{Name: "placeholder"}
+	for _, tc := range []struct {
+		mode     string
+		config   *PreprocessorConfig
+		expected interface{}
+	}{
+		{"default", &PreprocessorConfig{}, nil},
+		{"off", &PreprocessorConfig{NilNormalization: KeepTypedNil}, missing},
+		{"custom", &PreprocessorConfig{NilNormalization: func(v interface{}) interface{} {
+			if v == interface{}(missing) {
+				return placeholder
+			}
+			return v
+		}}, placeholder},
+		{"excluded", &PreprocessorConfig{NilNormalizationExclusions: []string{"Query.thing"}}, missing},
+	} {
+		result := PreprocessSchemaConfig(input, tc.config)
+		v, err := result.Query.Fields()["thing"].Resolve(graphql.ResolveParams{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.expected {
+			t.Errorf("%v: the resolver returned %#v", tc.mode, v)
+		}
+
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ thing { name } }`})
+		// graphql-go treats the typed nil as null too, so only the custom mode changes the data.
+		expected := `{"thing":null}`
+		if tc.mode == "custom" {
+			expected = `{"thing":{"name":"placeholder"}}`
+		}
+		if data, _ := json.Marshal(response.Data); len(response.Errors) > 0 || string(data) != expected {
+			t.Errorf("%v: unexpected result %s %v", tc.mode, data, response.Errors)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package graphqlapi
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// OperationNotAllowedCode is the extensions code of errors for requests rejected by an
+// OperationPolicy.
+const OperationNotAllowedCode = "OPERATION_NOT_ALLOWED"
+
+// OperationPolicy restricts the requests Execute accepts. A nil policy allows everything.
+type OperationPolicy struct {
+	// If non-nil, only operations with these names are allowed, and requests must specify an
+	// operation name.
+	AllowedNames map[string]bool
+
+	// If non-nil, requests must reference one of these documents by ID via WithDocumentID instead
+	// of supplying a request string.
+	PersistedDocuments map[string]string
+}
+
+type documentIDContextKey struct{}
+
+// WithDocumentID returns a copy of ctx indicating that the request references a persisted
+// document.
+func WithDocumentID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, documentIDContextKey{}, id)
+}
+
+// DocumentIDFromContext returns the persisted document ID carried by ctx, if any.
+func DocumentIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(documentIDContextKey{}).(string)
+	return id, ok
+}
+
+func operationNotAllowed(message string) *graphql.Result {
+	return &graphql.Result{
+		Errors: []gqlerrors.FormattedError{{
+			Message:    message,
+			Extensions: map[string]interface{}{"code": OperationNotAllowedCode},
+		}},
+	}
+}
+
+// apply checks the request against the policy without parsing it. If the request references a
+// persisted document, the returned params contain its request string.
+func (policy *OperationPolicy) apply(params graphql.Params) (graphql.Params, *graphql.Result) {
+	if policy == nil {
+		return params, nil
+	}
+	if policy.PersistedDocuments != nil {
+		id, ok := DocumentIDFromContext(params.Context)
+		if !ok || params.RequestString != "" {
+			return params, operationNotAllowed("Only persisted documents are allowed.")
+		}
+		document, ok := policy.PersistedDocuments[id]
+		if !ok {
+			return params, operationNotAllowed("Unknown persisted document.")
+		}
+		params.RequestString = document
+	}
+	if policy.AllowedNames != nil && !policy.AllowedNames[params.OperationName] {
+		return params, operationNotAllowed("Operation is not allowed.")
+	}
+	return params, nil
+}
@@ -0,0 +1,83 @@
+package graphqlapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestAllowedOperations(t *testing.T) {
+	input := testInput(graphql.Fields{
+		"name": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "widget", nil
+			},
+		},
+	})
+	requests := []struct {
+		name          string
+		ctx           context.Context
+		request       string
+		operationName string
+	}{
+		{"named", context.Background(), "query GetName { name }", "GetName"},
+		{"unnamed", context.Background(), "{ name }", ""},
+		{"persisted", WithDocumentID(context.Background(), "doc1"), "", ""},
+		{"unknown document", WithDocumentID(context.Background(), "doc2"), "", ""},
+		// Requiring persisted documents rejects this before it's parsed.
+		{"unparsable", context.Background(), "query GetName { name", "GetName"},
+	}
+	// The expected outcome of each request: executed, rejected by the policy, or another error.
+	const executed, rejected, failed = "executed", "rejected", "failed"
+	unrestricted := []string{executed, executed, failed, failed, failed}
+
+	for _, tc := range []struct {
+		mode     string
+		policy   *OperationPolicy
+		expected []string
+	}{
+		{"all", nil, unrestricted},
+		{"names", &OperationPolicy{AllowedNames: map[string]bool{"GetName": true}}, []string{executed, rejected, rejected, rejected, failed}},
+		{"persisted", &OperationPolicy{PersistedDocuments: map[string]string{"doc1": "{ name }"}}, []string{rejected, rejected, executed, rejected, rejected}},
+	} {
+		policy := tc.policy
+		for _, internal := range []bool{false, true} {
+			config := &PreprocessorConfig{
+				Flags: map[string]bool{"internal": internal},
+				AllowedOperations: func(cfg *PreprocessorConfig) *OperationPolicy {
+					if cfg.IsEnabled("internal") {
+						return nil
+					}
+					return policy
+				},
+			}
+			schema, err := graphql.NewSchema(PreprocessSchemaConfig(input, config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := tc.expected
+			if internal {
+				expected = unrestricted
+			}
+			for i, request := range requests {
+				result := Execute(config, graphql.Params{
+					Schema:        schema,
+					Context:       request.ctx,
+					RequestString: request.request,
+					OperationName: request.operationName,
+				})
+				outcome := failed
+				if len(result.Errors) == 0 && result.Data.(map[string]interface{})["name"] == "widget" {
+					outcome = executed
+				} else if len(result.Errors) == 1 && result.Errors[0].Extensions["code"] == OperationNotAllowedCode {
+					outcome = rejected
+				}
+				if outcome != expected[i] {
+					t.Errorf("%v, internal %v, %v: expected %v, got %v", tc.mode, internal, request.name, expected[i], result)
+				}
+			}
+		}
+	}
+}
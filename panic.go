@@ -0,0 +1,121 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+const maxPanicValueLength = 1024
+
+// PanicError is returned by wrapped resolvers when the original resolver panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace captured when the panic was recovered.
+	Stack []byte
+
+	// Runtime is true if the panic was caused by a runtime.Error such as a nil map write or an
+	// out-of-range index, as opposed to an explicit call to panic.
+	Runtime bool
+
+	message      string
+	err          error
+	includeStack bool
+}
+
+func newPanicError(r interface{}, stack []byte) *PanicError {
+	e := &PanicError{
+		Value: r,
+		Stack: stack,
+	}
+	switch r := r.(type) {
+	case runtime.Error:
+		e.Runtime = true
+		e.err = r
+		e.message = r.Error()
+	case error:
+		e.err = r
+		e.message = r.Error()
+	case fmt.Stringer:
+		e.message = r.String()
+	case string:
+		e.message = r
+	default:
+		e.message = fmt.Sprintf("%#v", r)
+		if len(e.message) > maxPanicValueLength {
+			e.message = e.message[:maxPanicValueLength] + "..."
+		}
+	}
+	return e
+}
+
+// newPanicError returns a PanicError whose message includes the stack according to the config.
+func (p *preprocessor) newPanicError(r interface{}, stack []byte) *PanicError {
+	e := newPanicError(r, stack)
+	e.includeStack = p.Config.PanicMessageIncludesStack
+	return e
+}
+
+// Error returns a message describing the panic value. The stack is only included if the config's
+// PanicMessageIncludesStack option is set.
+func (e *PanicError) Error() string {
+	if e.includeStack {
+		return e.message + "\n" + string(e.Stack)
+	}
+	return e.message
+}
+
+// Unwrap returns the panic value if it was an error.
+func (e *PanicError) Unwrap() error {
+	return e.err
+}
+
+const resolveWrapperFunction = modulePath + ".(*preprocessor).resolveWrapper.func"
+
+// capturePanicStack returns the stack trace for a PanicError according to the config's options.
+// It must be called directly from the resolver wrapper's deferred function.
+func (p *preprocessor) capturePanicStack() []byte {
+	var stack []byte
+	if p.Config.PanicStackFrames > 0 {
+		stack = resolverStack(p.Config.PanicStackFrames)
+	} else {
+		stack = debug.Stack()
+	}
+	if max := p.Config.PanicStackBytes; max > 0 && len(stack) > max {
+		stack = stack[:max]
+	}
+	if p.Config.RedactPanicStack != nil {
+		stack = p.Config.RedactPanicStack(stack)
+	}
+	return stack
+}
+
+// resolverStack formats up to maxFrames frames of the panicking goroutine's stack in the same
+// format as debug.Stack, starting at the frame that panicked and stopping at the resolver wrapper.
+func resolverStack(maxFrames int) []byte {
+	pcs := make([]uintptr, 256)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	var buf bytes.Buffer
+	panicking, n := false, 0
+	for n < maxFrames {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			panicking = true
+		case !panicking || strings.HasPrefix(frame.Function, "runtime."):
+		case strings.HasPrefix(frame.Function, resolveWrapperFunction):
+			return buf.Bytes()
+		default:
+			fmt.Fprintf(&buf, "%v(...)\n\t%v:%v\n", frame.Function, frame.File, frame.Line)
+			n++
+		}
+		if !more {
+			break
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,237 @@
+package graphqlapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+type panicStringer struct{}
+
+func (panicStringer) String() string {
+	return "stringer"
+}
+
+type panicStruct struct {
+	Name string
+}
+
+func TestPanicErrorShapes(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	for name, tc := range map[string]struct {
+		panic   func()
+		message string
+		runtime bool
+		unwrap  error
+	}{
+		"error": {
+			panic:   func() { panic(fmt.Errorf("wrapped: %w", sentinel)) },
+			message: "wrapped: sentinel",
+			unwrap:  sentinel,
+		},
+		"stringer": {
+			panic:   func() { panic(panicStringer{}) },
+			message: "stringer",
+		},
+		"string": {
+			panic:   func() { panic("boom") },
+			message: "boom",
+		},
+		"struct": {
+			panic:   func() { panic(panicStruct{Name: "x"}) },
+			message: `graphqlapi.panicStruct{Name:"x"}`,
+		},
+		"long struct": {
+			panic:   func() { panic(panicStruct{Name: strings.Repeat("x", 2*maxPanicValueLength)}) },
+			message: `graphqlapi.panicStruct{Name:"` + strings.Repeat("x", maxPanicValueLength-len(`graphqlapi.panicStruct{Name:"`)) + "...",
+		},
+		"nil map write": {
+			panic: func() {
+				var m map[string]int
+				m["x"] = 1
+			},
+			message: "assignment to entry in nil map",
+			runtime: true,
+		},
+		"index out of range": {
+			panic: func() {
+				var s []int
+				_ = s[len(s)]
+			},
+			message: "runtime error: index out of range [0] with length 0",
+			runtime: true,
+		},
+	} {
+		result, err := PreprocessSchemaConfigE(testInput(graphql.Fields{
+			"boom": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					tc.panic()
+					return nil, nil
+				},
+			},
+		}), &PreprocessorConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = result.Query.Fields()["boom"].Resolve(graphql.ResolveParams{})
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("%v: unexpected error %v", name, err)
+		}
+		if panicErr.Error() != tc.message {
+			t.Errorf("%v: unexpected message %q", name, panicErr.Error())
+		}
+		if panicErr.Runtime != tc.runtime {
+			t.Errorf("%v: Runtime is %v", name, panicErr.Runtime)
+		}
+		if tc.unwrap != nil && !errors.Is(err, tc.unwrap) {
+			t.Errorf("%v: the error doesn't unwrap to %v", name, tc.unwrap)
+		}
+	}
+}
+
+func panicTestInput() graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"boom": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				panic("boom")
+			},
+		},
+	})
+}
+
+func TestPanicHandler(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	var fields []string
+	result, err := PreprocessSchemaConfigE(panicTestInput(), &PreprocessorConfig{
+		PanicHandler: func(params graphql.ResolveParams, recovered interface{}, stack []byte) error {
+			fields = append(fields, params.Info.FieldName)
+			return sentinel
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = result.Query.Fields()["boom"].Resolve(graphql.ResolveParams{
+		Info: graphql.ResolveInfo{FieldName: "boom"},
+	})
+	if err != sentinel || len(fields) != 1 || fields[0] != "boom" {
+		t.Errorf("unexpected error %v for fields %v", err, fields)
+	}
+}
+
+func TestPanicHandlerRepanicIncludesStack(t *testing.T) {
+	var ended error
+	result, err := PreprocessSchemaConfigE(panicTestInput(), &PreprocessorConfig{
+		PanicMessageIncludesStack: true,
+		PanicHandler: func(graphql.ResolveParams, interface{}, []byte) error {
+			return nil
+		},
+		OnResolveStart: func(graphql.ResolveParams, string) func(error, time.Duration) {
+			return func(err error, _ time.Duration) {
+				ended = err
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("unexpected panic: %v", r)
+			}
+		}()
+		result.Query.Fields()["boom"].Resolve(graphql.ResolveParams{})
+	}()
+	panicErr, ok := ended.(*PanicError)
+	if !ok {
+		t.Fatalf("OnResolveStart got %v", ended)
+	}
+	if !strings.HasPrefix(panicErr.Error(), "boom\n") {
+		t.Errorf("the message doesn't include the stack: %q", panicErr.Error())
+	}
+}
+
+//go:noinline
+func panicTestDeeper() {
+	var m map[string]int
+	m["boom"]++
+}
+
+func panicTestResolve(graphql.ResolveParams) (interface{}, error) {
+	panicTestDeeper()
+	return nil, nil
+}
+
+type panicTestResolver struct{}
+
+func (*panicTestResolver) resolve(graphql.ResolveParams) (interface{}, error) {
+	panicTestDeeper()
+	return nil, nil
+}
+
+func TestPanicStackTrimming(t *testing.T) {
+	for name, resolve := range map[string]graphql.FieldResolveFn{
+		"panicTestResolve":             panicTestResolve,
+		"(*panicTestResolver).resolve": (&panicTestResolver{}).resolve,
+	} {
+		for _, frames := range []int{1, 2, 10} {
+			result, err := PreprocessSchemaConfigE(testInput(graphql.Fields{
+				"boom": &graphql.Field{Type: graphql.String, Resolve: resolve},
+			}), &PreprocessorConfig{PanicStackFrames: frames})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = result.Query.Fields()["boom"].Resolve(graphql.ResolveParams{})
+			panicErr, ok := err.(*PanicError)
+			if !ok {
+				t.Fatalf("%v: unexpected error %v", name, err)
+			}
+			var functions []string
+			for _, line := range strings.Split(string(panicErr.Stack), "\n") {
+				if !strings.HasPrefix(line, "\t") && line != "" {
+					functions = append(functions, strings.TrimPrefix(strings.TrimSuffix(line, "(...)"), modulePath+"."))
+				}
+			}
+			expected := []string{"panicTestDeeper", name}
+			if frames < len(expected) {
+				expected = expected[:frames]
+			}
+			if strings.Join(functions, ",") != strings.Join(expected, ",") {
+				t.Errorf("%v with %v frames: unexpected stack\n%s", name, frames, panicErr.Stack)
+			}
+		}
+	}
+}
+
+func TestPanicStackLimits(t *testing.T) {
+	var redacted []byte
+	result, err := PreprocessSchemaConfigE(panicTestInput(), &PreprocessorConfig{
+		PanicStackBytes: 64,
+		RedactPanicStack: func(stack []byte) []byte {
+			redacted = stack
+			return []byte("redacted")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = result.Query.Fields()["boom"].Resolve(graphql.ResolveParams{})
+	panicErr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(redacted) != 64 || !strings.HasPrefix(string(redacted), "goroutine ") {
+		t.Errorf("the redaction hook got %q", redacted)
+	}
+	if string(panicErr.Stack) != "redacted" {
+		t.Errorf("the stack wasn't redacted: %q", panicErr.Stack)
+	}
+}
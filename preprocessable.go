@@ -0,0 +1,50 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// PreprocessableType can be implemented by wrapper types defined outside of this package, such as
+// a helper that manufactures connection types, so that they can be handed to the preprocessor
+// without being expanded first.
+//
+// Preprocess is called each time the type is encountered. Like lists and conditionals, its result
+// isn't cached, but next preprocesses a type using the preprocessor's shared cache. The rules for
+// implementations are:
+//
+//   - next may be called any number of times, but only before Preprocess returns, and never with
+//     the receiver itself.
+//   - Types that are manufactured must be created once per original type, e.g. by memoizing them,
+//     since distinct types with the same name cause a panic. Passing them to next then yields the
+//     same preprocessed type every time.
+//   - The result must already be preprocessed, typically by being built from next's results.
+//   - Returning false removes the type along with the fields and arguments using it.
+type PreprocessableType interface {
+	graphql.Type
+	Preprocess(cfg *PreprocessorConfig, next func(graphql.Type) (graphql.Type, bool)) (graphql.Type, bool)
+}
+
+func (p *preprocessor) preprocessCustom(t PreprocessableType) (graphql.Type, bool) {
+	failed := false
+	result, ok := t.Preprocess(p.Config, func(next graphql.Type) (graphql.Type, bool) {
+		if next == graphql.Type(t) {
+			panic(fmt.Errorf("%v passed itself to next", t))
+		}
+		result, ok := p.preprocessType(next)
+		failed = !ok
+		return result, ok
+	})
+	if !ok {
+		// Keep the cause of the removal that led to this one, if any.
+		if !failed {
+			p.setCause("preprocessable type "+t.String(), nil, "")
+		}
+		return nil, false
+	}
+	if result == nil {
+		panic(fmt.Errorf("%v preprocessed into nil", t))
+	}
+	return result, true
+}
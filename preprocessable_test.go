@@ -0,0 +1,134 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// paginatedTestType is a user-defined wrapper that manufactures a connection object for its type.
+type paginatedTestType struct {
+	ofType     graphql.Type
+	connection *graphql.Object
+}
+
+var paginatedTestConnections = map[graphql.Type]*graphql.Object{}
+
+func paginatedTest(ofType graphql.Type) *paginatedTestType {
+	connection, ok := paginatedTestConnections[ofType]
+	if !ok {
+		connection = graphql.NewObject(graphql.ObjectConfig{
+			Name: ofType.Name() + "Connection",
+			Fields: graphql.Fields{
+				"nodes":      &graphql.Field{Type: graphql.NewList(ofType)},
+				"totalCount": &graphql.Field{Type: graphql.Int},
+			},
+		})
+		paginatedTestConnections[ofType] = connection
+	}
+	return &paginatedTestType{ofType: ofType, connection: connection}
+}
+
+func (t *paginatedTestType) Name() string        { return "Paginated" }
+func (t *paginatedTestType) Description() string { return "" }
+func (t *paginatedTestType) String() string      { return "Paginated(" + t.ofType.String() + ")" }
+func (t *paginatedTestType) Error() error        { return nil }
+
+func (t *paginatedTestType) Preprocess(cfg *PreprocessorConfig, next func(graphql.Type) (graphql.Type, bool)) (graphql.Type, bool) {
+	if _, ok := next(t.ofType); !ok {
+		return nil, false
+	}
+	return next(t.connection)
+}
+
+func TestPreprocessableType(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.ID},
+			"secret": &graphql.Field{Type: Beta(graphql.String)},
+		},
+	})
+	resolve := func(graphql.ResolveParams) (interface{}, error) {
+		return map[string]interface{}{
+			"nodes":      []interface{}{map[string]interface{}{"id": "1", "secret": "s"}},
+			"totalCount": 1,
+		}, nil
+	}
+	input := testInput(graphql.Fields{
+		"widgets":     &graphql.Field{Type: paginatedTest(widget), Resolve: resolve},
+		"moreWidgets": &graphql.Field{Type: paginatedTest(widget), Resolve: resolve},
+		"betaWidgets": &graphql.Field{Type: paginatedTest(Beta(widget)), Resolve: resolve},
+	})
+
+	for _, beta := range []bool{false, true} {
+		p := NewPreprocessor(&PreprocessorConfig{BetaFeaturesEnabled: beta})
+		result := p.Preprocess(input)
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		fields := result.Query.Fields()
+		if fields["widgets"].Type != fields["moreWidgets"].Type || fields["widgets"].Type.Name() != "WidgetConnection" {
+			t.Errorf("beta %v: the connection isn't shared", beta)
+		}
+		if _, ok := fields["betaWidgets"]; ok != beta {
+			t.Errorf("beta %v: Query.betaWidgets present: %v", beta, ok)
+		}
+
+		request := "{ widgets { totalCount nodes { id } } }"
+		expected := map[string]interface{}{"totalCount": 1, "nodes": []interface{}{map[string]interface{}{"id": "1"}}}
+		if beta {
+			request = "{ widgets { totalCount nodes { id secret } } }"
+			expected["nodes"] = []interface{}{map[string]interface{}{"id": "1", "secret": "s"}}
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: request})
+		if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, map[string]interface{}{"widgets": expected}) {
+			t.Errorf("beta %v: unexpected response %v", beta, response)
+		}
+	}
+}
+
+// removedTestType removes itself, or passes itself to next if reentrant.
+type removedTestType struct {
+	reentrant bool
+}
+
+func (t *removedTestType) Name() string        { return "Removed" }
+func (t *removedTestType) Description() string { return "" }
+func (t *removedTestType) String() string      { return "Removed" }
+func (t *removedTestType) Error() error        { return nil }
+
+func (t *removedTestType) Preprocess(cfg *PreprocessorConfig, next func(graphql.Type) (graphql.Type, bool)) (graphql.Type, bool) {
+	if t.reentrant {
+		return next(t)
+	}
+	return nil, false
+}
+
+func TestPreprocessableTypeRemoval(t *testing.T) {
+	input := func(t *removedTestType) graphql.SchemaConfig {
+		return testInput(graphql.Fields{
+			"id":      &graphql.Field{Type: graphql.ID},
+			"removed": &graphql.Field{Type: t},
+		})
+	}
+
+	p := NewPreprocessor(&PreprocessorConfig{})
+	if _, ok := p.Preprocess(input(&removedTestType{})).Query.Fields()["removed"]; ok {
+		t.Error("Query.removed wasn't removed")
+	}
+	causes := map[string]string{}
+	for _, removal := range p.Report().Removals {
+		causes[removal.Coordinate] = removal.Cause
+	}
+	if causes["Query.removed"] != "preprocessable type Removed" {
+		t.Errorf("Query.removed was removed with cause %q", causes["Query.removed"])
+	}
+
+	if _, err := PreprocessSchemaConfigE(input(&removedTestType{reentrant: true}), &PreprocessorConfig{}); err == nil || !strings.Contains(err.Error(), "Removed passed itself to next") {
+		t.Errorf("expected an error about re-entrancy, got %v", err)
+	}
+}
@@ -1,9 +1,17 @@
 package graphqlapi
 
 import (
+	"context"
 	"fmt"
+	"path"
 	"reflect"
-	"runtime/debug"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/language/ast"
@@ -13,10 +21,79 @@ type Conditional struct {
 	OfType    graphql.Type
 	Suffix    string
 	Condition func(*PreprocessorConfig) bool
+
+	// ConditionWithContext is like Condition, but is also given the element being gated. It's used
+	// if Condition is nil.
+	ConditionWithContext func(*PreprocessorConfig, ConditionContext) bool
+
+	// ConditionName names a condition in the config's ConditionRegistry. It's used if Condition and
+	// ConditionWithContext are nil.
+	ConditionName string
+
+	// SuffixStrategy determines the conditional's name. If nil, Suffix is appended to the name of
+	// the underlying type.
+	SuffixStrategy SuffixStrategy
+
+	// If true and the conditional wraps a non-null type, input object fields of this type are made
+	// nullable instead of being removed when the condition is false.
+	RelaxNonNull bool
+
+	// If non-empty, fields of this type are guarded by the named runtime flag. See RuntimeFlag.
+	RuntimeFlag string
+
+	// If true and the conditional wraps an object, it's preprocessed into a copy of the object named
+	// using the suffix when enabled, e.g. so that OrderBeta can be exposed alongside Order. The
+	// copy's fields are preprocessed like the original's and reference the same types, but their
+	// coordinates use the copy's name, e.g. for policies. The copy doesn't inherit the original's
+	// IsTypeOf function. See GoTypes.
+	RenameWhenEnabled bool
+
+	// WhenDisabled determines what happens to elements of this type when the condition is false.
+	WhenDisabled DisabledPolicy
+
+	// The reason fields of this type are deprecated with when WhenDisabled is
+	// DeprecateWhenDisabled. If empty, graphql.DefaultDeprecationReason is used.
+	DeprecationReason string
+
+	callsite string
+}
+
+// DisabledPolicy determines what happens to the elements of a conditional's type when its
+// condition is false.
+type DisabledPolicy int
+
+const (
+	// RemoveWhenDisabled removes the elements.
+	RemoveWhenDisabled DisabledPolicy = iota
+
+	// DeprecateWhenDisabled keeps the elements, preprocessing them as if the condition were true,
+	// and deprecates fields that aren't already deprecated. graphql-go can't deprecate arguments
+	// or input fields, so they're kept as they are. Either way, the deprecations are reported.
+	DeprecateWhenDisabled
+)
+
+// SuffixStrategy derives a conditional's name from the name of its underlying type. The config is
+// nil when the name is requested outside of preprocessing, e.g. via Conditional.Name.
+type SuffixStrategy interface {
+	Apply(baseName string, cfg *PreprocessorConfig) string
+}
+
+// ConstantSuffix is a SuffixStrategy that appends itself to the base name.
+type ConstantSuffix string
+
+func (s ConstantSuffix) Apply(baseName string, cfg *PreprocessorConfig) string {
+	return baseName + string(s)
+}
+
+func (b *Conditional) suffixStrategy() SuffixStrategy {
+	if b.SuffixStrategy != nil {
+		return b.SuffixStrategy
+	}
+	return ConstantSuffix(b.Suffix)
 }
 
 func (b *Conditional) Name() string {
-	return b.OfType.Name() + b.Suffix
+	return b.suffixStrategy().Apply(b.OfType.Name(), nil)
 }
 
 func (b *Conditional) Description() string {
@@ -24,69 +101,765 @@ func (b *Conditional) Description() string {
 }
 
 func (b *Conditional) String() string {
-	return b.OfType.String() + b.Suffix
+	return b.suffixStrategy().Apply(b.OfType.String(), nil)
 }
 
 func (b *Conditional) Error() error {
 	return b.OfType.Error()
 }
 
-func Beta(ofType graphql.Type) *Conditional {
+func (b *Conditional) declaration() string {
+	if b.callsite == "" {
+		return fmt.Sprintf("conditional %v", b.Name())
+	}
+	return fmt.Sprintf("conditional %v (declared at %v)", b.Name(), b.callsite)
+}
+
+func callsite(skip int) string {
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		return fmt.Sprintf("%v:%v", file, line)
+	}
+	return ""
+}
+
+// NewConditional returns a conditional that's only present if cond returns true. The suffix
+// distinguishes the conditional from other conditionals of the same type, so conditionals with
+// different conditions should use different suffixes.
+func NewConditional(ofType graphql.Type, suffix string, cond func(*PreprocessorConfig) bool) *Conditional {
+	return &Conditional{
+		OfType:    ofType,
+		Suffix:    suffix,
+		Condition: cond,
+		callsite:  callsite(1),
+	}
+}
+
+// NewConditionalWithContext is like NewConditional, but the condition is also given the element
+// being gated, e.g. to gate types by naming convention.
+func NewConditionalWithContext(ofType graphql.Type, suffix string, cond func(*PreprocessorConfig, ConditionContext) bool) *Conditional {
+	return &Conditional{
+		OfType:               ofType,
+		Suffix:               suffix,
+		ConditionWithContext: cond,
+		callsite:             callsite(1),
+	}
+}
+
+// Sunset returns a conditional that's always disabled, but whose elements are deprecated with the
+// reason instead of being removed. See DeprecateWhenDisabled.
+func Sunset(ofType graphql.Type, reason string) *Conditional {
 	return &Conditional{
 		OfType: ofType,
-		Suffix: "β",
-		Condition: func(cfg *PreprocessorConfig) bool {
-			return cfg.BetaFeaturesEnabled
+		Condition: func(*PreprocessorConfig) bool {
+			return false
 		},
+		WhenDisabled:      DeprecateWhenDisabled,
+		DeprecationReason: reason,
+		callsite:          callsite(1),
 	}
 }
 
-func BetaEnum(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+func Beta(ofType graphql.Type) *Conditional {
+	return newBeta(ofType, callsite(1))
+}
+
+func newBeta(ofType graphql.Type, callsite string) *Conditional {
+	return newStage("beta", ofType, callsite)
+}
+
+// BetaField returns a copy of the field that's removed unless beta features are enabled. Other uses
+// of the field's type are unaffected.
+func BetaField(f *graphql.Field) *graphql.Field {
+	field := *f
+	field.Type = newBeta(f.Type, callsite(1))
+	return &field
+}
+
+// BetaArg returns a copy of the argument that's removed unless beta features are enabled.
+func BetaArg(arg *graphql.ArgumentConfig) *graphql.ArgumentConfig {
+	a := *arg
+	a.Type = newBeta(arg.Type, callsite(1))
+	return &a
+}
+
+// BetaInputField returns a copy of the input object field that's removed unless beta features are
+// enabled. Set RelaxNonNull on the returned field's type to make it nullable instead.
+func BetaInputField(f *graphql.InputObjectFieldConfig) *graphql.InputObjectFieldConfig {
+	field := *f
+	field.Type = newBeta(f.Type, callsite(1))
+	return &field
+}
+
+// NewConditionalEnumValue returns an enum value that's only present if cond returns true.
+func NewConditionalEnumValue(value *graphql.EnumValueConfig, cond func(*PreprocessorConfig) bool) *graphql.EnumValueConfig {
+	return &graphql.EnumValueConfig{
+		Value: &conditionalEnum{
+			Value:     value,
+			Condition: cond,
+		},
+	}
+}
+
+// NewConditionalEnumValueWithContext is like NewConditionalEnumValue, but the condition is also
+// given the value being gated.
+func NewConditionalEnumValueWithContext(value *graphql.EnumValueConfig, cond func(*PreprocessorConfig, ConditionContext) bool) *graphql.EnumValueConfig {
+	return &graphql.EnumValueConfig{
+		Value: &conditionalEnum{
+			Value:                value,
+			ConditionWithContext: cond,
+		},
+	}
+}
+
+// SunsetEnum returns an enum value that's always disabled, but is deprecated with the reason
+// instead of being removed.
+func SunsetEnum(value *graphql.EnumValueConfig, reason string) *graphql.EnumValueConfig {
 	return &graphql.EnumValueConfig{
 		Value: &conditionalEnum{
 			Value: value,
-			Condition: func(cfg *PreprocessorConfig) bool {
-				return cfg.BetaFeaturesEnabled
+			Condition: func(*PreprocessorConfig) bool {
+				return false
 			},
+			DeprecationReason: reason,
 		},
 	}
 }
 
+func BetaEnum(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return FlagEnum("beta", value)
+}
+
+// ConditionalValue is implemented by enum values that are only present in the preprocessed schema
+// if Enabled returns true. Conditional enum values are created by placing a ConditionalValue in the
+// Value field of a graphql.EnumValueConfig, as BetaEnum does.
+type ConditionalValue interface {
+	Underlying() *graphql.EnumValueConfig
+	Enabled(*PreprocessorConfig) bool
+}
+
+// ConditionalValueWithContext can be implemented by a ConditionalValue whose condition needs the
+// value being gated. If implemented, EnabledWithContext is used instead of Enabled.
+type ConditionalValueWithContext interface {
+	EnabledWithContext(*PreprocessorConfig, ConditionContext) bool
+}
+
 type conditionalEnum struct {
-	Value     *graphql.EnumValueConfig
-	Condition func(*PreprocessorConfig) bool
+	Value                *graphql.EnumValueConfig
+	Condition            func(*PreprocessorConfig) bool
+	ConditionWithContext func(*PreprocessorConfig, ConditionContext) bool
+
+	// If non-empty, the value is deprecated with this reason instead of being removed when
+	// disabled.
+	DeprecationReason string
+}
+
+func (e *conditionalEnum) Underlying() *graphql.EnumValueConfig {
+	return e.Value
+}
+
+func (e *conditionalEnum) Enabled(cfg *PreprocessorConfig) bool {
+	return e.EnabledWithContext(cfg, ConditionContext{})
+}
+
+func (e *conditionalEnum) EnabledWithContext(cfg *PreprocessorConfig, ctx ConditionContext) bool {
+	if e.Condition == nil {
+		return e.ConditionWithContext(cfg, ctx)
+	}
+	return e.Condition(cfg)
+}
+
+// enumDeprecationReason returns the reason a disabled conditional enum value is deprecated with, or
+// "" if it's removed.
+func enumDeprecationReason(cfg *PreprocessorConfig, value ConditionalValue) string {
+	if e, ok := value.(*conditionalEnum); ok && e.DeprecationReason != "" {
+		return e.DeprecationReason
+	}
+	return cfg.DisabledEnumValueDeprecationReason
 }
 
 type PreprocessorConfig struct {
 	BetaFeaturesEnabled bool
+
+	// AlphaFeaturesEnabled, ExperimentalFeaturesEnabled, and InternalFeaturesEnabled enable the
+	// "alpha", "experimental", and "internal" flags as BetaFeaturesEnabled enables "beta".
+	AlphaFeaturesEnabled        bool
+	ExperimentalFeaturesEnabled bool
+	InternalFeaturesEnabled     bool
+
+	// StageSuffixes overrides the suffixes of the conditionals created by Alpha, Beta,
+	// Experimental, and Internal by flag name, e.g. {"beta": "Preview"}. The defaults are "Alpha",
+	// "Beta", "Experimental", and "Internal". Suffixes must be distinct and valid in names.
+	StageSuffixes map[string]string
+
+	// Flags explicitly enables or disables named flags.
+	Flags map[string]bool
+
+	// Environment determines the default for flags that aren't explicitly set. In Development,
+	// every flag is enabled. Elsewhere, only features that are on by default in the environment
+	// are. See FeatureFlag.DefaultOn.
+	Environment Environment
+
+	// APIVersion is the version of the API being built. See SinceVersion and UntilVersion.
+	// Conditions should read it via Version.
+	APIVersion int
+
+	// Roles held by the schema's audience. See RequireRole.
+	Roles []string
+
+	// If true, wrapped resolvers return the context's error without invoking the original resolver
+	// once the request's context is done.
+	AbortOnDoneContext bool
+
+	// Field coordinates (e.g. "Query.widget") whose resolvers are always invoked, even if
+	// AbortOnDoneContext is set.
+	AbortOnDoneContextExclusions []string
+
+	// If non-nil, OnResolverSkipped is invoked with the field coordinate each time a resolver isn't
+	// invoked due to AbortOnDoneContext.
+	OnResolverSkipped func(coordinate string)
+
+	// Policies gate types and fields by coordinate in addition to any inline conditionals. An
+	// element is only kept if its inline conditionals and every matching policy allow it.
+	Policies []Policy
+
+	// If true, a field gate on an interface also applies to the same-named field of each object
+	// implementing the interface.
+	PropagateInterfaceFieldGates bool
+
+	// If true, PreprocessSchemaConfig evaluates all field thunks of the preprocessed types before
+	// returning instead of deferring them until graphql.NewSchema. Errors and hooks then occur
+	// during preprocessing, and the thunks of the returned types just return precomputed fields.
+	EagerEvaluation bool
+
+	// GoTypes maps object names to the Go types of their resolved values. Preprocessed objects
+	// without an IsTypeOf function get one that matches values of the Go type, or pointers to
+	// it. Unions still need a ResolveType, but it may return nil to defer to IsTypeOf.
+	GoTypes map[string]reflect.Type
+
+	// If non-nil, ValidationRules returns rules that Execute applies in addition to
+	// graphql.SpecifiedRules when validating requests against this variant.
+	ValidationRules func(cfg *PreprocessorConfig) []graphql.ValidationRuleFn
+
+	// If non-nil, AllowedOperations returns the policy Execute enforces for this variant.
+	AllowedOperations func(cfg *PreprocessorConfig) *OperationPolicy
+
+	// If true, Execute adds VariantExtensions to the extensions of each result.
+	AddVariantExtensions bool
+
+	// DisclosedFlags maps the flags that VariantExtensions may list to the role the schema's
+	// audience must hold for them to be listed, or "" if they may be listed to any audience. Flags
+	// that aren't mapped are never listed, so that internal flag names don't leak to the public.
+	DisclosedFlags map[string]string
+
+	// Conditions resolves condition names used by conditionals and policies. If nil, only the
+	// built-in names are available.
+	Conditions *ConditionRegistry
+
+	// If true, a condition that panics is treated as false and a warning is emitted. Otherwise,
+	// preprocessing fails with an error identifying the element being evaluated.
+	FailClosedOnConditionPanic bool
+
+	// If non-nil, OnWarning is invoked for problems that don't prevent preprocessing.
+	OnWarning func(err error)
+
+	// Names of types that are used as-is. Their fields aren't preprocessed, so their resolvers
+	// aren't wrapped and any conditionals within them aren't honored. Types referenced by
+	// passthrough types must not also be referenced by preprocessed types.
+	PassthroughTypes []string
+
+	// If true, a Provenance block is appended to the query type's description. This forces
+	// evaluation of the preprocessed types as EagerEvaluation does.
+	EmbedProvenance bool
+
+	// If non-nil, ProvenanceTimestamp supplies the generation time recorded in the Provenance.
+	ProvenanceTimestamp func() time.Time
+
+	// If non-nil, TransformArgument is invoked with each rebuilt argument and may modify it. Note
+	// that graphql-go doesn't distinguish between a nil and an absent DefaultValue.
+	TransformArgument func(coordinate string, original *graphql.Argument, arg *graphql.ArgumentConfig)
+
+	// FieldVisitors are invoked in order with each kept object and interface field once it's been
+	// rebuilt, including its wrapped resolver. Each may return a replacement, or nil to remove the
+	// field. Fields removed by conditionals or policies aren't visited.
+	FieldVisitors []func(parent string, original *graphql.FieldDefinition, rebuilt *graphql.Field) *graphql.Field
+
+	// If positive, stacks captured when resolvers panic are limited to this many frames, starting
+	// at the frame that panicked and excluding the resolver wrapper and graphql-go.
+	PanicStackFrames int
+
+	// If positive, stacks captured when resolvers panic are truncated to this many bytes.
+	PanicStackBytes int
+
+	// If non-nil, RedactPanicStack is applied to stacks captured when resolvers panic before
+	// they're stored in a PanicError.
+	RedactPanicStack func(stack []byte) []byte
+
+	// If true, the messages of PanicErrors include their stacks. Otherwise the stack is only
+	// available via the error's Stack field, so it doesn't end up in client-visible messages.
+	PanicMessageIncludesStack bool
+
+	// If non-nil, PanicHandler is invoked when a resolver panics, with the stack captured according
+	// to the options above. The error it returns is returned by the resolver. If it returns nil, the
+	// panic is re-raised. Note that graphql-go's executor recovers resolver panics itself, reporting
+	// them as field errors, so to fail a test with the original stack the handler should do so
+	// directly. If nil, panics are recovered and returned as PanicErrors.
+	PanicHandler func(params graphql.ResolveParams, recovered interface{}, stack []byte) error
+
+	// If non-nil, OnResolveStart is invoked before each wrapped resolver with the field's coordinate.
+	// If it returns a non-nil function, that function is invoked when the resolver returns with its
+	// error and duration. If the resolver panics, the error is the one the panic was converted to,
+	// or a PanicError if the panic is re-raised.
+	OnResolveStart func(params graphql.ResolveParams, coordinate string) func(err error, duration time.Duration)
+
+	// RuntimeFlagChecker reports whether the named runtime flag is enabled for a request. It's
+	// required if the schema uses RuntimeFlag.
+	RuntimeFlagChecker func(ctx context.Context, name string) bool
+
+	// If non-empty, disabled conditional enum values are kept and deprecated with this reason
+	// instead of being removed. Values that are already deprecated keep their own reason.
+	DisabledEnumValueDeprecationReason string
+
+	// If true, fields whose types are removed by conditionals are kept, but resolve to
+	// ErrFeatureDisabled. Arguments, input fields, and enum values are still removed.
+	SoftDisable bool
+
+	// If true, wrapped resolvers that return slices or maps also convert typed-nil pointer
+	// elements to untyped nils. Elements of non-null list types are left as is so that graphql-go
+	// reports them. This walks every returned slice and map, so it has a cost for large lists.
+	NormalizeNilElements bool
+
+	// NilNormalization is applied to the results of wrapped resolvers. If nil, NormalizeTypedNil
+	// is used. Use KeepTypedNil to disable normalization.
+	NilNormalization func(v interface{}) interface{}
+
+	// Field coordinates (e.g. "Query.widget") whose results aren't normalized at all, regardless
+	// of NilNormalization and NormalizeNilElements.
+	NilNormalizationExclusions []string
+
+	// If true, fields whose types are removed by conditionals are kept and remain fully functional.
+	// Use NewHidingSchema to also hide them from introspection. HideOnly takes precedence over
+	// SoftDisable.
+	HideOnly bool
+
+	// If non-nil, OnDrop is invoked with the coordinate of each field, argument, input field, and
+	// enum value removed by preprocessing, along with the cause (e.g. "conditional WidgetBeta" or
+	// "policy internal"). Elements of types that are preprocessed lazily are reported when their
+	// thunks are evaluated.
+	OnDrop func(coordinate, reason string)
+
+	// If non-nil, OnKeep is invoked with the coordinate of each field, argument, input field, and
+	// enum value kept by preprocessing.
+	OnKeep func(coordinate string)
+
+	// If non-nil, OnDeprecatedFieldResolved is invoked with the coordinate of each field deprecated
+	// via DeprecateWhenDisabled before it's resolved, e.g. to log remaining usage.
+	OnDeprecatedFieldResolved func(params graphql.ResolveParams, coordinate string)
+
+	// TypeMiddleware maps type name patterns (e.g. "Admin*") to middleware applied to every field
+	// resolver of matching types. Patterns are applied in lexical order with earlier patterns
+	// outermost, and within a pattern the first middleware is outermost. Middleware is applied
+	// inside the panic recovery and AbortOnDoneContext handling.
+	TypeMiddleware map[string][]func(graphql.FieldResolveFn) graphql.FieldResolveFn
+
+	// ResolverMiddleware is applied to every field resolver, with the first middleware outermost.
+	// Unlike TypeMiddleware, it's applied outside the panic recovery, nil normalization and
+	// AbortOnDoneContext handling. Fields without resolvers are passed to middleware with a nil
+	// next. Middleware opts in to such fields by returning a resolver, which should typically
+	// fall back to graphql.DefaultResolveFn, and declines by returning nil.
+	ResolverMiddleware []func(next graphql.FieldResolveFn, field FieldInfo) graphql.FieldResolveFn
+
+	// If non-nil, OnResolverWrapped is invoked with the coordinate and original function of each
+	// resolver wrapped by preprocessing. Instrumentation that identifies resolvers by function can
+	// use it to map wrapped resolvers, identified at runtime by their field coordinate, back to the
+	// originals.
+	OnResolverWrapped func(coordinate string, original graphql.FieldResolveFn)
+
+	// If non-nil, resolvers are audited for identity comparisons against original types, such as
+	// info.ReturnType == widgetType, which never match after preprocessing. Whenever a resolver is
+	// invoked with a return or parent type that preprocessing replaced, and it doesn't consult
+	// SameType or SameParentType while resolving, OnTypeIdentityDependence is invoked once with the
+	// field's coordinate so that the resolver can be reviewed. Resolvers are never invoked more than
+	// once, and mutation and subscription fields aren't audited. See SameType.
+	OnTypeIdentityDependence func(coordinate string)
+
+	// Limits on the nesting depth of the types and fields being preprocessed and on the number of
+	// distinct types, guarding against runaway type construction. If zero, generous defaults are
+	// used. If negative, there's no limit.
+	MaxPreprocessingDepth int
+	MaxPreprocessedTypes  int
+
+	// Examples maps field coordinates to example values, as a resolver would return them. Examples
+	// are validated against the preprocessed field types and used by MockSchema. Only fields of
+	// scalar and enum types, or lists of them, support examples.
+	Examples map[string]interface{}
+
+	// If true, each field's example is appended to its description.
+	ExamplesInDescriptions bool
+
+	// ScalarOverrides maps scalar names to replacements, e.g. to fix ParseLiteral for custom
+	// scalars. Each replacement must have the same name as the scalar it replaces.
+	ScalarOverrides map[string]*graphql.Scalar
+
+	// If non-empty, ConditionalNotice is appended to the descriptions of fields, arguments, input
+	// fields and enum values that are present because their conditions are true, e.g. to tell
+	// clients that they're beta features.
+	ConditionalNotice string
+
+	// If true, DateTime scalars aren't replaced with one that parses string literals. See
+	// https://github.com/graphql-go/graphql/issues/250.
+	KeepDateTime bool
+
+	// TypeVisitors are invoked in order with each kept named type and its preprocessed result, and
+	// may return a replacement of the same kind, which is cached in place of the result. Visitors
+	// that don't transform a type should return the result unchanged. Visitors must not evaluate
+	// the fields of the preprocessed type, which may refer back to the type before its result is
+	// cached. Replacements should evaluate them lazily instead, e.g. in a fields thunk.
+	TypeVisitors []func(original, preprocessed graphql.Type) graphql.Type
+
+	// If true, problems that would otherwise be worked around with a warning or a removal cause
+	// preprocessing to fail, e.g. default values that refer to removed enum values, invalid enums
+	// and enum values with the same internal value, non-null
+	// fields whose types are removed within the non-null wrapper, and removed non-null arguments
+	// and input fields.
+	Strict bool
+
+	// If true, types in the input's Types are only preprocessed and included in the result if
+	// they're reachable from the preprocessed roots. Objects implementing reachable interfaces are
+	// considered reachable.
+	PruneUnreachable bool
+
+	// If non-nil and returning true, the result has no mutation or subscription root respectively,
+	// e.g. to restrict an entire Subscription root to beta. Types in the input's Types that are
+	// only reachable from an omitted root are removed as well.
+	OmitMutation     func(*PreprocessorConfig) bool
+	OmitSubscription func(*PreprocessorConfig) bool
+
+	readFlags *readSet
+}
+
+// readSet records the flags and other dependencies read by conditions. It's safe for concurrent
+// use, since schemas can retain the config they were preprocessed with.
+type readSet struct {
+	mutex sync.Mutex
+	names map[string]bool
+}
+
+func newReadSet() *readSet {
+	return &readSet{
+		names: map[string]bool{},
+	}
+}
+
+func (s *readSet) add(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.names[name] = true
+}
+
+// sorted returns the sorted names that were read.
+func (s *readSet) sorted() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	names := make([]string, 0, len(s.names))
+	for name := range s.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsEnabled returns whether the named flag is enabled. Conditions should read flags via IsEnabled
+// so that ReadFlags can tell which flags a schema depends on.
+func (c *PreprocessorConfig) IsEnabled(flag string) bool {
+	return c.flagEnabled(flag, nil)
+}
+
+// versionDependency is the name under which reads of APIVersion are tracked alongside flags.
+const versionDependency = "@version"
+
+// Version returns APIVersion. Conditions should read the version via Version so that ReadFlags can
+// tell that a schema depends on it.
+func (c *PreprocessorConfig) Version() int {
+	c.read(versionDependency)
+	return c.APIVersion
+}
+
+// read records that the named flag or other dependency was read. See ReadFlags.
+func (c *PreprocessorConfig) read(name string) {
+	if c.readFlags != nil {
+		c.readFlags.add(name)
+	}
+}
+
+// flagEnabled determines whether a flag is enabled. In order of precedence:
+//
+//  1. If the flag is explicitly set in Flags, that value is used. BetaFeaturesEnabled explicitly
+//     enables the "beta" flag, and likewise for the other release stages.
+//  2. If the config's environment is Development, it's enabled, whether or not it's declared.
+//  3. If the flag is on by default in the config's environment, it's enabled.
+//  4. Otherwise, it's disabled.
+func (c *PreprocessorConfig) flagEnabled(flag string, defaultOn []Environment) bool {
+	c.read(flag)
+	if enabled, ok := c.Flags[flag]; ok {
+		return enabled
+	}
+	if enabled, ok := stages[flag]; ok && *enabled(c) {
+		return true
+	}
+	if c.Environment == Development {
+		return true
+	}
+	for _, env := range defaultOn {
+		if env == c.Environment {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFlags preprocesses the input and returns the sorted names of the flags read via IsEnabled
+// while doing so. Configs that only differ in other flags produce equivalent schemas. If the
+// version was read via Version, e.g. by SinceVersion, "@version" is included, and each role
+// checked via HasRole is included as "@role:<role>".
+func ReadFlags(input graphql.SchemaConfig, config *PreprocessorConfig) []string {
+	cfg := *config
+	cfg.readFlags = newReadSet()
+	cfg.EagerEvaluation = true
+	PreprocessSchemaConfig(input, &cfg)
+	return cfg.readFlags.sorted()
+}
+
+// Policy gates every type or field whose coordinate matches CoordinatePattern. Type coordinates
+// are type names (e.g. "BillingAccount") and field coordinates are of the form "Type.field".
+// Patterns use path.Match syntax, so "Billing*" matches all types whose names begin with
+// "Billing" along with their fields, and "Query.experimental*" matches fields of Query.
+type Policy struct {
+	Name              string
+	CoordinatePattern string
+	Condition         func(*PreprocessorConfig) bool
+
+	// ConditionName names a condition in the config's ConditionRegistry. It's used if Condition is
+	// nil.
+	ConditionName string
+}
+
+// GateAllFields returns a policy that applies the condition to every field of the object without
+// renaming it. This is the supported way to gate an entire root type such as Query.
+func GateAllFields(obj *graphql.Object, condition func(*PreprocessorConfig) bool) Policy {
+	return Policy{
+		Name:              "all fields of " + obj.Name(),
+		CoordinatePattern: obj.Name() + ".*",
+		Condition:         condition,
+	}
+}
+
+func matchCoordinate(pattern, coordinate string) bool {
+	matched, _ := path.Match(pattern, coordinate)
+	return matched
+}
+
+// problem fails in strict mode, and is reported as a warning otherwise.
+func (p *preprocessor) problem(err error) {
+	if p.Config.Strict {
+		panic(err)
+	}
+	p.warn(err)
+}
+
+func (p *preprocessor) warn(err error) {
+	if p.Config.OnWarning != nil {
+		p.Config.OnWarning(err)
+	}
+}
+
+func (p *preprocessor) policiesAllow(coordinate string) bool {
+	for _, policy := range p.Config.Policies {
+		if matchCoordinate(policy.CoordinatePattern, coordinate) && !p.evaluateCondition("policy "+policy.Name, policy.Condition, policy.ConditionName) {
+			p.setCause("policy "+policy.Name, policy.Condition, policy.ConditionName)
+			return false
+		}
+	}
+	return true
 }
 
 type preprocessor struct {
 	Config            *PreprocessorConfig
 	PreprocessedTypes map[string]graphql.Type
+	OriginalTypes     map[string]graphql.Type
+
+	// Guards writes to PreprocessedTypes and OriginalTypes against the reads made by resolvers at
+	// runtime, which may happen while Preprocessor.Type or Preprocessor.Field is adding types.
+	// Reads made while preprocessing happen on the writing goroutine, so they don't need it.
+	typesMutex sync.RWMutex
+
+	// The mutation and subscription root types, whose resolvers aren't audited for type identity
+	// dependence. See auditTypeIdentity.
+	unauditedRoots map[graphql.Type]bool
+
+	// The types and fields currently being preprocessed, for diagnostics.
+	path []string
+
+	// The coordinate of the field, argument, or input field whose type is being preprocessed, if
+	// any. It's passed to conditions via ConditionContext.
+	coordinate string
+
+	// The deprecation of the element at the coordinate, if a conditional with DeprecateWhenDisabled
+	// was disabled while preprocessing its type.
+	deprecation *Deprecation
+
+	// Whether the type being preprocessed at the coordinate was removed within a non-null wrapper,
+	// and the same for the type most recently preprocessed by preprocessTypeAt. See strict.go.
+	removalWithinNonNull bool
+	removedWithinNonNull bool
+
+	// If non-nil, types are shared with other variants. See PreprocessSchemaConfigs.
+	sharing *typeSharing
+
+	// The *flagsContext most recently derived by wrapped resolvers. Every resolver of a request is
+	// given the same context, so it's reused for the rest of the request.
+	flagsContext atomic.Value
+
+	// The fields of the input objects whose field thunks are being evaluated, by name.
+	definingInputs map[string]graphql.InputObjectConfigFieldMap
+
+	// Coordinates of fields kept by PreprocessorConfig.HideOnly. See NewHidingSchema.
+	hidden map[string]bool
+
+	// If non-nil, removals and substitutions are recorded here. See Preprocessor.
+	report *Report
+
+	// The cause of the most recent removal, and the causes of removed types by cache key. These
+	// are only tracked if report is non-nil.
+	cause  *Removal
+	causes map[string]*Removal
 }
 
+// PreprocessSchemaConfig returns the variant of the input described by the config. It may be called
+// concurrently, including with the same input: the input's thunks are evaluated lazily, each type's
+// exactly once, as preprocessing reaches them. The config must not be modified while it's in use.
+// The result's types are new graphql-go types, which cache their thunks without synchronization
+// like the input's, so build a schema from the result, or use the config's EagerEvaluation option,
+// before sharing it between goroutines.
 func PreprocessSchemaConfig(input graphql.SchemaConfig, config *PreprocessorConfig) graphql.SchemaConfig {
-	p := &preprocessor{
+	return preprocessSchemaConfig(input, config, nil)
+}
+
+// newPreprocessor validates the config and returns a preprocessor for it.
+func newPreprocessor(config *PreprocessorConfig, report *Report) *preprocessor {
+	config.validateStageSuffixes()
+	for _, policy := range config.Policies {
+		if _, err := path.Match(policy.CoordinatePattern, ""); err != nil {
+			panic(fmt.Errorf("invalid pattern for policy %v: %v", policy.Name, err))
+		}
+		if policy.Condition == nil {
+			if _, ok := config.Conditions.Lookup(policy.ConditionName); !ok {
+				panic(fmt.Errorf("policy %v references unknown condition %q", policy.Name, policy.ConditionName))
+			}
+		}
+	}
+	for pattern := range config.TypeMiddleware {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Errorf("invalid type middleware pattern %q: %v", pattern, err))
+		}
+	}
+	return &preprocessor{
 		Config:            config,
 		PreprocessedTypes: make(map[string]graphql.Type),
+		OriginalTypes:     make(map[string]graphql.Type),
+		definingInputs:    make(map[string]graphql.InputObjectConfigFieldMap),
+		hidden:            make(map[string]bool),
+		report:            report,
+		causes:            make(map[string]*Removal),
+		unauditedRoots:    make(map[graphql.Type]bool),
+	}
+}
+
+func preprocessSchemaConfig(input graphql.SchemaConfig, config *PreprocessorConfig, report *Report) graphql.SchemaConfig {
+	return newPreprocessor(config, report).preprocessSchemaConfig(input)
+}
+
+func (p *preprocessor) preprocessSchemaConfig(input graphql.SchemaConfig) graphql.SchemaConfig {
+	config := p.Config
+	for _, obj := range []*graphql.Object{input.Mutation, input.Subscription} {
+		if obj != nil {
+			p.unauditedRoots[obj] = true
+		}
 	}
 	result := input
 	if obj := input.Query; obj != nil {
-		result.Query = p.preprocessObject(obj)
+		result.Query = p.preprocessRoot("query", obj)
 	}
+	var omitted []*graphql.Object
 	if obj := input.Mutation; obj != nil {
-		result.Mutation = p.preprocessObject(obj)
+		if p.omitRoot("mutation", obj, config.OmitMutation) {
+			result.Mutation, omitted = nil, append(omitted, obj)
+		} else {
+			result.Mutation = p.preprocessRoot("mutation", obj)
+		}
 	}
 	if obj := input.Subscription; obj != nil {
-		result.Subscription = p.preprocessObject(obj)
+		if p.omitRoot("subscription", obj, config.OmitSubscription) {
+			result.Subscription, omitted = nil, append(omitted, obj)
+		} else {
+			result.Subscription = p.preprocessRoot("subscription", obj)
+		}
 	}
 	result.Types = nil
-	for _, t := range input.Types {
-		if newType, ok := p.preprocessType(t); ok {
-			result.Types = append(result.Types, newType)
+	if config.PruneUnreachable {
+		result.Types = p.preprocessReachableTypes(input.Types, graphql.SchemaConfig{
+			Query:        result.Query,
+			Mutation:     result.Mutation,
+			Subscription: result.Subscription,
+		})
+	} else {
+		exclusive := omittedRootTypes(input, omitted)
+		for _, t := range input.Types {
+			if name := namedType(unwrapConditionals(t)).Name(); exclusive[name] {
+				p.setCause("omitted root", nil, "")
+				p.removed("type", name, "")
+			} else if newType, ok := p.preprocessType(t); ok {
+				result.Types = append(result.Types, newType)
+			} else {
+				p.removed("type", namedType(unwrapConditionals(t)).Name(), "")
+			}
 		}
 	}
+	result.Directives = nil
+	for _, d := range input.Directives {
+		result.Directives = append(result.Directives, p.preprocessDirective(d))
+	}
+	if config.EagerEvaluation {
+		// graphql-go caches the result of each thunk, so walking the types is enough to force them.
+		schemaTypes(result)
+	}
+	if config.EmbedProvenance && result.Query != nil {
+		result.Query.PrivateDescription = embedProvenance(result.Query.PrivateDescription, newProvenance(input, result, config))
+	}
+	return result
+}
+
+// omitRoot returns whether the root should be omitted according to the hook, reporting its removal
+// if so.
+func (p *preprocessor) omitRoot(operation string, obj *graphql.Object, omit func(*PreprocessorConfig) bool) bool {
+	if omit == nil || !p.callCondition("omitting the "+operation+" root type "+obj.Name(), func() bool {
+		return omit(p.Config)
+	}) {
+		return false
+	}
+	p.setCause("omitted "+operation+" root", omit, "")
+	p.removed("type", obj.Name(), "")
+	return true
+}
+
+func (p *preprocessor) preprocessRoot(operation string, obj *graphql.Object) *graphql.Object {
+	t, ok := p.preprocessType(obj)
+	if !ok {
+		panic(fmt.Errorf("the %v root type %v was removed by preprocessing", operation, obj.Name()))
+	}
+	result := t.(*graphql.Object)
+	if len(result.Fields()) == 0 && len(obj.Fields()) > 0 {
+		panic(fmt.Errorf("every field of the %v root type %v was removed by preprocessing", operation, obj.Name()))
+	}
 	return result
 }
 
@@ -105,14 +878,124 @@ var fixedDateTime = graphql.NewScalar(graphql.ScalarConfig{
 	},
 })
 
+// checkCollision panics if t and a previously preprocessed type share a cache key, but aren't
+// the same type. This happens when a conditional's suffixed name matches another type's name, or
+// when distinct types have the same name. Since conditionals aren't cached, this ensures that
+// cached results always belong to the type being preprocessed.
+func (p *preprocessor) checkCollision(key string, t graphql.Type) {
+	original, ok := p.OriginalTypes[key]
+	if !ok {
+		p.typesMutex.Lock()
+		p.OriginalTypes[key] = t
+		p.typesMutex.Unlock()
+		p.checkTypeCount()
+		return
+	}
+	if original == t {
+		return
+	}
+	a, aIsConditional := original.(*Conditional)
+	b, bIsConditional := t.(*Conditional)
+	switch {
+	case aIsConditional && bIsConditional:
+		if a.OfType != b.OfType {
+			panic(fmt.Errorf("%v collides with %v", b.declaration(), a.declaration()))
+		}
+	case aIsConditional:
+		panic(fmt.Errorf("%v collides with type %v", a.declaration(), t.Name()))
+	case bIsConditional:
+		panic(fmt.Errorf("%v collides with type %v", b.declaration(), original.Name()))
+	case !sameScalar(original, t):
+		panic(fmt.Errorf("distinct types are named %v", key))
+	}
+}
+
+// sameScalar returns whether a and b are the same scalar, allowing for either to be a
+// ScalarVariants wrapping the other.
+func sameScalar(a, b graphql.Type) bool {
+	if v, ok := a.(*ScalarVariants); ok {
+		a = v.Scalar
+	}
+	if v, ok := b.(*ScalarVariants); ok {
+		b = v.Scalar
+	}
+	return a == b
+}
+
+func (p *preprocessor) isPassthrough(t graphql.Type) bool {
+	for _, name := range p.Config.PassthroughTypes {
+		if name == t.Name() {
+			p.warnPassthroughConditionals(t)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *preprocessor) warnPassthroughConditionals(t graphql.Type) {
+	types := map[string]graphql.Type{}
+	switch t := t.(type) {
+	case *graphql.Object:
+		for name, f := range t.Fields() {
+			types[name] = f.Type
+		}
+	case *graphql.Interface:
+		for name, f := range t.Fields() {
+			types[name] = f.Type
+		}
+	case *graphql.InputObject:
+		for name, f := range t.Fields() {
+			types[name] = f.Type
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for fieldType := types[name]; fieldType != nil; {
+			switch ft := fieldType.(type) {
+			case *graphql.List:
+				fieldType = ft.OfType
+			case *graphql.NonNull:
+				fieldType = ft.OfType
+			case *Conditional:
+				p.warn(fmt.Errorf("%v.%v uses %v, which isn't honored because %v is a passthrough type", t.Name(), name, ft.declaration(), t.Name()))
+				fieldType = nil
+			default:
+				fieldType = nil
+			}
+		}
+	}
+}
+
+// typeKey returns the key under which the type's preprocessed result is cached. Conditionals are
+// named using the config.
+func (p *preprocessor) typeKey(t graphql.Type) string {
+	switch t := t.(type) {
+	case *graphql.List:
+		return "[" + p.typeKey(t.OfType) + "]"
+	case *graphql.NonNull:
+		return p.typeKey(t.OfType) + "!"
+	case *Conditional:
+		return t.suffixStrategy().Apply(p.typeKey(t.OfType), p.Config)
+	}
+	return t.String()
+}
+
 func (p *preprocessor) preprocessType(t graphql.Type) (result graphql.Type, ok bool) {
-	if result, ok := p.PreprocessedTypes[t.String()]; ok {
-		return result, result != nil
+	defer p.enter(t.String())()
+
+	if t, ok := t.(*FallbackType); ok {
+		return p.preprocessFallback(t)
+	}
+	if t, ok := t.(PreprocessableType); ok {
+		return p.preprocessCustom(t)
 	}
-	defer func() {
-		p.PreprocessedTypes[t.String()] = result
-	}()
 
+	// Conditionals and wrappers aren't cached. Their results are derived from their underlying
+	// types, and conditionals that share a suffix may have different conditions.
 	switch t := t.(type) {
 	case *graphql.List:
 		ofType, ok := p.preprocessType(t.OfType)
@@ -123,123 +1006,594 @@ func (p *preprocessor) preprocessType(t graphql.Type) (result graphql.Type, ok b
 	case *graphql.NonNull:
 		ofType, ok := p.preprocessType(t.OfType)
 		if !ok {
+			p.removalWithinNonNull = true
 			return nil, false
 		}
 		return graphql.NewNonNull(ofType), true
-	case *graphql.InputObject:
-		return p.preprocessInputObject(t), true
-	case *graphql.Object:
-		return p.preprocessObject(t), true
 	case *Conditional:
-		if t.Condition(p.Config) {
+		// A conditional that isn't distinguishable from its underlying type in this variant can't
+		// collide with anything.
+		if key := p.typeKey(t); key != p.typeKey(t.OfType) {
+			p.checkCollision(key, t)
+		}
+		condition := p.conditionAt(t, p.coordinate)
+		if p.evaluateCondition(t.declaration(), condition, t.ConditionName) {
+			if t.RenameWhenEnabled {
+				return p.preprocessRenamed(t)
+			}
 			return p.preprocessType(t.OfType)
 		}
+		if t.WhenDisabled == DeprecateWhenDisabled {
+			p.deprecation = &Deprecation{
+				Reason: t.DeprecationReason,
+				Cause:  "conditional " + p.typeKey(t),
+			}
+			if p.deprecation.Reason == "" {
+				p.deprecation.Reason = graphql.DefaultDeprecationReason
+			}
+			if p.report != nil {
+				p.deprecation.Flags = conditionFlags(p.Config, condition, t.ConditionName)
+			}
+			return p.preprocessType(t.OfType)
+		}
+		p.setCause("conditional "+p.typeKey(t), condition, t.ConditionName)
+		return nil, false
+	}
+
+	// The coordinate only applies to the wrappers of the type, not to the types it references.
+	defer p.at("")()
+
+	key := p.typeKey(t)
+	p.checkCollision(key, t)
+	evaluateThunks(t)
+
+	if result, ok := p.PreprocessedTypes[key]; ok {
+		if result == nil {
+			p.cause = p.causes[key]
+		}
+		return result, result != nil
+	}
+	defer func() {
+		if result != nil {
+			result = p.visit(t, result)
+		}
+		p.cacheType(key, result)
+	}()
+
+	if !p.policiesAllow(t.Name()) {
+		p.causes[key] = p.cause
 		return nil, false
+	}
+	if p.isPassthrough(t) {
+		return t, true
+	}
+
+	switch t := t.(type) {
+	case *graphql.InputObject:
+		return p.preprocessInputObject(t), true
+	case *graphql.Object:
+		return p.preprocessObject(t, t.Name()), true
 	case *graphql.Scalar:
-		if t.Name() == "DateTime" {
+		if override, ok := p.Config.ScalarOverrides[t.Name()]; ok {
+			if override.Name() != t.Name() {
+				panic(fmt.Errorf("the override for scalar %v is named %v", t.Name(), override.Name()))
+			}
+			p.substituted(t.Name(), "the scalar override for "+t.Name())
+			return override, true
+		}
+		if t.Name() == "DateTime" && !p.Config.KeepDateTime {
+			p.substituted("DateTime", "a DateTime scalar that parses string literals")
 			return fixedDateTime, true
 		}
 		return t, true
+	case *ScalarVariants:
+		return p.preprocessScalarVariants(t), true
 	case *graphql.Enum:
-		return p.preprocessEnum(t), true
+		if enum, ok := p.preprocessEnum(t); ok {
+			return enum, true
+		}
+		p.causes[key] = p.cause
+		return nil, false
 	case *graphql.Interface:
 		return p.preprocessInterface(t), true
 	case *graphql.Union:
-		return p.preprocessUnion(t), true
+		if union, ok := p.preprocessUnion(t); ok {
+			return union, true
+		}
+		p.causes[key] = p.cause
+		return nil, false
 	}
 
 	panic(fmt.Errorf("unknown graphql type %T", t))
 }
 
-func resolveWrapper(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+func (p *preprocessor) resolveWrapper(parent, name string, original graphql.FieldResolveFn) graphql.FieldResolveFn {
+	coordinate := parent + "." + name
+	resolve := p.applyTypeMiddleware(parent, original)
 	if resolve == nil {
 		return nil
 	}
-	return func(p graphql.ResolveParams) (v interface{}, err error) {
+	if original != nil && p.Config.OnResolverWrapped != nil {
+		p.Config.OnResolverWrapped(coordinate, original)
+	}
+	abortOnDoneContext := p.Config.AbortOnDoneContext
+	for _, excluded := range p.Config.AbortOnDoneContextExclusions {
+		if excluded == coordinate {
+			abortOnDoneContext = false
+		}
+	}
+	onResolverSkipped := p.Config.OnResolverSkipped
+	onResolveStart := p.Config.OnResolveStart
+	normalizeNil := p.Config.NilNormalization
+	if normalizeNil == nil {
+		normalizeNil = NormalizeTypedNil
+	}
+	normalizeNilElements := p.Config.NormalizeNilElements
+	for _, excluded := range p.Config.NilNormalizationExclusions {
+		if excluded == coordinate {
+			normalizeNil, normalizeNilElements = nil, false
+		}
+	}
+	return func(params graphql.ResolveParams) (v interface{}, err error) {
+		if FlagsFromContext(params.Context) == nil {
+			params.Context = p.withFlags(params.Context, p.variantConfig(params.Info))
+		}
+
+		if abortOnDoneContext && params.Context != nil {
+			if err := params.Context.Err(); err != nil {
+				if onResolverSkipped != nil {
+					onResolverSkipped(coordinate)
+				}
+				return nil, err
+			}
+		}
+
+		if onResolveStart != nil {
+			if onResolveEnd := onResolveStart(params, coordinate); onResolveEnd != nil {
+				start := time.Now()
+				defer func() {
+					onResolveEnd(err, time.Since(start))
+				}()
+			}
+		}
+
 		defer func() {
 			if r := recover(); r != nil {
-				err = fmt.Errorf("%v\n%v", r, string(debug.Stack()))
+				stack := p.capturePanicStack()
+				if p.Config.PanicHandler != nil {
+					if err = p.Config.PanicHandler(params, r, stack); err == nil {
+						err = p.newPanicError(r, stack)
+						panic(r)
+					}
+					return
+				}
+				err = p.newPanicError(r, stack)
 			}
 		}()
 
-		v, err = resolve(p)
+		v, err = resolve(params)
 
-		// graphql-go interprets typed nil as non-null. that makes things messy and error-prone, so
-		// let's just fix that for all our resolve functions here
-		if v != nil {
-			if vValue := reflect.ValueOf(v); vValue.Kind() == reflect.Ptr && vValue.IsNil() {
-				v = nil
-			}
+		if normalizeNil != nil {
+			v = normalizeNil(v)
+		}
+		if normalizeNilElements && v != nil {
+			v = normalizeNilElementsOf(v, params.Info.ReturnType)
 		}
 
 		return v, err
 	}
 }
 
-func (p *preprocessor) preprocessEnum(enum *graphql.Enum) *graphql.Enum {
+type flagsContext struct {
+	parent  context.Context
+	config  *PreprocessorConfig
+	derived context.Context
+}
+
+// withFlags returns WithFlags(ctx, config), reusing the most recently derived context if it has
+// the same parent and config.
+func (p *preprocessor) withFlags(ctx context.Context, config *PreprocessorConfig) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if cached, ok := p.flagsContext.Load().(*flagsContext); ok && cached.parent == ctx && cached.config == config {
+		return cached.derived
+	}
+	derived := WithFlags(ctx, config)
+	p.flagsContext.Store(&flagsContext{
+		parent:  ctx,
+		config:  config,
+		derived: derived,
+	})
+	return derived
+}
+
+// variantConfig returns the config of the variant being executed, which differs from the
+// preprocessor's for types shared by PreprocessSchemaConfigs.
+func (p *preprocessor) variantConfig(info graphql.ResolveInfo) *PreprocessorConfig {
+	if p.sharing != nil {
+		if config := p.sharing.variantConfig(info.Schema.QueryType()); config != nil {
+			return config
+		}
+	}
+	return p.Config
+}
+
+var nameRegexp = regexp.MustCompile(`^[_a-zA-Z][_a-zA-Z0-9]*$`)
+
+// preprocessEnum preprocesses the enum, returning false if all of its values are removed.
+func (p *preprocessor) preprocessEnum(enum *graphql.Enum) (*graphql.Enum, bool) {
 	config := graphql.EnumConfig{
 		Name:        enum.Name(),
 		Description: enum.Description(),
 		Values:      make(map[string]*graphql.EnumValueConfig),
 	}
-	for _, value := range enum.Values() {
-		if Conditional, ok := value.Value.(*conditionalEnum); ok {
-			if Conditional.Condition(p.Config) {
-				config.Values[value.Name] = Conditional.Value
+	if err := enum.Error(); err != nil {
+		p.problem(fmt.Errorf("invalid enum %v: %v", enum.Name(), err))
+		p.setCause("invalid enum", nil, "")
+		return nil, false
+	}
+	// Names are unique because graphql-go builds values from a map, but internal values may
+	// coincide once conditionals are unwrapped, which makes serialization ambiguous.
+	var internal []interface{}
+	var internalNames []string
+	var flags []string
+	for i, value := range sortedValues(enum.Values()) {
+		if value == nil {
+			p.problem(fmt.Errorf("enum %v has a nil value at index %v", enum.Name(), i))
+			continue
+		}
+		if !nameRegexp.MatchString(value.Name) {
+			p.problem(fmt.Errorf("enum %v has a value with invalid name %q", enum.Name(), value.Name))
+			p.setCause("invalid enum value name", nil, "")
+			p.removed("enum value", enum.Name()+"."+value.Name, enum.Name())
+			continue
+		}
+
+		var kept *graphql.EnumValueConfig
+		if conditional, ok := value.Value.(ConditionalValue); ok {
+			enabled := conditional.Enabled
+			if contextual, ok := conditional.(ConditionalValueWithContext); ok {
+				ctx := newConditionContext(enum, enum.Name()+"."+value.Name)
+				enabled = func(cfg *PreprocessorConfig) bool {
+					return contextual.EnabledWithContext(cfg, ctx)
+				}
+			}
+			if p.callCondition("enum value "+enum.Name()+"."+value.Name, func() bool {
+				return enabled(p.Config)
+			}) {
+				underlying := conditional.Underlying()
+				if underlying == nil {
+					p.problem(fmt.Errorf("enum %v has a conditional value %v with a nil config", enum.Name(), value.Name))
+					p.setCause("conditional enum value with a nil config", nil, "")
+					p.removed("enum value", enum.Name()+"."+value.Name, enum.Name())
+					continue
+				}
+				if p.Config.ConditionalNotice != "" {
+					annotated := *underlying
+					annotated.Description = p.annotate(annotated.Description)
+					underlying = &annotated
+				}
+				kept = underlying
+			} else if reason := enumDeprecationReason(p.Config, conditional); reason == "" {
+				p.setCause("conditional enum value", enabled, "")
+				p.removed("enum value", enum.Name()+"."+value.Name, enum.Name())
+				if p.cause != nil {
+					flags = append(flags, p.cause.Flags...)
+				}
+			} else if underlying := conditional.Underlying(); underlying != nil {
+				deprecated := *underlying
+				if deprecated.DeprecationReason == "" {
+					deprecated.DeprecationReason = reason
+				}
+				kept = &deprecated
+				deprecation := &Deprecation{
+					Reason: deprecated.DeprecationReason,
+					Cause:  "conditional enum value",
+				}
+				if p.report != nil {
+					deprecation.Flags = conditionFlags(p.Config, enabled, "")
+				}
+				p.deprecated("enum value", enum.Name()+"."+value.Name, enum.Name(), deprecation)
 			}
 		} else {
-			config.Values[value.Name] = &graphql.EnumValueConfig{
+			kept = &graphql.EnumValueConfig{
 				Value:             value.Value,
 				Description:       value.Description,
 				DeprecationReason: value.DeprecationReason,
 			}
 		}
+		if kept == nil {
+			continue
+		}
+
+		v := kept.Value
+		if v == nil {
+			v = value.Name
+		}
+		duplicate := false
+		for j, other := range internal {
+			if reflect.DeepEqual(v, other) {
+				p.problem(fmt.Errorf("enum %v has values %v and %v with the same internal value %v", enum.Name(), internalNames[j], value.Name, v))
+				p.setCause("duplicate enum value", nil, "")
+				p.removed("enum value", enum.Name()+"."+value.Name, enum.Name())
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		internal, internalNames = append(internal, v), append(internalNames, value.Name)
+		config.Values[value.Name] = kept
+		p.kept(enum.Name() + "." + value.Name)
+	}
+	if len(config.Values) == 0 {
+		if p.report != nil || p.Config.OnDrop != nil {
+			p.cause = &Removal{
+				Cause: "no enum values",
+				Flags: sortedUnique(flags),
+			}
+		}
+		return nil, false
 	}
-	return graphql.NewEnum(config)
+	return graphql.NewEnum(config), true
 }
 
-func (p *preprocessor) preprocessField(def *graphql.FieldDefinition) (*graphql.Field, bool) {
-	newType, ok := p.preprocessType(def.Type)
-	if !ok {
+func (p *preprocessor) applyTypeMiddleware(typeName string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	var patterns []string
+	for pattern := range p.Config.TypeMiddleware {
+		if matchCoordinate(pattern, typeName) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return resolve
+	}
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	sort.Strings(patterns)
+	for i := len(patterns) - 1; i >= 0; i-- {
+		middleware := p.Config.TypeMiddleware[patterns[i]]
+		for j := len(middleware) - 1; j >= 0; j-- {
+			resolve = middleware[j](resolve)
+		}
+	}
+	return resolve
+}
+
+// removableByConditionals returns whether the type or any type it wraps is a conditional that
+// removes its elements when disabled. Runtime flags, sunsets and fallbacks never remove elements,
+// so they aren't annotated.
+func removableByConditionals(t graphql.Type) bool {
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.List:
+			t = wrapper.OfType
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		case *Conditional:
+			if wrapper.RuntimeFlag == "" && wrapper.WhenDisabled == RemoveWhenDisabled {
+				return true
+			}
+			t = wrapper.OfType
+		default:
+			return false
+		}
+	}
+}
+
+// annotate appends the config's ConditionalNotice, if any, to the description of an element that's
+// present because of a conditional.
+func (p *preprocessor) annotate(description string) string {
+	if p.Config.ConditionalNotice == "" {
+		return description
+	}
+	if description != "" {
+		description += "\n\n"
+	}
+	return description + p.Config.ConditionalNotice
+}
+
+func (p *preprocessor) fieldAllowed(parent string, def *graphql.FieldDefinition) bool {
+	return p.policiesAllow(parent + "." + def.Name)
+}
+
+// preprocessField preprocesses a field of the named parent. The parent's original type is only
+// used to audit resolvers, and may be nil if unknown.
+func (p *preprocessor) preprocessField(parent string, parentType graphql.Type, def *graphql.FieldDefinition) (*graphql.Field, bool) {
+	defer p.enter(parent + "." + def.Name)()
+	if !p.fieldAllowed(parent, def) {
+		p.removed("field", parent+"."+def.Name, parent)
 		return nil, false
 	}
+	resolve := def.Resolve
+	if p.Config.OnTypeIdentityDependence != nil && resolve != nil {
+		resolve = p.auditTypeIdentity(parent+"."+def.Name, parentType, resolve)
+	}
+	description := def.Description
+	newType, deprecation, ok := p.preprocessTypeAt(parent+"."+def.Name, def.Type)
+	if ok && removableByConditionals(def.Type) {
+		description = p.annotate(description)
+	}
+	if !ok {
+		if !p.Config.SoftDisable && !p.Config.HideOnly {
+			p.checkFieldRemoval(parent + "." + def.Name)
+			p.removed("field", parent+"."+def.Name, parent)
+			return nil, false
+		}
+		if newType, ok = p.preprocessType(stripConditionals(def.Type)); !ok {
+			p.checkFieldRemoval(parent + "." + def.Name)
+			p.removed("field", parent+"."+def.Name, parent)
+			return nil, false
+		}
+		if p.Config.HideOnly {
+			p.hidden[parent+"."+def.Name] = true
+		} else {
+			resolve = resolveFeatureDisabled
+		}
+	}
+	if flags := runtimeFlags(def.Type); len(flags) > 0 {
+		resolve = p.runtimeFlagGuard(parent+"."+def.Name, flags, resolve)
+	}
+	resolve = p.applyResolverMiddleware(FieldInfo{
+		ParentType:  parent,
+		FieldName:   def.Name,
+		Conditional: hasConditionals(def.Type),
+	}, p.resolveWrapper(parent, def.Name, resolve))
 	f := &graphql.Field{
 		Name:              def.Name,
 		Type:              newType,
-		Resolve:           resolveWrapper(def.Resolve),
+		Resolve:           resolve,
 		DeprecationReason: def.DeprecationReason,
-		Description:       def.Description,
+		Description:       description,
+	}
+	if deprecation != nil {
+		if f.DeprecationReason == "" {
+			f.DeprecationReason = deprecation.Reason
+		}
+		if hook := p.Config.OnDeprecatedFieldResolved; hook != nil {
+			coordinate, resolve := parent+"."+def.Name, f.Resolve
+			if resolve == nil {
+				resolve = graphql.DefaultResolveFn
+			}
+			f.Resolve = func(params graphql.ResolveParams) (interface{}, error) {
+				hook(params, coordinate)
+				return resolve(params)
+			}
+		}
+		p.deprecated("field", parent+"."+def.Name, parent, deprecation)
 	}
 	if len(def.Args) > 0 {
 		f.Args = make(graphql.FieldConfigArgument)
-		for _, arg := range def.Args {
-			if newType, ok := p.preprocessType(arg.Type); ok {
-				f.Args[arg.Name()] = &graphql.ArgumentConfig{
+		for _, arg := range sortedArgs(def.Args) {
+			if c, ok := arg.Type.(*Conditional); ok && arg.DefaultValue == nil {
+				if _, ok := c.OfType.(*graphql.NonNull); ok {
+					p.warn(fmt.Errorf("%v(%v:) is a conditional non-null argument without a default value", parent+"."+def.Name, arg.Name()))
+				}
+			}
+			coordinate := parent + "." + def.Name + "(" + arg.Name() + ":)"
+			if newType, deprecation, ok := p.preprocessTypeAt(coordinate, arg.Type); ok {
+				if deprecation != nil {
+					p.deprecated("argument", coordinate, parent+"."+def.Name, deprecation)
+				}
+				config := &graphql.ArgumentConfig{
 					Type:         newType,
-					DefaultValue: arg.DefaultValue,
-					Description:  arg.Description(),
+					DefaultValue: p.normalizeDefault(coordinate, newType, arg.DefaultValue),
+					Description:  arg.PrivateDescription,
+				}
+				if removableByConditionals(arg.Type) {
+					config.Description = p.annotate(config.Description)
+				}
+				if p.Config.TransformArgument != nil {
+					p.Config.TransformArgument(coordinate, arg, config)
 				}
+				f.Args[arg.Name()] = config
+				p.kept(coordinate)
+			} else {
+				p.checkInputRemoval(parent+"."+def.Name+"("+arg.Name()+":)", arg.Type)
+				p.removed("argument", parent+"."+def.Name+"("+arg.Name()+":)", parent+"."+def.Name)
 			}
 		}
 	}
+	p.applyExample(parent+"."+def.Name, f)
+	for _, visitor := range p.Config.FieldVisitors {
+		if f = visitor(parent, def, f); f == nil {
+			p.setCause("field visitor", nil, "")
+			p.removed("field", parent+"."+def.Name, parent)
+			return nil, false
+		}
+	}
+	p.kept(parent + "." + def.Name)
 	return f, true
 }
 
+func (p *preprocessor) preprocessDirective(d *graphql.Directive) *graphql.Directive {
+	switch d {
+	case graphql.IncludeDirective, graphql.SkipDirective, graphql.DeprecatedDirective:
+		return d
+	}
+	defer p.enter("@" + d.Name)()
+	config := graphql.DirectiveConfig{
+		Name:        d.Name,
+		Description: d.Description,
+		Locations:   d.Locations,
+		Args:        graphql.FieldConfigArgument{},
+	}
+	for _, arg := range sortedArgs(d.Args) {
+		coordinate := "@" + d.Name + "(" + arg.Name() + ":)"
+		newType, deprecation, ok := p.preprocessTypeAt(coordinate, arg.Type)
+		if !ok {
+			p.checkInputRemoval(coordinate, arg.Type)
+			p.removed("argument", coordinate, "@"+d.Name)
+			continue
+		}
+		if deprecation != nil {
+			p.deprecated("argument", coordinate, "@"+d.Name, deprecation)
+		}
+		config.Args[arg.Name()] = &graphql.ArgumentConfig{
+			Type:         newType,
+			DefaultValue: p.normalizeDefault(coordinate, newType, arg.DefaultValue),
+			Description:  arg.PrivateDescription,
+		}
+		p.kept(coordinate)
+	}
+	return graphql.NewDirective(config)
+}
+
 func (p *preprocessor) preprocessInputObject(obj *graphql.InputObject) *graphql.InputObject {
+	path := p.pathSnapshot()
 	return graphql.NewInputObject(graphql.InputObjectConfig{
 		Name: obj.Name(),
 		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			defer p.resume(path)()
 			fields := graphql.InputObjectConfigFieldMap{}
-			for name, f := range obj.Fields() {
-				newType, ok := p.preprocessType(f.Type)
+			inputFields := obj.Fields()
+			for _, name := range inputFieldNames(inputFields) {
+				f := inputFields[name]
+				t := f.Type
+				if c, ok := t.(*Conditional); ok && c.RelaxNonNull && c.WhenDisabled != DeprecateWhenDisabled {
+					if nonNull, ok := c.OfType.(*graphql.NonNull); ok {
+						if p.evaluateCondition(c.declaration(), p.conditionAt(c, obj.Name()+"."+name), c.ConditionName) {
+							t = nonNull
+						} else {
+							t = nonNull.OfType
+						}
+					}
+				}
+				newType, deprecation, ok := p.preprocessTypeAt(obj.Name()+"."+name, t)
 				if !ok {
+					p.checkInputRemoval(obj.Name()+"."+name, t)
+					p.removed("input field", obj.Name()+"."+name, obj.Name())
 					continue
 				}
+				if deprecation != nil {
+					p.deprecated("input field", obj.Name()+"."+name, obj.Name(), deprecation)
+				}
 				fields[name] = &graphql.InputObjectFieldConfig{
 					Type:         newType,
 					DefaultValue: f.DefaultValue,
 					Description:  f.Description(),
 				}
+				if removableByConditionals(t) {
+					fields[name].Description = p.annotate(fields[name].Description)
+				}
+				p.kept(obj.Name() + "." + name)
+			}
+			// graphql-go can't carry this error for input objects, so it's raised like other
+			// preprocessing errors.
+			if err := obj.Error(); err != nil {
+				panic(fmt.Errorf("invalid input object %v: %v", obj.Name(), err))
+			}
+
+			// Defaults are normalized once every field is known, since they may refer to this
+			// input object, whose fields can't be requested until this thunk returns.
+			p.definingInputs[obj.Name()] = fields
+			defer delete(p.definingInputs, obj.Name())
+			for _, name := range inputConfigFieldNames(fields) {
+				f := fields[name]
+				f.DefaultValue = p.normalizeDefault(obj.Name()+"."+name, f.Type, f.DefaultValue)
 			}
 			return fields
 		}),
@@ -247,66 +1601,245 @@ func (p *preprocessor) preprocessInputObject(obj *graphql.InputObject) *graphql.
 	})
 }
 
-func (p *preprocessor) preprocessUnion(u *graphql.Union) *graphql.Union {
+// preprocessUnion preprocesses the union, returning false if all of its members are removed.
+func (p *preprocessor) preprocessUnion(u *graphql.Union) (*graphql.Union, bool) {
 	config := graphql.UnionConfig{
 		Description: u.Description(),
 		Name:        u.Name(),
-		ResolveType: u.ResolveType,
 	}
+	if u.ResolveType != nil {
+		// graphql-go requires a ResolveType for unions whose members lack IsTypeOf, so when it
+		// doesn't resolve a value, fall back to the IsTypeOf functions synthesized from GoTypes.
+		config.ResolveType = func(params graphql.ResolveTypeParams) *graphql.Object {
+			if obj := p.preprocessedObject(u.ResolveType(params)); obj != nil {
+				return obj
+			}
+			for _, obj := range config.Types {
+				if obj.IsTypeOf != nil && obj.IsTypeOf(graphql.IsTypeOfParams{Value: params.Value, Info: params.Info, Context: params.Context}) {
+					return obj
+				}
+			}
+			return nil
+		}
+	}
+	var removed, flags []string
 	for _, obj := range u.Types() {
-		config.Types = append(config.Types, p.preprocessObject(obj))
+		if newType, ok := p.preprocessType(obj); ok {
+			config.Types = append(config.Types, newType.(*graphql.Object))
+		} else {
+			removed = append(removed, obj.Name())
+			if p.cause != nil {
+				flags = append(flags, p.cause.Flags...)
+			}
+		}
+	}
+	if len(config.Types) == 0 && len(removed) > 0 {
+		if p.report != nil || p.Config.OnDrop != nil {
+			p.cause = &Removal{
+				Cause: "no union members (" + strings.Join(removed, ", ") + " removed)",
+				Flags: sortedUnique(flags),
+			}
+		}
+		return nil, false
+	}
+	return graphql.NewUnion(config), true
+}
+
+// preprocessedObject returns the preprocessed instance of an object, or nil if it was removed or
+// never preprocessed. It only reads the cache, so it's safe to use at execution time.
+func (p *preprocessor) preprocessedObject(obj *graphql.Object) *graphql.Object {
+	if obj == nil {
+		return nil
+	}
+	key := p.typeKey(obj)
+	t, ok := p.cachedType(key)
+	if !ok && p.sharing != nil {
+		// The object may have been shared by a variant preprocessed later.
+		t = p.sharing.lookup(key)
+	}
+	result, _ := t.(*graphql.Object)
+	return result
+}
+
+func (p *preprocessor) cacheType(key string, t graphql.Type) {
+	p.typesMutex.Lock()
+	defer p.typesMutex.Unlock()
+	p.PreprocessedTypes[key] = t
+}
+
+// cachedType looks up a preprocessed type at runtime. See typesMutex.
+func (p *preprocessor) cachedType(key string) (graphql.Type, bool) {
+	p.typesMutex.RLock()
+	defer p.typesMutex.RUnlock()
+	t, ok := p.PreprocessedTypes[key]
+	return t, ok
+}
+
+// originalType looks up the original type of a preprocessed type at runtime. See typesMutex.
+func (p *preprocessor) originalType(key string) (graphql.Type, bool) {
+	p.typesMutex.RLock()
+	defer p.typesMutex.RUnlock()
+	t, ok := p.OriginalTypes[key]
+	return t, ok
+}
+
+// visit applies the config's TypeVisitors to a kept named type.
+func (p *preprocessor) visit(original, result graphql.Type) graphql.Type {
+	for _, visitor := range p.Config.TypeVisitors {
+		result = visitor(original, result)
 	}
-	return graphql.NewUnion(config)
+	return result
 }
 
-func (p *preprocessor) preprocessObject(obj *graphql.Object) *graphql.Object {
+// preprocessRenamed preprocesses an enabled conditional with RenameWhenEnabled set. The copy is
+// cached under the conditional's name, separately from the original.
+func (p *preprocessor) preprocessRenamed(c *Conditional) (graphql.Type, bool) {
+	obj, ok := c.OfType.(*graphql.Object)
+	if !ok {
+		panic(fmt.Errorf("%v renames its type when enabled, but %v isn't an object", c.declaration(), c.OfType))
+	}
+	name := p.typeKey(c)
+	if !nameRegexp.MatchString(name) {
+		panic(fmt.Errorf("%v renames its type when enabled, but %q isn't a valid name", c.declaration(), name))
+	}
+	if result, ok := p.PreprocessedTypes[name]; ok {
+		return result, result != nil
+	}
+	if _, ok := p.preprocessType(obj); !ok {
+		return nil, false
+	}
+	result := p.visit(c, p.preprocessObject(obj, name))
+	p.cacheType(name, result)
+	return result, true
+}
+
+// preprocessObject preprocesses the object, naming the result with the given name.
+func (p *preprocessor) preprocessObject(obj *graphql.Object, name string) *graphql.Object {
+	path := p.pathSnapshot()
 	return graphql.NewObject(graphql.ObjectConfig{
-		Name: obj.Name(),
+		Name: name,
 		Interfaces: graphql.InterfacesThunk(func() []*graphql.Interface {
+			defer p.resume(path)()
 			ifaces := []*graphql.Interface{}
 			for _, iface := range obj.Interfaces() {
 				if newType, ok := p.preprocessType(iface); ok {
 					ifaces = append(ifaces, newType.(*graphql.Interface))
+				} else {
+					p.removed("interface", name+" implements "+iface.Name(), name)
 				}
 			}
+			if p.Config.Strict && len(ifaces) == 0 && len(obj.Interfaces()) > 0 {
+				panic(fmt.Errorf("%v implements no interfaces after preprocessing", name))
+			}
 			return ifaces
 		}),
 		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			defer p.resume(path)()
 			fields := graphql.Fields{}
-			for name, def := range obj.Fields() {
-				f, ok := p.preprocessField(def)
+			defs := obj.Fields()
+			for _, fieldName := range fieldNames(defs) {
+				def := defs[fieldName]
+				if p.Config.PropagateInterfaceFieldGates && !p.interfaceFieldsAllowed(obj, fieldName) {
+					p.removed("field", name+"."+fieldName, name)
+					continue
+				}
+				f, ok := p.preprocessField(name, obj, def)
 				if !ok {
 					continue
 				}
-				fields[name] = f
+				fields[fieldName] = f
 			}
-			if obj.Error() != nil {
-				panic(obj.Error().Error())
+			if err := obj.Error(); err != nil {
+				return invalidFields(fmt.Errorf("invalid object %v: %v", obj.Name(), err))
 			}
 			return fields
 		}),
-		IsTypeOf:    obj.IsTypeOf,
-		Description: obj.Description(),
+		IsTypeOf:    p.isTypeOf(obj, name),
+		Description: obj.PrivateDescription, // obj.Description() always returns ""
 	})
 }
 
+// isTypeOf returns the IsTypeOf function of the object's preprocessed result with the given name.
+// A renamed copy doesn't inherit the original's function, which would also claim the original's
+// values, so it only has one if the config's GoTypes maps the copy's name.
+func (p *preprocessor) isTypeOf(obj *graphql.Object, name string) graphql.IsTypeOfFn {
+	if obj.IsTypeOf != nil && name == obj.Name() {
+		return obj.IsTypeOf
+	}
+	t, ok := p.Config.GoTypes[name]
+	if !ok || t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	ptr := reflect.PtrTo(t)
+	return func(params graphql.IsTypeOfParams) bool {
+		vt := reflect.TypeOf(params.Value)
+		return vt == t || vt == ptr
+	}
+}
+
+// interfaceFieldsAllowed returns whether each interface of the object that declares the field
+// keeps it, whether it's gated by a policy or by a conditional in its type. The preprocessed
+// interfaces are consulted, so that their fields' conditions aren't evaluated again.
+func (p *preprocessor) interfaceFieldsAllowed(obj *graphql.Object, name string) bool {
+	for _, iface := range obj.Interfaces() {
+		evaluateThunks(iface)
+		if _, ok := iface.Fields()[name]; !ok {
+			continue
+		}
+		// Objects no longer implement removed interfaces.
+		t, ok := p.preprocessType(iface)
+		if !ok {
+			continue
+		}
+		if _, ok := t.(*graphql.Interface).Fields()[name]; !ok {
+			p.setCause("interface field "+iface.Name()+"."+name, nil, "")
+			return false
+		}
+	}
+	return true
+}
+
 func (p *preprocessor) preprocessInterface(iface *graphql.Interface) *graphql.Interface {
+	var resolveType graphql.ResolveTypeFn
+	if iface.ResolveType != nil {
+		// Objects that don't implement the preprocessed interface, e.g. because the interface was
+		// removed from them, are never resolved to.
+		resolveType = func(params graphql.ResolveTypeParams) *graphql.Object {
+			obj := p.preprocessedObject(iface.ResolveType(params))
+			if obj != nil {
+				for _, implemented := range obj.Interfaces() {
+					if preprocessed, _ := p.cachedType(p.typeKey(iface)); graphql.Type(implemented) == preprocessed {
+						return obj
+					}
+				}
+			}
+			return nil
+		}
+	}
+	path := p.pathSnapshot()
 	return graphql.NewInterface(graphql.InterfaceConfig{
 		Name: iface.Name(),
 		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			defer p.resume(path)()
 			fields := graphql.Fields{}
-			for name, def := range iface.Fields() {
-				f, ok := p.preprocessField(def)
+			defs := iface.Fields()
+			for _, name := range fieldNames(defs) {
+				def := defs[name]
+				f, ok := p.preprocessField(iface.Name(), iface, def)
 				if !ok {
 					continue
 				}
 				fields[name] = f
 			}
+			if err := iface.Error(); err != nil {
+				return invalidFields(fmt.Errorf("invalid interface %v: %v", iface.Name(), err))
+			}
 			return fields
 		}),
-		ResolveType: func(params graphql.ResolveTypeParams) *graphql.Object {
-			return p.preprocessObject(iface.ResolveType(params))
-		},
+		ResolveType: resolveType,
 		Description: iface.Description(),
 	})
 }
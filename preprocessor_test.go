@@ -0,0 +1,1950 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// testInput returns a schema config whose query type has the given fields, with any other types
+// that aren't reachable from them.
+func testInput(fields graphql.Fields, types ...graphql.Type) graphql.SchemaConfig {
+	return graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: fields,
+		}),
+		Types: types,
+	}
+}
+
+// expiredContext is a context that has expired, but whose Done channel is never closed. graphql-go's
+// executor returns as soon as Done is closed while its own goroutine is still writing the result, so
+// a closed channel would race.
+type expiredContext struct {
+	context.Context
+}
+
+func (expiredContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (expiredContext) Err() error {
+	return context.Canceled
+}
+
+func TestAbortOnDoneContext(t *testing.T) {
+	var mutex sync.Mutex
+	invocations := map[string]int{}
+	counting := func(name string) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				invocations[name]++
+				return name, nil
+			},
+		}
+	}
+	// The wrapped resolvers report each skipped or excluded resolver, so the test doesn't depend
+	// on when graphql-go's executor returns.
+	var resolved sync.WaitGroup
+	skipped := make(chan string, 3)
+	result, err := PreprocessSchemaConfigE(testInput(graphql.Fields{
+		"child": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Child",
+				Fields: graphql.Fields{
+					"a":       counting("a"),
+					"b":       counting("b"),
+					"cleanup": counting("cleanup"),
+				},
+			}),
+		},
+	}), &PreprocessorConfig{
+		AbortOnDoneContext:           true,
+		AbortOnDoneContextExclusions: []string{"Child.cleanup"},
+		OnResolverSkipped: func(coordinate string) {
+			skipped <- coordinate
+			resolved.Done()
+		},
+		OnResolveStart: func(_ graphql.ResolveParams, coordinate string) func(error, time.Duration) {
+			if coordinate != "Child.cleanup" {
+				return nil
+			}
+			return func(error, time.Duration) {
+				resolved.Done()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wait := func() {
+		done := make(chan struct{})
+		go func() {
+			resolved.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the resolvers")
+		}
+	}
+
+	root := map[string]interface{}{"child": map[string]interface{}{}}
+	resolved.Add(3)
+	response := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ child { a b cleanup } }`,
+		RootObject:    root,
+		Context:       expiredContext{context.Background()},
+	})
+	wait()
+	close(skipped)
+	if len(response.Errors) != 2 {
+		t.Errorf("expected context errors for a and b, got %v", response.Errors)
+	}
+	var coordinates []string
+	for coordinate := range skipped {
+		coordinates = append(coordinates, coordinate)
+	}
+	sort.Strings(coordinates)
+	if !reflect.DeepEqual(coordinates, []string{"Child.a", "Child.b"}) {
+		t.Errorf("unexpected skipped resolvers: %v", coordinates)
+	}
+	mutex.Lock()
+	if invocations["a"] != 0 || invocations["b"] != 0 {
+		t.Errorf("resolvers were invoked with a done context: %v", invocations)
+	}
+	if invocations["cleanup"] != 1 {
+		t.Errorf("the excluded resolver wasn't invoked")
+	}
+	mutex.Unlock()
+
+	resolved.Add(1)
+	response = graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ child { a b cleanup } }`,
+		RootObject:    root,
+		Context:       context.Background(),
+	})
+	wait()
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(response.Errors) > 0 || invocations["a"] != 1 || invocations["b"] != 1 {
+		t.Errorf("resolvers weren't invoked with a live context: %v, %v", response.Errors, invocations)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	billing := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BillingAccount",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"id":               &graphql.Field{Type: graphql.ID},
+		"billing":          &graphql.Field{Type: billing},
+		"experimentalA":    &graphql.Field{Type: graphql.String},
+		"experimentalBeta": &graphql.Field{Type: Beta(graphql.String)},
+		"notExperimental":  &graphql.Field{Type: graphql.String},
+	})
+	policies := []Policy{
+		{
+			Name:              "internal billing",
+			CoordinatePattern: "Billing*",
+			Condition: func(cfg *PreprocessorConfig) bool {
+				return cfg.IsEnabled("internal")
+			},
+		},
+		{
+			Name:              "experiments",
+			CoordinatePattern: "Query.experimental*",
+			ConditionName:     "flag:experiments",
+		},
+	}
+
+	for _, tc := range []struct {
+		config   *PreprocessorConfig
+		expected []string
+	}{
+		{
+			config:   &PreprocessorConfig{},
+			expected: []string{"id", "notExperimental"},
+		},
+		{
+			config:   &PreprocessorConfig{InternalFeaturesEnabled: true, Flags: map[string]bool{"experiments": true}},
+			expected: []string{"billing", "experimentalA", "id", "notExperimental"},
+		},
+		{
+			config:   &PreprocessorConfig{BetaFeaturesEnabled: true, Flags: map[string]bool{"experiments": true}},
+			expected: []string{"experimentalA", "experimentalBeta", "id", "notExperimental"},
+		},
+	} {
+		tc.config.Policies = policies
+		result, err := PreprocessSchemaConfigE(input, tc.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fields := fieldNames(result.Query.Fields()); !reflect.DeepEqual(fields, tc.expected) {
+			t.Errorf("unexpected fields %v, expected %v", fields, tc.expected)
+		}
+	}
+
+	p := NewPreprocessor(&PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+		Policies:            policies,
+	})
+	p.Preprocess(input)
+	causes := map[string]string{}
+	for _, removal := range p.Report().Removals {
+		causes[removal.Coordinate] = removal.Cause
+	}
+	for coordinate, expected := range map[string]string{
+		"Query.billing":          "policy internal billing",
+		"Query.experimentalA":    "policy experiments",
+		"Query.experimentalBeta": "policy experiments",
+	} {
+		if causes[coordinate] != expected {
+			t.Errorf("%v was removed with cause %q", coordinate, causes[coordinate])
+		}
+	}
+}
+
+func TestConditionalSuffixCollisions(t *testing.T) {
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	userBeta := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserBeta",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	_, file, line, _ := runtime.Caller(0)
+	conditional := Beta(user)
+	declaration := fmt.Sprintf("conditional UserBeta (declared at %v:%v)", file, line+1)
+
+	for _, fields := range []graphql.Fields{
+		{"a": &graphql.Field{Type: userBeta}, "b": &graphql.Field{Type: conditional}},
+		{"a": &graphql.Field{Type: conditional}, "b": &graphql.Field{Type: userBeta}},
+	} {
+		_, err := PreprocessSchemaConfigE(testInput(fields), &PreprocessorConfig{})
+		if expected := declaration + " collides with type UserBeta"; err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q, got %v", expected, err)
+		}
+	}
+
+	_, err := PreprocessSchemaConfigE(testInput(graphql.Fields{
+		"a": &graphql.Field{Type: conditional},
+		"b": &graphql.Field{Type: NewConditional(graphql.NewObject(graphql.ObjectConfig{
+			Name: "UserB",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+			},
+		}), "eta", func(*PreprocessorConfig) bool { return true })},
+	}), &PreprocessorConfig{})
+	if expected := ") collides with " + declaration; err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected a collision between conditionals, got %v", err)
+	}
+}
+
+func TestSameSuffixConditionals(t *testing.T) {
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	for _, passingFirst := range []bool{false, true} {
+		passing := NewConditional(user, "Gated", func(*PreprocessorConfig) bool { return true })
+		failing := NewConditional(user, "Gated", func(*PreprocessorConfig) bool { return false })
+		fields := graphql.Fields{"a": &graphql.Field{Type: failing}, "b": &graphql.Field{Type: passing}}
+		if passingFirst {
+			fields = graphql.Fields{"a": &graphql.Field{Type: passing}, "b": &graphql.Field{Type: failing}}
+		}
+		result, err := PreprocessSchemaConfigE(testInput(fields), &PreprocessorConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []string
+		for name := range result.Query.Fields() {
+			kept = append(kept, name)
+		}
+		if expected := map[bool]string{false: "b", true: "a"}[passingFirst]; len(kept) != 1 || kept[0] != expected {
+			t.Errorf("passing first %v: unexpected fields %v", passingFirst, kept)
+		}
+	}
+
+	_, err := PreprocessSchemaConfigE(testInput(graphql.Fields{
+		"a": &graphql.Field{Type: user},
+		"b": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+			Name: "User",
+			Fields: graphql.Fields{
+				"name": &graphql.Field{Type: graphql.String},
+			},
+		})},
+	}), &PreprocessorConfig{})
+	if err == nil || !strings.Contains(err.Error(), "distinct types are named User") {
+		t.Errorf("expected an error about distinct types named User, got %v", err)
+	}
+}
+
+type isTypeOfDog struct {
+	Name string
+}
+
+type isTypeOfCat struct {
+	Name string
+}
+
+func TestSynthesizedIsTypeOf(t *testing.T) {
+	animal := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Animal",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	dog := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Dog",
+		Interfaces: []*graphql.Interface{animal},
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	cat := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Cat",
+		Interfaces: []*graphql.Interface{animal},
+		Fields: graphql.Fields{
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	pet := graphql.NewUnion(graphql.UnionConfig{
+		Name:  "Pet",
+		Types: []*graphql.Object{dog, cat},
+		// graphql-go requires a ResolveType here, but it can defer to the synthesized IsTypeOf.
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+			return nil
+		},
+	})
+	resolve := func(p graphql.ResolveParams) (interface{}, error) {
+		values := []interface{}{isTypeOfDog{Name: "Rex"}}
+		if FlagsFromContext(p.Context).IsEnabled("beta") {
+			values = append(values, &isTypeOfCat{Name: "Tom"})
+		}
+		return values, nil
+	}
+	input := testInput(graphql.Fields{
+		"pets":    &graphql.Field{Type: graphql.NewList(pet), Resolve: resolve},
+		"animals": &graphql.Field{Type: graphql.NewList(animal), Resolve: resolve},
+		"cat":     &graphql.Field{Type: Beta(cat)},
+	}, dog)
+
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			BetaFeaturesEnabled: beta,
+			GoTypes: map[string]reflect.Type{
+				"Dog": reflect.TypeOf(isTypeOfDog{}),
+				"Cat": reflect.TypeOf(&isTypeOfCat{}),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := graphql.Do(graphql.Params{
+			Schema: schema,
+			RequestString: `{
+				pets { __typename ... on Dog { name } }
+				animals { __typename name }
+			}`,
+		})
+		if len(response.Errors) > 0 {
+			t.Fatalf("beta %v: %v", beta, response.Errors)
+		}
+		expected := `{"animals":[{"__typename":"Dog","name":"Rex"}],"pets":[{"__typename":"Dog","name":"Rex"}]}`
+		if beta {
+			expected = `{"animals":[{"__typename":"Dog","name":"Rex"},{"__typename":"Cat","name":"Tom"}],"pets":[{"__typename":"Dog","name":"Rex"},{"__typename":"Cat"}]}`
+		}
+		if data, _ := json.Marshal(response.Data); string(data) != expected {
+			t.Errorf("beta %v: unexpected data %s", beta, data)
+		}
+	}
+}
+
+func TestGateAllFields(t *testing.T) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"orders":   &graphql.Field{Type: graphql.String},
+			"invoices": &graphql.Field{Type: graphql.String},
+		},
+	})
+	policies := []Policy{
+		GateAllFields(query, func(cfg *PreprocessorConfig) bool { return cfg.BetaFeaturesEnabled }),
+	}
+
+	_, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{Policies: policies})
+	if err == nil || !strings.Contains(err.Error(), "every field of the query root type Query was removed") {
+		t.Errorf("expected an explanatory error, got %v", err)
+	}
+
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+		Policies:            policies,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Query.Name() != "Query" || len(result.Query.Fields()) != 2 {
+		t.Errorf("unexpected query type %v with fields %v", result.Query.Name(), fieldNames(result.Query.Fields()))
+	}
+}
+
+func TestSubscriptionFieldGates(t *testing.T) {
+	input := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+			},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{
+				"orderUpdated": &graphql.Field{Type: graphql.String},
+				"invoiceUpdated": BetaField(&graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if !FlagsFromContext(p.Context).IsEnabled("beta") {
+							t.Error("the resolver didn't get the config")
+						}
+						return "paid", nil
+					},
+				}),
+			},
+		}),
+	}
+	for _, beta := range []bool{false, true} {
+		config := &PreprocessorConfig{BetaFeaturesEnabled: beta}
+		preprocessed, err := PreprocessSchemaConfigE(input, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(preprocessed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := Execute(config, graphql.Params{
+			Schema:        schema,
+			RequestString: `{ __type(name: "Subscription") { fields { name } } }`,
+		})
+		data, _ := json.Marshal(result.Data)
+		if strings.Contains(string(data), "invoiceUpdated") != beta {
+			t.Errorf("beta %v: unexpected introspection %s", beta, data)
+		}
+
+		result = Execute(config, graphql.Params{
+			Schema:        schema,
+			RequestString: `subscription { invoiceUpdated }`,
+		})
+		if beta {
+			if len(result.Errors) > 0 || !reflect.DeepEqual(result.Data, map[string]interface{}{"invoiceUpdated": "paid"}) {
+				t.Errorf("beta: unexpected result %v %v", result.Data, result.Errors)
+			}
+		} else if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, `Cannot query field "invoiceUpdated"`) {
+			t.Errorf("expected a validation error, got %v", result.Errors)
+		}
+	}
+}
+
+// internalUnsuffixed suffixes names except in the internal variant, where beta types are just
+// normal types.
+type internalUnsuffixed string
+
+func (s internalUnsuffixed) Apply(baseName string, cfg *PreprocessorConfig) string {
+	if cfg != nil && cfg.InternalFeaturesEnabled {
+		return baseName
+	}
+	return baseName + string(s)
+}
+
+type vendorPrefix string
+
+func (s vendorPrefix) Apply(baseName string, cfg *PreprocessorConfig) string {
+	return string(s) + baseName
+}
+
+func TestSuffixStrategies(t *testing.T) {
+	order := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	input := func(strategy SuffixStrategy) graphql.SchemaConfig {
+		c := Beta(order)
+		c.SuffixStrategy = strategy
+		c.RenameWhenEnabled = true
+		return testInput(graphql.Fields{
+			"order":     &graphql.Field{Type: order},
+			"betaOrder": &graphql.Field{Type: c},
+		})
+	}
+
+	for _, tc := range []struct {
+		strategy SuffixStrategy
+		internal bool
+		expected string
+	}{
+		{internalUnsuffixed("Beta"), false, "OrderBeta"},
+		{internalUnsuffixed("Beta"), true, "Order"},
+		{vendorPrefix("Acme"), false, "AcmeOrder"},
+	} {
+		result, err := PreprocessSchemaConfigE(input(tc.strategy), &PreprocessorConfig{
+			BetaFeaturesEnabled:     true,
+			InternalFeaturesEnabled: tc.internal,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("%v: %v", tc.expected, err)
+		}
+		fields := result.Query.Fields()
+		if name := fields["betaOrder"].Type.Name(); name != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, name)
+		}
+		if tc.expected == "Order" && fields["betaOrder"].Type != fields["order"].Type {
+			t.Error("the unsuffixed conditional's type is a copy")
+		}
+	}
+
+	// Strategies that produce invalid or taken names are rejected.
+	_, err := PreprocessSchemaConfigE(input(vendorPrefix("acme-")), &PreprocessorConfig{BetaFeaturesEnabled: true})
+	if err == nil || !strings.Contains(err.Error(), `"acme-Order" isn't a valid name`) {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+	config := input(ConstantSuffix("Item"))
+	config.Types = []graphql.Type{graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderItem",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})}
+	_, err = PreprocessSchemaConfigE(config, &PreprocessorConfig{BetaFeaturesEnabled: true})
+	if err == nil || !strings.Contains(err.Error(), "collides with type OrderItem") {
+		t.Errorf("expected a collision, got %v", err)
+	}
+}
+
+func TestRenamedCopyCoordinates(t *testing.T) {
+	order := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.ID},
+			"total":    &graphql.Field{Type: graphql.Float},
+			"discount": &graphql.Field{Type: Flag("discounts", graphql.Float)},
+		},
+		IsTypeOf: func(graphql.IsTypeOfParams) bool {
+			return true
+		},
+	})
+	betaOrder := Beta(order)
+	betaOrder.RenameWhenEnabled = true
+	input := testInput(graphql.Fields{
+		"order":     &graphql.Field{Type: order},
+		"betaOrder": &graphql.Field{Type: betaOrder},
+	})
+	config := &PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+		Policies: []Policy{{
+			Name:              "no beta totals",
+			CoordinatePattern: "OrderBeta.total",
+			Condition: func(*PreprocessorConfig) bool {
+				return false
+			},
+		}},
+	}
+
+	p := NewPreprocessor(config)
+	result := p.Preprocess(input)
+	fields := result.Query.Fields()
+	if _, ok := fields["order"].Type.(*graphql.Object).Fields()["total"]; !ok {
+		t.Error("Order.total was removed")
+	}
+	copied := fields["betaOrder"].Type.(*graphql.Object)
+	if _, ok := copied.Fields()["total"]; ok {
+		t.Error("OrderBeta.total wasn't removed")
+	}
+	var removed []string
+	for _, removal := range p.Report().Removals {
+		removed = append(removed, removal.Coordinate)
+	}
+	if expected := []string{"Order.discount", "OrderBeta.discount", "OrderBeta.total"}; !reflect.DeepEqual(removed, expected) {
+		t.Errorf("unexpected removals %v", removed)
+	}
+	if copied.IsTypeOf != nil {
+		t.Error("the copy inherited the original's IsTypeOf")
+	}
+
+	hiding := *config
+	hiding.HideOnly = true
+	s, err := NewHidingSchema(input, &hiding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Hidden, []string{"Order.discount", "OrderBeta.discount"}) {
+		t.Errorf("unexpected hidden coordinates %v", s.Hidden)
+	}
+}
+
+func TestPassthroughTypes(t *testing.T) {
+	resolveMetric := func(graphql.ResolveParams) (interface{}, error) {
+		return 42, nil
+	}
+	vendor := graphql.NewObject(graphql.ObjectConfig{
+		Name: "VendorMetrics",
+		Fields: graphql.Fields{
+			"metric": &graphql.Field{Type: graphql.Int, Resolve: resolveMetric},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"metrics": &graphql.Field{
+			Type: vendor,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return struct{}{}, nil
+			},
+		},
+	})
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{PassthroughTypes: []string{"VendorMetrics"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := result.Query.Fields()["metrics"]
+	if metrics.Type != vendor {
+		t.Fatal("the passthrough type was copied")
+	}
+	if resolve := vendor.Fields()["metric"].Resolve; reflect.ValueOf(resolve).Pointer() != reflect.ValueOf(resolveMetric).Pointer() {
+		t.Error("the passthrough type's resolver was wrapped")
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ metrics { metric } }"})
+	if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, map[string]interface{}{"metrics": map[string]interface{}{"metric": 42}}) {
+		t.Errorf("unexpected response %v", response)
+	}
+
+	// Conditionals within passthrough types aren't honored, which is worth a warning.
+	var warnings []error
+	_, err = PreprocessSchemaConfigE(testInput(graphql.Fields{
+		"metrics": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+			Name: "VendorMetrics",
+			Fields: graphql.Fields{
+				"metric": &graphql.Field{Type: graphql.Int},
+				"beta":   &graphql.Field{Type: graphql.NewList(Beta(graphql.Int))},
+			},
+		})},
+	}), &PreprocessorConfig{
+		PassthroughTypes: []string{"VendorMetrics"},
+		OnWarning:        func(err error) { warnings = append(warnings, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "VendorMetrics.beta uses conditional IntBeta") {
+		t.Errorf("expected a warning about VendorMetrics.beta, got %v", warnings)
+	}
+}
+
+const argumentIntrospectionQuery = `{
+	__type(name: "Query") {
+		fields {
+			name
+			args { name description defaultValue type { name kind ofType { name kind } } }
+		}
+	}
+}`
+
+// introspectArguments returns the introspected arguments of the schema's query fields, sorted by
+// name since graphql-go orders arguments by map iteration.
+func introspectArguments(t *testing.T, config graphql.SchemaConfig) string {
+	schema, err := graphql.NewSchema(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{Schema: schema, RequestString: argumentIntrospectionQuery})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	fields := response.Data.(map[string]interface{})["__type"].(map[string]interface{})["fields"].([]interface{})
+	for _, field := range fields {
+		args := field.(map[string]interface{})["args"].([]interface{})
+		sort.Slice(args, func(i, j int) bool {
+			return args[i].(map[string]interface{})["name"].(string) < args[j].(map[string]interface{})["name"].(string)
+		})
+	}
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestArgumentIntrospectionPreserved(t *testing.T) {
+	sortOrder := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortOrder",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  &graphql.EnumValueConfig{Value: "asc"},
+			"DESC": &graphql.EnumValueConfig{Value: "desc"},
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "WidgetFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"widgets": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+			Args: graphql.FieldConfigArgument{
+				"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10, Description: "The number of widgets.\n\n  Indented."},
+				"after":  &graphql.ArgumentConfig{Type: graphql.String},
+				"order":  &graphql.ArgumentConfig{Type: sortOrder, DefaultValue: "desc"},
+				"filter": &graphql.ArgumentConfig{Type: filter, Description: " Leading and trailing spaces. "},
+				"ids":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.ID))},
+			},
+		},
+		"count": &graphql.Field{Type: graphql.Int},
+	})
+
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if original, preprocessed := introspectArguments(t, input), introspectArguments(t, result); original != preprocessed {
+		t.Errorf("argument introspection differs:\n%v\n%v", original, preprocessed)
+	}
+
+	var transformed []string
+	result, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		TransformArgument: func(coordinate string, original *graphql.Argument, arg *graphql.ArgumentConfig) {
+			transformed = append(transformed, coordinate)
+			if arg.Description == "" {
+				arg.Description = "Undocumented."
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Query.widgets(after:)", "Query.widgets(filter:)", "Query.widgets(first:)", "Query.widgets(ids:)", "Query.widgets(order:)"}
+	if !reflect.DeepEqual(transformed, expected) {
+		t.Errorf("unexpected transformed arguments %v", transformed)
+	}
+	for _, arg := range result.Query.Fields()["widgets"].Args {
+		if arg.Description() == "" {
+			t.Errorf("%v wasn't transformed", arg.Name())
+		}
+	}
+}
+
+func resolveWrappedTestName(graphql.ResolveParams) (interface{}, error) {
+	return "name", nil
+}
+
+type wrappedTestResolver struct {
+	value string
+}
+
+func (r *wrappedTestResolver) resolve(graphql.ResolveParams) (interface{}, error) {
+	return r.value, nil
+}
+
+func TestOnResolverWrapped(t *testing.T) {
+	method := (&wrappedTestResolver{value: "method"}).resolve
+	prefix := "bound"
+	closure := func(graphql.ResolveParams) (interface{}, error) {
+		return prefix, nil
+	}
+	originals := map[string]graphql.FieldResolveFn{
+		"Query.function": resolveWrappedTestName,
+		"Query.method":   method,
+		"Query.closure":  closure,
+	}
+	input := testInput(graphql.Fields{
+		"function": &graphql.Field{Type: graphql.String, Resolve: originals["Query.function"]},
+		"method":   &graphql.Field{Type: graphql.String, Resolve: originals["Query.method"]},
+		"closure":  &graphql.Field{Type: graphql.String, Resolve: originals["Query.closure"]},
+		"default":  &graphql.Field{Type: graphql.String},
+	})
+
+	wrapped := map[string]graphql.FieldResolveFn{}
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		OnResolverWrapped: func(coordinate string, original graphql.FieldResolveFn) {
+			wrapped[coordinate] = original
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wrapped) != len(originals) {
+		t.Errorf("unexpected wrapped resolvers %v", wrapped)
+	}
+	for coordinate, original := range originals {
+		if reflect.ValueOf(wrapped[coordinate]).Pointer() != reflect.ValueOf(original).Pointer() {
+			t.Errorf("the original resolver of %v wasn't recovered", coordinate)
+		}
+		field := result.Query.Fields()[strings.TrimPrefix(coordinate, "Query.")]
+		if reflect.ValueOf(field.Resolve).Pointer() == reflect.ValueOf(original).Pointer() {
+			t.Errorf("%v wasn't wrapped", coordinate)
+		}
+		// Method values and closures carry their receivers and captured variables.
+		expected, _ := original(graphql.ResolveParams{})
+		if value, _ := wrapped[coordinate](graphql.ResolveParams{}); value != expected {
+			t.Errorf("the recovered resolver of %v returned %v", coordinate, value)
+		}
+	}
+}
+
+func TestBetaField(t *testing.T) {
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.ID},
+			"price": BetaField(&graphql.Field{Type: graphql.Float}),
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object { return nil },
+	})
+	gadget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Gadget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.ID},
+			"price": BetaField(&graphql.Field{Type: graphql.Float}),
+			// A beta field of a beta type is removed once.
+			"gadget": BetaField(&graphql.Field{Type: Beta(gadget)}),
+		},
+	})
+	input := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"widget":     &graphql.Field{Type: widget},
+				"betaWidget": BetaField(&graphql.Field{Type: widget}),
+				// The type of a beta field is still available for other uses.
+				"total": &graphql.Field{Type: graphql.Float},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"touch":   &graphql.Field{Type: widget},
+				"reprice": BetaField(&graphql.Field{Type: widget}),
+			},
+		}),
+	}
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		preprocessedWidget := result.Query.Fields()["widget"].Type.(*graphql.Object)
+		preprocessedNode := preprocessedWidget.Interfaces()[0]
+		for coordinate, fields := range map[string]graphql.FieldDefinitionMap{
+			"Query.betaWidget": result.Query.Fields(),
+			"Mutation.reprice": result.Mutation.Fields(),
+			"Widget.price":     preprocessedWidget.Fields(),
+			"Widget.gadget":    preprocessedWidget.Fields(),
+			"Node.price":       preprocessedNode.Fields(),
+		} {
+			f, ok := fields[coordinate[strings.Index(coordinate, ".")+1:]]
+			if ok != beta {
+				t.Errorf("beta %v: %v present: %v", beta, coordinate, ok)
+			} else if ok && coordinate == "Widget.gadget" && f.Type.Name() != "Gadget" {
+				t.Errorf("Widget.gadget has type %v", f.Type)
+			}
+		}
+		if _, ok := result.Query.Fields()["total"]; !ok {
+			t.Errorf("beta %v: Query.total was removed", beta)
+		}
+	}
+}
+
+func relaxedBetaInputField(f *graphql.InputObjectFieldConfig) *graphql.InputObjectFieldConfig {
+	field := BetaInputField(f)
+	field.Type.(*Conditional).RelaxNonNull = true
+	return field
+}
+
+func TestBetaInputFields(t *testing.T) {
+	address := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "AddressInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"street":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"unit":    BetaInputField(&graphql.InputObjectFieldConfig{Type: graphql.Int}),
+			"country": relaxedBetaInputField(&graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String), DefaultValue: "US"}),
+		},
+	})
+	order := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+			"note":     BetaInputField(&graphql.InputObjectFieldConfig{Type: graphql.String}),
+			"shipping": relaxedBetaInputField(&graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(address)}),
+		},
+	})
+	input := testInput(graphql.Fields{
+		"order": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(order)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				data, err := json.Marshal(p.Args["input"])
+				return string(data), err
+			},
+		},
+	})
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+		sdl, err := SchemaConfigToSDL(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"country: String = \"US\"\n", "shipping: AddressInput\n"}
+		unexpected := []string{"note:", "unit:"}
+		if beta {
+			expected, unexpected = []string{"country: String! = \"US\"\n", "shipping: AddressInput!\n", "note: String\n", "unit: Int\n"}, nil
+		}
+		for _, s := range expected {
+			if !strings.Contains(sdl, s) {
+				t.Errorf("beta %v: the SDL doesn't contain %q:\n%v", beta, s, sdl)
+			}
+		}
+		for _, s := range unexpected {
+			if strings.Contains(sdl, s) {
+				t.Errorf("beta %v: the SDL contains %q:\n%v", beta, s, sdl)
+			}
+		}
+
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// graphql-go requires non-null input fields even if they have defaults.
+		request := `{ order(input: {id: "1", shipping: {street: "Main"}}) }`
+		if beta {
+			request = `{ order(input: {id: "1", shipping: {street: "Main", country: "US"}}) }`
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: request})
+		if len(response.Errors) > 0 {
+			t.Fatalf("beta %v: %v", beta, response.Errors)
+		}
+		if order := response.Data.(map[string]interface{})["order"]; order != `{"id":"1","shipping":{"country":"US","street":"Main"}}` {
+			t.Errorf("beta %v: unexpected order %v", beta, order)
+		}
+		response = graphql.Do(graphql.Params{Schema: schema, RequestString: `{ order(input: {id: "1"}) }`})
+		if len(response.Errors) > 0 != beta {
+			t.Errorf("beta %v: unexpected errors for an order without shipping: %v", beta, response.Errors)
+		}
+	}
+}
+
+func TestUnionMembersShareTypes(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	gadget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Gadget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	var query *graphql.Object
+	query = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"widget": &graphql.Field{Type: widget},
+				"search": &graphql.Field{
+					Type: graphql.NewUnion(graphql.UnionConfig{
+						Name:  "SearchResult",
+						Types: []*graphql.Object{widget, gadget},
+						ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+							return widget
+						},
+					}),
+					Resolve: func(graphql.ResolveParams) (interface{}, error) {
+						return map[string]interface{}{"id": "1"}, nil
+					},
+				},
+				// The root type is also referenced by a field.
+				"self": &graphql.Field{
+					Type: query,
+					Resolve: func(graphql.ResolveParams) (interface{}, error) {
+						return struct{}{}, nil
+					},
+				},
+			}
+		}),
+	})
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := result.Query.Fields()
+	if fields["search"].Type.(*graphql.Union).Types()[0] != fields["widget"].Type {
+		t.Error("the union member and Query.widget are different instances")
+	}
+	if fields["self"].Type != result.Query {
+		t.Error("Query.self and the query root are different instances")
+	}
+
+	response := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ self { search { __typename ... on Widget { id } } } }`,
+	})
+	if data, _ := json.Marshal(response.Data); len(response.Errors) > 0 || string(data) != `{"self":{"search":{"__typename":"Widget","id":"1"}}}` {
+		t.Errorf("unexpected result %s %v", data, response.Errors)
+	}
+}
+
+func TestInterfaceTypeResolution(t *testing.T) {
+	for _, withResolveType := range []bool{false, true} {
+		var widget *graphql.Object
+		nodeConfig := graphql.InterfaceConfig{
+			Name: "Node",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+			},
+		}
+		if withResolveType {
+			nodeConfig.ResolveType = func(graphql.ResolveTypeParams) *graphql.Object {
+				return widget
+			}
+		}
+		node := graphql.NewInterface(nodeConfig)
+		widgetConfig := graphql.ObjectConfig{
+			Name:       "Widget",
+			Interfaces: []*graphql.Interface{node},
+			Fields: graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+			},
+		}
+		if !withResolveType {
+			widgetConfig.IsTypeOf = func(p graphql.IsTypeOfParams) bool {
+				_, ok := p.Value.(map[string]interface{})
+				return ok
+			}
+		}
+		widget = graphql.NewObject(widgetConfig)
+		input := testInput(graphql.Fields{
+			"node": &graphql.Field{
+				Type: node,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{"id": "1"}, nil
+				},
+			},
+		}, widget)
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{})
+		if err != nil {
+			t.Fatalf("ResolveType %v: %v", withResolveType, err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatalf("ResolveType %v: %v", withResolveType, err)
+		}
+
+		preprocessedNode := result.Query.Fields()["node"].Type.(*graphql.Interface)
+		if withResolveType {
+			if obj := preprocessedNode.ResolveType(graphql.ResolveTypeParams{}); obj != schema.Type("Widget") {
+				t.Errorf("ResolveType returned %p rather than the schema's Widget %p", obj, schema.Type("Widget"))
+			}
+		} else if preprocessedNode.ResolveType != nil {
+			t.Error("the preprocessed interface has a ResolveType")
+		}
+
+		response := graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: `{ node { __typename id } }`,
+		})
+		if data, _ := json.Marshal(response.Data); len(response.Errors) > 0 || string(data) != `{"node":{"__typename":"Widget","id":"1"}}` {
+			t.Errorf("ResolveType %v: unexpected result %s %v", withResolveType, data, response.Errors)
+		}
+	}
+}
+
+// directivesTestExtension is only compared, never invoked.
+type directivesTestExtension struct {
+	graphql.Extension
+}
+
+func TestDirectiveArgumentTypes(t *testing.T) {
+	since := graphql.NewDirective(graphql.DirectiveConfig{
+		Name:      "since",
+		Locations: []string{graphql.DirectiveLocationField},
+		Args: graphql.FieldConfigArgument{
+			"at":      &graphql.ArgumentConfig{Type: graphql.DateTime},
+			"preview": BetaArg(&graphql.ArgumentConfig{Type: graphql.Boolean}),
+		},
+	})
+	extension := &directivesTestExtension{}
+	input := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"createdAt": &graphql.Field{Type: graphql.DateTime},
+			},
+		}),
+		Directives: append([]*graphql.Directive{since}, graphql.SpecifiedDirectives...),
+		Extensions: []graphql.Extension{extension},
+	}
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Extensions) != 1 || result.Extensions[0] != extension {
+			t.Errorf("beta %v: the extensions weren't carried over: %v", beta, result.Extensions)
+		}
+		// Building the schema fails if the directive references the original DateTime scalar.
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("beta %v: %v", beta, err)
+		}
+
+		if len(result.Directives) != len(input.Directives) || result.Directives[1] != graphql.IncludeDirective {
+			t.Fatalf("beta %v: unexpected directives %v", beta, result.Directives)
+		}
+		args := map[string]graphql.Type{}
+		for _, arg := range result.Directives[0].Args {
+			args[arg.Name()] = arg.Type
+		}
+		if args["at"] != fixedDateTime || result.Query.Fields()["createdAt"].Type != fixedDateTime {
+			t.Errorf("beta %v: @since(at:) has type %p and Query.createdAt %p, rather than the fixed DateTime", beta, args["at"], result.Query.Fields()["createdAt"].Type)
+		}
+		if _, ok := args["preview"]; ok != beta {
+			t.Errorf("beta %v: @since(preview:) present: %v", beta, ok)
+		}
+	}
+}
+
+func TestOnResolveStart(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"ok": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					time.Sleep(time.Millisecond)
+					return "ok", nil
+				},
+			},
+			"failed": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					return nil, sentinel
+				},
+			},
+			"panicked": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					panic("boom")
+				},
+			},
+		},
+	})
+	type observation struct {
+		err      error
+		duration time.Duration
+	}
+	observations := map[string]observation{}
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{
+		OnResolveStart: func(params graphql.ResolveParams, coordinate string) func(error, time.Duration) {
+			return func(err error, duration time.Duration) {
+				observations[coordinate] = observation{err, duration}
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graphql.Do(graphql.Params{Schema: schema, RequestString: `{ ok failed panicked }`})
+
+	if o, ok := observations["Query.ok"]; !ok || o.err != nil || o.duration < time.Millisecond {
+		t.Errorf("unexpected observation of Query.ok: %+v", o)
+	}
+	if o := observations["Query.failed"]; o.err != sentinel {
+		t.Errorf("unexpected observation of Query.failed: %+v", o)
+	}
+	var panicErr *PanicError
+	if o := observations["Query.panicked"]; !errors.As(o.err, &panicErr) || panicErr.Error() != "boom" {
+		t.Errorf("unexpected observation of Query.panicked: %+v", o)
+	}
+}
+
+func BenchmarkOnResolveStart(b *testing.B) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(graphql.ResolveParams) (interface{}, error) {
+					return "name", nil
+				},
+			},
+		},
+	})
+	for name, onResolveStart := range map[string]func(graphql.ResolveParams, string) func(error, time.Duration){
+		"Nil": nil,
+		"NoOp": func(graphql.ResolveParams, string) func(error, time.Duration) {
+			return func(error, time.Duration) {}
+		},
+	} {
+		b.Run(name, func(b *testing.B) {
+			result := PreprocessSchemaConfig(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{OnResolveStart: onResolveStart})
+			resolve := result.Query.Fields()["name"].Resolve
+			params := graphql.ResolveParams{Context: WithFlags(context.Background(), &PreprocessorConfig{})}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resolve(params)
+			}
+		})
+	}
+}
+
+func TestConditionalNotice(t *testing.T) {
+	order := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Order",
+		Description: "An order.",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.ID},
+			"refund": &graphql.Field{Type: Beta(graphql.Float), Description: "The refunded amount."},
+		},
+	})
+	status := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"OPEN":     &graphql.EnumValueConfig{Value: "open"},
+			"REFUNDED": BetaEnum(&graphql.EnumValueConfig{Value: "refunded"}),
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"status": &graphql.InputObjectFieldConfig{Type: status},
+			"refunded": &graphql.InputObjectFieldConfig{
+				Type:        Beta(graphql.Boolean),
+				Description: "Only refunded orders.",
+			},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"order": &graphql.Field{
+			Type: order,
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: filter},
+				"at":     &graphql.ArgumentConfig{Type: Beta(graphql.String)},
+			},
+		},
+		// Order is reached through a conditional and directly, but its fields are
+		// annotated once and Order itself isn't annotated.
+		"betaOrder": &graphql.Field{Type: Beta(order)},
+	})
+
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			BetaFeaturesEnabled: beta,
+			ConditionalNotice:   "Beta feature.",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatal(err)
+		}
+
+		descriptions := map[string]string{}
+		for name, field := range result.Query.Fields() {
+			descriptions["Query."+name] = field.Description
+			for _, arg := range field.Args {
+				descriptions["Query."+name+"("+arg.Name()+":)"] = arg.Description()
+			}
+		}
+		preprocessedOrder := result.Query.Fields()["order"].Type.(*graphql.Object)
+		descriptions["Order"] = preprocessedOrder.PrivateDescription
+		for name, field := range preprocessedOrder.Fields() {
+			descriptions["Order."+name] = field.Description
+		}
+		var preprocessedFilter *graphql.InputObject
+		for _, arg := range result.Query.Fields()["order"].Args {
+			if arg.Name() == "filter" {
+				preprocessedFilter = arg.Type.(*graphql.InputObject)
+			}
+		}
+		for name, field := range preprocessedFilter.Fields() {
+			descriptions["Filter."+name] = field.Description()
+		}
+		for _, value := range preprocessedFilter.Fields()["status"].Type.(*graphql.Enum).Values() {
+			descriptions["Status."+value.Name] = value.Description
+		}
+
+		expected := map[string]string{
+			"Query.order":          "",
+			"Query.order(filter:)": "",
+			"Order":                "An order.",
+			"Order.id":             "",
+			"Filter.status":        "",
+			"Status.OPEN":          "",
+		}
+		if beta {
+			expected["Query.betaOrder"] = "Beta feature."
+			expected["Query.order(at:)"] = "Beta feature."
+			expected["Order.refund"] = "The refunded amount.\n\nBeta feature."
+			expected["Filter.refunded"] = "Only refunded orders.\n\nBeta feature."
+			expected["Status.REFUNDED"] = "Beta feature."
+		}
+		if !reflect.DeepEqual(descriptions, expected) {
+			t.Errorf("beta %v: unexpected descriptions %q", beta, descriptions)
+		}
+	}
+}
+
+func TestConditionalNoticeSkipsPermanentFields(t *testing.T) {
+	input := testInput(graphql.Fields{
+		"beta":     &graphql.Field{Type: Beta(graphql.String)},
+		"runtime":  &graphql.Field{Type: RuntimeFlag("refunds", graphql.String)},
+		"sunset":   &graphql.Field{Type: Sunset(graphql.String, "Use beta.")},
+		"fallback": &graphql.Field{Type: Fallback(Beta(graphql.Int), graphql.String)},
+	})
+	for _, beta := range []bool{false, true} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			BetaFeaturesEnabled: beta,
+			ConditionalNotice:   "Beta feature.",
+			RuntimeFlagChecker: func(context.Context, string) bool {
+				return true
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		descriptions := map[string]string{}
+		for name, field := range result.Query.Fields() {
+			descriptions[name] = field.Description
+		}
+		expected := map[string]string{"runtime": "", "sunset": "", "fallback": ""}
+		if beta {
+			expected["beta"] = "Beta feature."
+		}
+		if !reflect.DeepEqual(descriptions, expected) {
+			t.Errorf("beta %v: unexpected descriptions %q", beta, descriptions)
+		}
+	}
+}
+
+func TestTypeVisitors(t *testing.T) {
+	var user *graphql.Object
+	user = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":     &graphql.Field{Type: graphql.ID},
+				"friend": &graphql.Field{Type: user},
+			}
+		}),
+	})
+	input := testInput(graphql.Fields{
+		"user": &graphql.Field{
+			Type: user,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1", "friend": map[string]interface{}{"id": "2"}}, nil
+			},
+		},
+	})
+
+	var order []string
+	prefix := func(visitor, prefix string) func(original, preprocessed graphql.Type) graphql.Type {
+		return func(original, preprocessed graphql.Type) graphql.Type {
+			order = append(order, visitor+":"+original.Name())
+			obj, ok := preprocessed.(*graphql.Object)
+			if !ok {
+				return preprocessed
+			}
+			return graphql.NewObject(graphql.ObjectConfig{
+				Name: prefix + obj.Name(),
+				Fields: graphql.FieldsThunk(func() graphql.Fields {
+					fields := graphql.Fields{}
+					for name, def := range obj.Fields() {
+						fields[name] = &graphql.Field{Type: def.Type, Resolve: def.Resolve}
+					}
+					return fields
+				}),
+			})
+		}
+	}
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+		TypeVisitors: []func(original, preprocessed graphql.Type) graphql.Type{
+			prefix("first", "Tenant_"),
+			prefix("second", "Acme_"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: "{ __typename user { __typename id friend { __typename id } } }",
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	expected := map[string]interface{}{
+		"__typename": "Acme_Tenant_Query",
+		"user": map[string]interface{}{
+			"__typename": "Acme_Tenant_User",
+			"id":         "1",
+			"friend":     map[string]interface{}{"__typename": "Acme_Tenant_User", "id": "2"},
+		},
+	}
+	if !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("unexpected data %v", response.Data)
+	}
+
+	// Each type is visited once, by each visitor in order.
+	visits := map[string][]string{}
+	for _, visit := range order {
+		name := visit[strings.Index(visit, ":")+1:]
+		visits[name] = append(visits[name], visit)
+	}
+	for _, name := range []string{"Query", "User", "ID"} {
+		if expected := []string{"first:" + name, "second:" + name}; !reflect.DeepEqual(visits[name], expected) {
+			t.Errorf("%v was visited by %v", name, visits[name])
+		}
+	}
+}
+
+func TestFieldVisitors(t *testing.T) {
+	var widget *graphql.Object
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.ID},
+			"adminNote": &graphql.Field{Type: graphql.String},
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+			return widget
+		},
+	})
+	widget = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.ID},
+			"adminNote": &graphql.Field{Type: graphql.String},
+			"secret":    &graphql.Field{Type: Beta(graphql.String)},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"node": &graphql.Field{
+			Type: node,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1"}, nil
+			},
+		},
+	}, widget)
+
+	var visited []string
+	p := NewPreprocessor(&PreprocessorConfig{
+		FieldVisitors: []func(string, *graphql.FieldDefinition, *graphql.Field) *graphql.Field{
+			func(parent string, original *graphql.FieldDefinition, rebuilt *graphql.Field) *graphql.Field {
+				visited = append(visited, parent+"."+original.Name)
+				if strings.HasPrefix(original.Name, "admin") {
+					return nil
+				}
+				return rebuilt
+			},
+			func(parent string, original *graphql.FieldDefinition, rebuilt *graphql.Field) *graphql.Field {
+				rebuilt.Description = "Visited."
+				return rebuilt
+			},
+		},
+	})
+	result := p.Preprocess(input)
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Widget.secret is removed by its conditional, so it's never visited.
+	sort.Strings(visited)
+	if expected := []string{"Node.adminNote", "Node.id", "Query.node", "Widget.adminNote", "Widget.id"}; !reflect.DeepEqual(visited, expected) {
+		t.Errorf("unexpected visits %v", visited)
+	}
+
+	response := graphql.Do(graphql.Params{
+		Schema: schema,
+		RequestString: `{
+			node { id ... on Widget { id } }
+			__type(name: "Widget") { fields { name description } }
+		}`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	expected := map[string]interface{}{
+		"node": map[string]interface{}{"id": "1"},
+		"__type": map[string]interface{}{
+			"fields": []interface{}{
+				map[string]interface{}{"name": "id", "description": "Visited."},
+			},
+		},
+	}
+	if !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("unexpected data %v", response.Data)
+	}
+
+	causes := map[string]string{}
+	for _, removal := range p.Report().Removals {
+		causes[removal.Coordinate] = removal.Cause
+	}
+	for coordinate, cause := range map[string]string{
+		"Node.adminNote":   "field visitor",
+		"Widget.adminNote": "field visitor",
+		"Widget.secret":    "conditional StringBeta",
+	} {
+		if causes[coordinate] != cause {
+			t.Errorf("%v was removed with cause %q", coordinate, causes[coordinate])
+		}
+	}
+}
+
+func TestInterfaceRemoval(t *testing.T) {
+	var widget, gadget *graphql.Object
+	resolveType := func(p graphql.ResolveTypeParams) *graphql.Object {
+		if p.Value.(map[string]interface{})["kind"] == "gadget" {
+			return gadget
+		}
+		return widget
+	}
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+		ResolveType: resolveType,
+	})
+	auditable := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Auditable",
+		Fields: graphql.Fields{
+			"auditedAt": &graphql.Field{Type: graphql.String},
+		},
+		ResolveType: resolveType,
+	})
+	widget = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Widget",
+		Interfaces: []*graphql.Interface{node, auditable},
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.ID},
+			"auditedAt": &graphql.Field{Type: graphql.String},
+		},
+	})
+	gadget = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "Gadget",
+		Interfaces: []*graphql.Interface{auditable},
+		Fields: graphql.Fields{
+			"auditedAt": &graphql.Field{Type: graphql.String},
+		},
+	})
+	input := testInput(graphql.Fields{
+		"node": &graphql.Field{
+			Type: node,
+			Args: graphql.FieldConfigArgument{
+				"kind": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1", "kind": p.Args["kind"]}, nil
+			},
+		},
+		"audited": &graphql.Field{Type: auditable},
+	}, widget, gadget)
+	policies := []Policy{{Name: "audit", CoordinatePattern: "Auditable", ConditionName: "flag:audit"}}
+
+	for _, audit := range []bool{false, true} {
+		dropped := map[string]string{}
+		p := NewPreprocessor(&PreprocessorConfig{
+			Flags:    map[string]bool{"audit": audit},
+			Policies: policies,
+			OnDrop: func(coordinate, reason string) {
+				dropped[coordinate] = reason
+			},
+		})
+		result := p.Preprocess(input)
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatalf("audit %v: %v", audit, err)
+		}
+
+		var interfaces []string
+		for _, iface := range result.Types[0].(*graphql.Object).Interfaces() {
+			interfaces = append(interfaces, iface.Name())
+		}
+		if expected := map[bool][]string{false: {"Node"}, true: {"Node", "Auditable"}}[audit]; !reflect.DeepEqual(interfaces, expected) {
+			t.Errorf("audit %v: Widget implements %v", audit, interfaces)
+		}
+
+		removals := map[string]Removal{}
+		for _, removal := range p.Report().Removals {
+			removals[removal.Coordinate] = removal
+		}
+		for _, coordinate := range []string{"Widget implements Auditable", "Gadget implements Auditable"} {
+			if removal, ok := removals[coordinate]; ok != !audit || ok && (removal.Kind != "interface" || removal.Cause != "policy audit") {
+				t.Errorf("audit %v: unexpected removal of %v: %+v", audit, coordinate, removal)
+			}
+			if reason, ok := dropped[coordinate]; ok != !audit || ok && reason != "policy audit" {
+				t.Errorf("audit %v: unexpected drop of %v: %q", audit, coordinate, reason)
+			}
+		}
+
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ node { id ... on Widget { auditedAt } } }`})
+		if len(response.Errors) > 0 {
+			t.Errorf("audit %v: %v", audit, response.Errors)
+		}
+
+		// Gadget doesn't implement Node, so Node's ResolveType never routes to it.
+		response = graphql.Do(graphql.Params{Schema: schema, RequestString: `{ node(kind: "gadget") { id } }`})
+		if len(response.Errors) != 1 || !reflect.DeepEqual(response.Data, map[string]interface{}{"node": nil}) {
+			t.Errorf("audit %v: unexpected response %v", audit, response)
+		}
+	}
+
+	// Gadget's only interface is removed.
+	_, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Policies: policies, Strict: true})
+	if err == nil || !strings.Contains(err.Error(), "Gadget implements no interfaces after preprocessing") {
+		t.Errorf("expected a strict error about Gadget, got %v", err)
+	}
+}
+
+func TestUnionMemberRemoval(t *testing.T) {
+	var article, video *graphql.Object
+	article = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: graphql.Fields{
+			"title": &graphql.Field{Type: graphql.String},
+		},
+	})
+	video = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Video",
+		Fields: graphql.Fields{
+			"url": &graphql.Field{Type: graphql.String},
+		},
+	})
+	searchResult := graphql.NewUnion(graphql.UnionConfig{
+		Name:  "SearchResult",
+		Types: []*graphql.Object{article, video},
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			if _, ok := p.Value.(map[string]interface{})["url"]; ok {
+				return video
+			}
+			return article
+		},
+	})
+	input := testInput(graphql.Fields{
+		"id": &graphql.Field{Type: graphql.ID},
+		"search": &graphql.Field{
+			Type: graphql.NewList(searchResult),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return []interface{}{map[string]interface{}{"url": "https://example.com"}}, nil
+			},
+		},
+	})
+	policies := []Policy{
+		{Name: "articles", CoordinatePattern: "Article", ConditionName: "flag:articles"},
+		{Name: "videos", CoordinatePattern: "Video", ConditionName: "flag:videos"},
+	}
+
+	for _, videos := range []bool{false, true} {
+		p := NewPreprocessor(&PreprocessorConfig{
+			Flags:    map[string]bool{"videos": videos},
+			Policies: policies,
+		})
+		preprocessed := p.Preprocess(input)
+		schema, err := graphql.NewSchema(preprocessed)
+		if err != nil {
+			t.Fatalf("videos %v: %v", videos, err)
+		}
+
+		if !videos {
+			if _, ok := preprocessed.Query.Fields()["search"]; ok {
+				t.Error("Query.search wasn't removed")
+			}
+			var removal Removal
+			for _, r := range p.Report().Removals {
+				if r.Coordinate == "Query.search" {
+					removal = r
+				}
+			}
+			if removal.Cause != "no union members (Article, Video removed)" || !reflect.DeepEqual(removal.Flags, []string{"articles", "videos"}) {
+				t.Errorf("unexpected removal of Query.search: %+v", removal)
+			}
+			continue
+		}
+
+		response := graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: `{ search { __typename ... on Video { url } } }`,
+		})
+		expected := map[string]interface{}{
+			"search": []interface{}{
+				map[string]interface{}{"__typename": "Video", "url": "https://example.com"},
+			},
+		}
+		if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, expected) {
+			t.Errorf("unexpected response %v", response)
+		}
+	}
+}
+
+func TestSunset(t *testing.T) {
+	status := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"OPEN": &graphql.EnumValueConfig{Value: "open"},
+			"HELD": SunsetEnum(&graphql.EnumValueConfig{Value: "held"}, "Holds are going away."),
+		},
+	})
+	input := testInput(graphql.Fields{
+		"legacy": &graphql.Field{
+			Type: Sunset(graphql.String, "This feature is being removed."),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "still works", nil
+			},
+		},
+		"older": &graphql.Field{
+			Type:              Sunset(graphql.String, "This feature is being removed."),
+			DeprecationReason: "Use legacy.",
+		},
+		"status": &graphql.Field{
+			Type: status,
+			Args: graphql.FieldConfigArgument{
+				"hint": &graphql.ArgumentConfig{Type: Sunset(graphql.String, "Hints are ignored.")},
+			},
+		},
+	})
+
+	var resolved []string
+	p := NewPreprocessor(&PreprocessorConfig{
+		OnDeprecatedFieldResolved: func(params graphql.ResolveParams, coordinate string) {
+			resolved = append(resolved, coordinate)
+		},
+	})
+	schema, err := graphql.NewSchema(p.Preprocess(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{
+		Schema: schema,
+		RequestString: `{
+			legacy
+			__type(name: "Query") { fields(includeDeprecated: true) { name isDeprecated deprecationReason args { name } } }
+			status: __type(name: "Status") { enumValues(includeDeprecated: true) { name isDeprecated deprecationReason } }
+		}`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	data := response.Data.(map[string]interface{})
+	if data["legacy"] != "still works" || !reflect.DeepEqual(resolved, []string{"Query.legacy"}) {
+		t.Errorf("unexpected resolution %v of %v", data["legacy"], resolved)
+	}
+	expected := map[string]interface{}{
+		"fields": []interface{}{
+			map[string]interface{}{"name": "legacy", "isDeprecated": true, "deprecationReason": "This feature is being removed.", "args": []interface{}{}},
+			map[string]interface{}{"name": "older", "isDeprecated": true, "deprecationReason": "Use legacy.", "args": []interface{}{}},
+			map[string]interface{}{"name": "status", "isDeprecated": false, "deprecationReason": "", "args": []interface{}{map[string]interface{}{"name": "hint"}}},
+		},
+	}
+	if !reflect.DeepEqual(data["__type"], expected) {
+		t.Errorf("unexpected fields %v", data["__type"])
+	}
+	values := map[string]interface{}{}
+	for _, value := range data["status"].(map[string]interface{})["enumValues"].([]interface{}) {
+		values[value.(map[string]interface{})["name"].(string)] = value
+	}
+	expected = map[string]interface{}{
+		"HELD": map[string]interface{}{"name": "HELD", "isDeprecated": true, "deprecationReason": "Holds are going away."},
+		"OPEN": map[string]interface{}{"name": "OPEN", "isDeprecated": false, "deprecationReason": ""},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected enum values %v", values)
+	}
+
+	report := p.Report()
+	if len(report.Removals) > 0 {
+		t.Errorf("unexpected removals %+v", report.Removals)
+	}
+	var deprecations []string
+	for _, deprecation := range report.Deprecations {
+		deprecations = append(deprecations, deprecation.Kind+" "+deprecation.Coordinate+": "+deprecation.Reason)
+	}
+	// Elements that graphql-go can't deprecate, or that are already deprecated, are reported too.
+	if expected := []string{
+		"field Query.legacy: This feature is being removed.",
+		"field Query.older: This feature is being removed.",
+		"argument Query.status(hint:): Hints are ignored.",
+		"enum value Status.HELD: Holds are going away.",
+	}; !reflect.DeepEqual(deprecations, expected) {
+		t.Errorf("unexpected deprecations %q", deprecations)
+	}
+}
+
+func TestOmitRoots(t *testing.T) {
+	shared := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Shared",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	event := graphql.NewObject(graphql.ObjectConfig{
+		Name: "WidgetEvent",
+		Fields: graphql.Fields{
+			"widget": &graphql.Field{Type: widget},
+			"shared": &graphql.Field{Type: shared},
+		},
+	})
+	input := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"shared": &graphql.Field{Type: shared},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createWidget": &graphql.Field{Type: widget},
+			},
+		}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Subscription",
+			Fields: graphql.Fields{
+				"widgetEvents": &graphql.Field{Type: event},
+			},
+		}),
+		Types: []graphql.Type{event, widget, shared},
+	}
+
+	for _, prune := range []bool{false, true} {
+		for _, beta := range []bool{false, true} {
+			p := NewPreprocessor(&PreprocessorConfig{
+				BetaFeaturesEnabled: beta,
+				PruneUnreachable:    prune,
+				OmitSubscription: func(cfg *PreprocessorConfig) bool {
+					return !cfg.IsEnabled("beta")
+				},
+			})
+			result := p.Preprocess(input)
+			schema, err := graphql.NewSchema(result)
+			if err != nil {
+				t.Fatalf("prune %v, beta %v: %v", prune, beta, err)
+			}
+			if (result.Subscription != nil) != beta || result.Mutation == nil {
+				t.Errorf("prune %v, beta %v: unexpected roots", prune, beta)
+			}
+			var types []string
+			for _, named := range result.Types {
+				types = append(types, named.Name())
+			}
+			expected := []string{"Widget", "Shared"}
+			if beta {
+				expected = []string{"WidgetEvent", "Widget", "Shared"}
+			}
+			if !reflect.DeepEqual(types, expected) {
+				t.Errorf("prune %v, beta %v: unexpected types %v", prune, beta, types)
+			}
+
+			response := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ __schema { subscriptionType { name } } }`})
+			var subscriptionType interface{}
+			if beta {
+				subscriptionType = map[string]interface{}{"name": "Subscription"}
+			}
+			if expected := map[string]interface{}{"__schema": map[string]interface{}{"subscriptionType": subscriptionType}}; len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, expected) {
+				t.Errorf("prune %v, beta %v: unexpected response %v", prune, beta, response)
+			}
+
+			removals := map[string]Removal{}
+			for _, removal := range p.Report().Removals {
+				removals[removal.Coordinate] = removal
+			}
+			if removal, ok := removals["Subscription"]; ok == beta || !beta && (removal.Cause != "omitted subscription root" || !reflect.DeepEqual(removal.Flags, []string{"beta"})) {
+				t.Errorf("prune %v, beta %v: unexpected removal of Subscription: %+v", prune, beta, removal)
+			}
+			if _, ok := removals["WidgetEvent"]; ok == beta {
+				t.Errorf("prune %v, beta %v: WidgetEvent removed: %v", prune, beta, ok)
+			}
+		}
+	}
+}
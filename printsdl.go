@@ -0,0 +1,289 @@
+package graphqlapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+var specifiedScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// SchemaConfigToSDL evaluates the config's thunks and prints it as SDL. Types, fields, arguments,
+// input fields, and enum values are sorted by name so that the output is suitable for golden
+// files. Conditionals are printed using their names.
+func SchemaConfigToSDL(config graphql.SchemaConfig) (sdl string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to print schema: %v", r)
+		}
+	}()
+
+	types := schemaTypes(config)
+	for _, t := range types {
+		if err := t.Error(); err != nil {
+			return "", fmt.Errorf("invalid type %v: %v", t.Name(), err)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Name() < types[j].Name()
+	})
+
+	var blocks []string
+	if schema := schemaDefinitionSDL(config); schema != "" {
+		blocks = append(blocks, schema)
+	}
+	var directives []*graphql.Directive
+	for _, d := range config.Directives {
+		switch d {
+		case graphql.IncludeDirective, graphql.SkipDirective, graphql.DeprecatedDirective:
+		default:
+			directives = append(directives, d)
+		}
+	}
+	sort.Slice(directives, func(i, j int) bool {
+		return directives[i].Name < directives[j].Name
+	})
+	for _, d := range directives {
+		blocks = append(blocks, descriptionSDL("", d.Description)+"directive @"+d.Name+argumentsSDL("", d.Args)+" on "+strings.Join(d.Locations, " | "))
+	}
+	for _, t := range types {
+		if block := typeSDL(t); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, "\n\n") + "\n", nil
+}
+
+// HashSchemaConfig returns a digest of the config's SDL as printed by SchemaConfigToSDL. It's
+// stable across runs, so it's suitable for cache keys and change detection.
+func HashSchemaConfig(config graphql.SchemaConfig) (string, error) {
+	sdl, err := SchemaConfigToSDL(config)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(sdl))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func schemaDefinitionSDL(config graphql.SchemaConfig) string {
+	var operations []string
+	custom := false
+	for _, root := range []struct {
+		operation, name string
+		obj             *graphql.Object
+	}{
+		{"query", "Query", config.Query},
+		{"mutation", "Mutation", config.Mutation},
+		{"subscription", "Subscription", config.Subscription},
+	} {
+		if root.obj == nil {
+			continue
+		}
+		operations = append(operations, "  "+root.operation+": "+root.obj.Name())
+		custom = custom || root.obj.Name() != root.name
+	}
+	if !custom {
+		return ""
+	}
+	return "schema {\n" + strings.Join(operations, "\n") + "\n}"
+}
+
+func descriptionSDL(indent, description string) string {
+	if description == "" {
+		return ""
+	}
+	lines := strings.Split(strings.Replace(description, `"""`, `\"""`, -1), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return indent + `"""` + "\n" + strings.Join(lines, "\n") + "\n" + indent + `"""` + "\n"
+}
+
+func deprecationSDL(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case graphql.DefaultDeprecationReason:
+		return " @deprecated"
+	}
+	return " @deprecated(reason: " + stringLiteral(reason) + ")"
+}
+
+func stringLiteral(s string) string {
+	buf, _ := json.Marshal(s)
+	return string(buf)
+}
+
+func typeSDL(t graphql.Type) string {
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		if specifiedScalars[t.Name()] {
+			return ""
+		}
+		return descriptionSDL("", t.Description()) + "scalar " + t.Name()
+	case *ScalarVariants:
+		return descriptionSDL("", t.Description()) + "scalar " + t.Name()
+	case *graphql.Object:
+		var names []string
+		for _, iface := range t.Interfaces() {
+			names = append(names, iface.Name())
+		}
+		sort.Strings(names)
+		implements := ""
+		if len(names) > 0 {
+			implements = " implements " + strings.Join(names, " & ")
+		}
+		return descriptionSDL("", t.PrivateDescription) + "type " + t.Name() + implements + fieldsSDL(t.Fields())
+	case *graphql.Interface:
+		return descriptionSDL("", t.Description()) + "interface " + t.Name() + fieldsSDL(t.Fields())
+	case *graphql.Union:
+		var names []string
+		for _, obj := range t.Types() {
+			names = append(names, obj.Name())
+		}
+		return descriptionSDL("", t.Description()) + "union " + t.Name() + " = " + strings.Join(names, " | ")
+	case *graphql.Enum:
+		values := t.Values()
+		sort.Slice(values, func(i, j int) bool {
+			return values[i].Name < values[j].Name
+		})
+		var lines []string
+		for _, value := range values {
+			lines = append(lines, descriptionSDL("  ", value.Description)+"  "+value.Name+deprecationSDL(value.DeprecationReason))
+		}
+		return descriptionSDL("", t.Description()) + "enum " + t.Name() + " {\n" + strings.Join(lines, "\n") + "\n}"
+	case *graphql.InputObject:
+		fields := t.Fields()
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var lines []string
+		for _, name := range names {
+			f := fields[name]
+			lines = append(lines, descriptionSDL("  ", f.Description())+"  "+name+": "+f.Type.String()+defaultValueSDL(f.Type, f.DefaultValue))
+		}
+		return descriptionSDL("", t.Description()) + "input " + t.Name() + " {\n" + strings.Join(lines, "\n") + "\n}"
+	}
+	return ""
+}
+
+func fieldsSDL(fields graphql.FieldDefinitionMap) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		def := fields[name]
+		lines = append(lines, descriptionSDL("  ", def.Description)+"  "+name+argumentsSDL("  ", def.Args)+": "+def.Type.String()+deprecationSDL(def.DeprecationReason))
+	}
+	return " {\n" + strings.Join(lines, "\n") + "\n}"
+}
+
+// argumentsSDL prints the arguments of a field or directive declared at the given indentation. If
+// any argument has a description, each argument is printed on its own line.
+func argumentsSDL(indent string, args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	sorted := append([]*graphql.Argument(nil), args...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name() < sorted[j].Name()
+	})
+	described := false
+	for _, arg := range sorted {
+		if arg.Description() != "" {
+			described = true
+		}
+	}
+	var parts []string
+	for _, arg := range sorted {
+		part := arg.Name() + ": " + arg.Type.String() + defaultValueSDL(arg.Type, arg.DefaultValue)
+		if described {
+			part = descriptionSDL(indent+"  ", arg.Description()) + indent + "  " + part
+		}
+		parts = append(parts, part)
+	}
+	if described {
+		return "(\n" + strings.Join(parts, "\n") + "\n" + indent + ")"
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func defaultValueSDL(t graphql.Type, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return " = " + valueLiteral(t, value)
+}
+
+// valueLiteral prints a coerced input value as a GraphQL literal.
+func valueLiteral(t graphql.Type, value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return valueLiteral(t.OfType, value)
+	case *Conditional:
+		return valueLiteral(t.OfType, value)
+	case *graphql.List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return valueLiteral(t.OfType, value)
+		}
+		elements := make([]string, v.Len())
+		for i := range elements {
+			elements[i] = valueLiteral(t.OfType, v.Index(i).Interface())
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *graphql.InputObject:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		fields := t.Fields()
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var parts []string
+		for _, name := range names {
+			if f, ok := fields[name]; ok {
+				parts = append(parts, name+": "+valueLiteral(f.Type, m[name]))
+			}
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case *graphql.Enum:
+		if name, ok := t.Serialize(value).(string); ok {
+			return name
+		}
+	case graphql.Leaf:
+		switch serialized := t.Serialize(value).(type) {
+		case string:
+			return stringLiteral(serialized)
+		case nil:
+		default:
+			return fmt.Sprint(serialized)
+		}
+	}
+	return stringLiteral(fmt.Sprint(value))
+}
@@ -0,0 +1,165 @@
+package graphqlapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func printSDLTestInput() graphql.SchemaConfig {
+	order := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Order",
+		Description: "A customer's order.",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"total":     &graphql.Field{Type: graphql.Float, Description: "The total in cents.", DeprecationReason: "Use amount."},
+			"createdAt": &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+	order.PrivateDescription = "A customer's order."
+	status := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"OPEN":   &graphql.EnumValueConfig{Value: "open"},
+			"CLOSED": &graphql.EnumValueConfig{Value: "closed", DeprecationReason: "Orders are never closed."},
+			"HELD":   BetaEnum(&graphql.EnumValueConfig{Value: "held"}),
+		},
+	})
+	betaOrder := Beta(order)
+	betaOrder.RenameWhenEnabled = true
+	return testInput(graphql.Fields{
+		"order": &graphql.Field{
+			Type: order,
+			Args: graphql.FieldConfigArgument{
+				"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				"status": &graphql.ArgumentConfig{Type: status, Description: "Only return orders with this status."},
+			},
+		},
+		"betaOrder": &graphql.Field{Type: betaOrder},
+		"since":     &graphql.Field{Type: graphql.DateTime},
+	})
+}
+
+func TestSchemaConfigToSDL(t *testing.T) {
+	// The fixed DateTime scalar is printed once, with graphql-go's description.
+	dateTime := `"""` + "\n" + graphql.DateTime.Description() + "\n" + `"""` + "\nscalar DateTime\n\n"
+	for beta, expected := range map[bool]string{
+		false: `"""
+A customer's order.
+"""
+type Order {
+  createdAt: DateTime
+  id: ID!
+  """
+  The total in cents.
+  """
+  total: Float @deprecated(reason: "Use amount.")
+}
+
+type Query {
+  order(
+    id: ID!
+    """
+    Only return orders with this status.
+    """
+    status: Status
+  ): Order
+  since: DateTime
+}
+
+enum Status {
+  CLOSED @deprecated(reason: "Orders are never closed.")
+  OPEN
+}
+`,
+		true: `"""
+A customer's order.
+"""
+type Order {
+  createdAt: DateTime
+  id: ID!
+  """
+  The total in cents.
+  """
+  total: Float @deprecated(reason: "Use amount.")
+}
+
+"""
+A customer's order.
+"""
+type OrderBeta {
+  createdAt: DateTime
+  id: ID!
+  """
+  The total in cents.
+  """
+  total: Float @deprecated(reason: "Use amount.")
+}
+
+type Query {
+  betaOrder: OrderBeta
+  order(
+    id: ID!
+    """
+    Only return orders with this status.
+    """
+    status: Status
+  ): Order
+  since: DateTime
+}
+
+enum Status {
+  CLOSED @deprecated(reason: "Orders are never closed.")
+  HELD
+  OPEN
+}
+`,
+	} {
+		result, err := PreprocessSchemaConfigE(printSDLTestInput(), &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sdl, err := SchemaConfigToSDL(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sdl != dateTime+expected {
+			t.Errorf("beta %v: unexpected SDL:\n%v", beta, sdl)
+		}
+	}
+}
+
+func TestHashSchemaConfig(t *testing.T) {
+	hashes := map[bool]string{}
+	for i := 0; i < 20; i++ {
+		for _, beta := range []bool{false, true} {
+			var dropped []string
+			result, err := PreprocessSchemaConfigE(printSDLTestInput(), &PreprocessorConfig{
+				BetaFeaturesEnabled: beta,
+				OnDrop: func(coordinate, reason string) {
+					dropped = append(dropped, coordinate)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hash, err := HashSchemaConfig(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if previous, ok := hashes[beta]; ok && hash != previous {
+				t.Fatalf("beta %v: the hash changed from %v to %v", beta, previous, hash)
+			}
+			hashes[beta] = hash
+
+			// Elements are visited in the same order every time.
+			if expected := map[bool]string{false: "Query.betaOrder,Status.HELD"}[beta]; strings.Join(dropped, ",") != expected {
+				t.Fatalf("beta %v: unexpected drops %v", beta, dropped)
+			}
+		}
+	}
+	if hashes[false] == hashes[true] {
+		t.Error("the variants have the same hash")
+	}
+}
@@ -0,0 +1,273 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+)
+
+// PromotionReport describes the conditionals replaced by PromoteFlag.
+type PromotionReport struct {
+	Flag string
+
+	// Coordinates of the elements whose conditionals were replaced by their underlying types or
+	// values.
+	Coordinates []string
+
+	// Callsites of the replaced conditionals, where known. These are the wrappers left to delete.
+	Callsites []string
+
+	// Coordinates of elements whose conditionals read the flag along with other flags. These
+	// aren't promoted.
+	Compound []string
+}
+
+type promoter struct {
+	flag   string
+	probe  *PreprocessorConfig
+	types  map[graphql.Type]graphql.Type
+	report *PromotionReport
+}
+
+// PromoteFlag returns a copy of the input in which every conditional gated solely by the given
+// flag is replaced by its underlying type or value. Preprocessing the result should produce the
+// same schema as preprocessing the input with the flag enabled. Named conditions are resolved
+// using the built-in conditions only.
+func PromoteFlag(input graphql.SchemaConfig, flag string) (result graphql.SchemaConfig, report *PromotionReport, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to promote %v: %v", flag, r)
+		}
+	}()
+
+	p := &promoter{
+		flag:  flag,
+		probe: &PreprocessorConfig{},
+		types: map[graphql.Type]graphql.Type{},
+		report: &PromotionReport{
+			Flag: flag,
+		},
+	}
+	result = input
+	if input.Query != nil {
+		result.Query = p.promote(input.Query, input.Query.Name()).(*graphql.Object)
+	}
+	if input.Mutation != nil {
+		result.Mutation = p.promote(input.Mutation, input.Mutation.Name()).(*graphql.Object)
+	}
+	if input.Subscription != nil {
+		result.Subscription = p.promote(input.Subscription, input.Subscription.Name()).(*graphql.Object)
+	}
+	result.Types = nil
+	for _, t := range input.Types {
+		result.Types = append(result.Types, p.promote(t, namedType(unwrapConditionals(t)).Name()))
+	}
+
+	// Force the thunks so that the report is complete.
+	for _, t := range schemaTypes(result) {
+		if err := t.Error(); err != nil {
+			return result, nil, err
+		}
+	}
+
+	p.report.Coordinates = sortedUnique(p.report.Coordinates)
+	p.report.Callsites = sortedUnique(p.report.Callsites)
+	p.report.Compound = sortedUnique(p.report.Compound)
+	return result, p.report, nil
+}
+
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+	var result []string
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// classify returns whether a condition is gated solely by the flag, recording compound conditions
+// that also read it.
+func (p *promoter) classify(coordinate string, condition func(*PreprocessorConfig) bool, name string) bool {
+	flags := conditionFlags(p.probe, condition, name)
+	if len(flags) == 1 && flags[0] == p.flag {
+		return true
+	}
+	for _, flag := range flags {
+		if flag == p.flag {
+			p.report.Compound = append(p.report.Compound, coordinate)
+		}
+	}
+	return false
+}
+
+func (p *promoter) promote(t graphql.Type, coordinate string) graphql.Type {
+	switch t := t.(type) {
+	case *graphql.List:
+		return graphql.NewList(p.promote(t.OfType, coordinate))
+	case *graphql.NonNull:
+		return graphql.NewNonNull(p.promote(t.OfType, coordinate))
+	case *Conditional:
+		if p.classify(coordinate, t.conditionFor(newConditionContext(t.OfType, coordinate)), t.ConditionName) {
+			p.report.Coordinates = append(p.report.Coordinates, coordinate)
+			if t.callsite != "" {
+				p.report.Callsites = append(p.report.Callsites, t.callsite)
+			}
+			return p.promote(t.OfType, coordinate)
+		}
+		conditional := *t
+		conditional.OfType = p.promote(t.OfType, coordinate)
+		return &conditional
+	case *FallbackType:
+		fallback := *t
+		fallback.Flagged = p.promote(t.Flagged, coordinate)
+		fallback.Fallback = p.promote(t.Fallback, coordinate)
+		return &fallback
+	}
+
+	if result, ok := p.types[t]; ok {
+		return result
+	}
+	var result graphql.Type
+	switch t := t.(type) {
+	case *graphql.Object:
+		result = p.promoteObject(t)
+	case *graphql.Interface:
+		result = p.promoteInterface(t)
+	case *graphql.Union:
+		result = p.promoteUnion(t)
+	case *graphql.InputObject:
+		result = p.promoteInputObject(t)
+	case *graphql.Enum:
+		result = p.promoteEnum(t)
+	default:
+		result = t
+	}
+	p.types[t] = result
+	return result
+}
+
+func (p *promoter) promoteFields(parent string, defs graphql.FieldDefinitionMap) graphql.Fields {
+	fields := graphql.Fields{}
+	for name, def := range defs {
+		coordinate := parent + "." + name
+		f := &graphql.Field{
+			Name:              def.Name,
+			Type:              p.promote(def.Type, coordinate),
+			Resolve:           def.Resolve,
+			DeprecationReason: def.DeprecationReason,
+			Description:       def.Description,
+		}
+		if len(def.Args) > 0 {
+			f.Args = graphql.FieldConfigArgument{}
+			for _, arg := range def.Args {
+				f.Args[arg.Name()] = &graphql.ArgumentConfig{
+					Type:         p.promote(arg.Type, coordinate+"("+arg.Name()+":)"),
+					DefaultValue: arg.DefaultValue,
+					Description:  arg.PrivateDescription,
+				}
+			}
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+func (p *promoter) promoteObject(obj *graphql.Object) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: obj.Name(),
+		Interfaces: graphql.InterfacesThunk(func() []*graphql.Interface {
+			var ifaces []*graphql.Interface
+			for _, iface := range obj.Interfaces() {
+				ifaces = append(ifaces, p.promote(iface, iface.Name()).(*graphql.Interface))
+			}
+			return ifaces
+		}),
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return p.promoteFields(obj.Name(), obj.Fields())
+		}),
+		IsTypeOf:    obj.IsTypeOf,
+		Description: obj.PrivateDescription,
+	})
+}
+
+func (p *promoter) promoteResolveType(resolveType graphql.ResolveTypeFn) graphql.ResolveTypeFn {
+	if resolveType == nil {
+		return nil
+	}
+	return func(params graphql.ResolveTypeParams) *graphql.Object {
+		if obj := resolveType(params); obj != nil {
+			return p.promote(obj, obj.Name()).(*graphql.Object)
+		}
+		return nil
+	}
+}
+
+func (p *promoter) promoteInterface(iface *graphql.Interface) *graphql.Interface {
+	return graphql.NewInterface(graphql.InterfaceConfig{
+		Name: iface.Name(),
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return p.promoteFields(iface.Name(), iface.Fields())
+		}),
+		ResolveType: p.promoteResolveType(iface.ResolveType),
+		Description: iface.Description(),
+	})
+}
+
+func (p *promoter) promoteUnion(u *graphql.Union) *graphql.Union {
+	config := graphql.UnionConfig{
+		Name:        u.Name(),
+		ResolveType: p.promoteResolveType(u.ResolveType),
+		Description: u.Description(),
+	}
+	for _, obj := range u.Types() {
+		config.Types = append(config.Types, p.promote(obj, obj.Name()).(*graphql.Object))
+	}
+	return graphql.NewUnion(config)
+}
+
+func (p *promoter) promoteInputObject(obj *graphql.InputObject) *graphql.InputObject {
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: obj.Name(),
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			fields := graphql.InputObjectConfigFieldMap{}
+			for name, f := range obj.Fields() {
+				fields[name] = &graphql.InputObjectFieldConfig{
+					Type:         p.promote(f.Type, obj.Name()+"."+name),
+					DefaultValue: f.DefaultValue,
+					Description:  f.Description(),
+				}
+			}
+			return fields
+		}),
+		Description: obj.Description(),
+	})
+}
+
+func (p *promoter) promoteEnum(enum *graphql.Enum) *graphql.Enum {
+	config := graphql.EnumConfig{
+		Name:        enum.Name(),
+		Description: enum.Description(),
+		Values:      graphql.EnumValueConfigMap{},
+	}
+	promoted := false
+	for _, value := range enum.Values() {
+		if conditional, ok := value.Value.(ConditionalValue); ok && p.classify(enum.Name()+"."+value.Name, conditional.Enabled, "") {
+			p.report.Coordinates = append(p.report.Coordinates, enum.Name()+"."+value.Name)
+			config.Values[value.Name] = conditional.Underlying()
+			promoted = true
+			continue
+		}
+		config.Values[value.Name] = &graphql.EnumValueConfig{
+			Value:             value.Value,
+			Description:       value.Description,
+			DeprecationReason: value.DeprecationReason,
+		}
+	}
+	if !promoted {
+		return enum
+	}
+	return graphql.NewEnum(config)
+}
@@ -0,0 +1,79 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestPromoteFlag(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	refund := Flag("payments", graphql.NewObject(graphql.ObjectConfig{
+		Name: "Refund",
+		Fields: graphql.Fields{
+			"amount":  &graphql.Field{Type: graphql.Float},
+			"partial": &graphql.Field{Type: NewConditional(graphql.Boolean, "Partial", AllOf(Feature("payments").Enabled, Feature("beta").Enabled))},
+		},
+	}))
+	currency := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Currency",
+		Values: graphql.EnumValueConfigMap{
+			"USD": &graphql.EnumValueConfig{Value: "usd"},
+			"EUR": FlagEnum("payments", &graphql.EnumValueConfig{Value: "eur"}),
+		},
+	})
+	input := testInput(graphql.Fields{
+		"refund":   &graphql.Field{Type: refund},
+		"currency": &graphql.Field{Type: currency},
+		"beta":     BetaField(&graphql.Field{Type: graphql.String}),
+	})
+
+	promoted, report, err := PromoteFlag(input, "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &PromotionReport{
+		Flag:        "payments",
+		Coordinates: []string{"Currency.EUR", "Query.refund"},
+		Callsites:   []string{fmt.Sprintf("%v:%v", file, line+1)},
+		Compound:    []string{"Refund.partial"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	for _, beta := range []bool{false, true} {
+		withFlag, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{
+			BetaFeaturesEnabled: beta,
+			Flags:               map[string]bool{"payments": true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		withPromotion, err := PreprocessSchemaConfigE(promoted, &PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := SchemaConfigToSDL(withFlag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := SchemaConfigToSDL(withPromotion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The compound condition still reads the flag, which is off without the promotion.
+		if beta {
+			if a == b {
+				t.Error("the compound conditional was promoted")
+			}
+			continue
+		}
+		if a != b {
+			t.Errorf("the promoted schema differs from the schema with the flag enabled:\n%v\n%v", a, b)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package graphqlapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	provenanceStart = "--- graphql-go-preprocessor provenance ---"
+	provenanceEnd   = "--- end of provenance ---"
+	modulePath      = "github.com/theaaf/graphql-go-preprocessor"
+)
+
+// ErrNoProvenance is returned by ReadProvenance and ReadProvenanceSDL if the schema doesn't have
+// a provenance block.
+var ErrNoProvenance = errors.New("the schema has no provenance block")
+
+// Provenance describes how a schema variant was generated. It's embedded in the query type's
+// description if PreprocessorConfig.EmbedProvenance is set.
+type Provenance struct {
+	// The version of this package, or "(devel)" if it's unknown.
+	Version string `json:"version"`
+
+	// Only present if PreprocessorConfig.ProvenanceTimestamp is set.
+	GeneratedAt *time.Time `json:"generatedAt,omitempty"`
+
+	// A hash of the flags and environment used to preprocess the schema.
+	ConfigFingerprint string `json:"configFingerprint"`
+
+	// The number of types, fields, arguments, input fields, and enum values that were removed.
+	GatedElements int `json:"gatedElements"`
+}
+
+func moduleVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath {
+			return info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				return dep.Version
+			}
+		}
+	}
+	return "(devel)"
+}
+
+func configFingerprint(config *PreprocessorConfig) string {
+	flags := make([]string, 0, len(config.Flags))
+	for flag := range config.Flags {
+		flags = append(flags, flag)
+	}
+	sort.Strings(flags)
+	h := sha256.New()
+	fmt.Fprintf(h, "environment\t%v\n", config.Environment)
+	fmt.Fprintf(h, "beta\t%v\n", config.BetaFeaturesEnabled)
+	// Other stages are only included when enabled so that existing fingerprints are unchanged.
+	for _, stage := range []string{"alpha", "experimental", "internal"} {
+		if *stages[stage](config) {
+			fmt.Fprintf(h, "%v\ttrue\n", stage)
+		}
+	}
+	if config.APIVersion != 0 {
+		fmt.Fprintf(h, "version\t%v\n", config.APIVersion)
+	}
+	if len(config.Roles) > 0 {
+		roles := append([]string(nil), config.Roles...)
+		sort.Strings(roles)
+		fmt.Fprintf(h, "roles\t%v\n", strings.Join(roles, ","))
+	}
+	for _, flag := range flags {
+		fmt.Fprintf(h, "flag:%v\t%v\n", flag, config.Flags[flag])
+	}
+	for _, stage := range []string{"alpha", "beta", "experimental", "internal"} {
+		if suffix, ok := config.StageSuffixes[stage]; ok {
+			fmt.Fprintf(h, "suffix:%v\t%v\n", stage, suffix)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newProvenance(input, result graphql.SchemaConfig, config *PreprocessorConfig) *Provenance {
+	before, after := schemaCoordinates(input), schemaCoordinates(result)
+	gated := 0
+	for coordinate := range before {
+		if _, ok := after[coordinate]; !ok {
+			gated++
+		}
+	}
+	provenance := &Provenance{
+		Version:           moduleVersion(),
+		ConfigFingerprint: configFingerprint(config),
+		GatedElements:     gated,
+	}
+	if config.ProvenanceTimestamp != nil {
+		t := config.ProvenanceTimestamp().UTC()
+		provenance.GeneratedAt = &t
+	}
+	return provenance
+}
+
+// embedProvenance replaces any provenance block in the description with a new one.
+func embedProvenance(description string, provenance *Provenance) string {
+	buf, err := json.Marshal(provenance)
+	if err != nil {
+		panic(err)
+	}
+	if start := strings.Index(description, provenanceStart); start >= 0 {
+		description = strings.TrimRight(description[:start], "\n")
+	}
+	if description != "" {
+		description += "\n\n"
+	}
+	return description + provenanceStart + "\n" + string(buf) + "\n" + provenanceEnd
+}
+
+func extractProvenance(description string) (*Provenance, error) {
+	start := strings.Index(description, provenanceStart)
+	if start < 0 {
+		return nil, ErrNoProvenance
+	}
+	block := description[start+len(provenanceStart):]
+	end := strings.Index(block, provenanceEnd)
+	if end < 0 {
+		return nil, fmt.Errorf("the provenance block is unterminated")
+	}
+	var provenance Provenance
+	if err := json.Unmarshal([]byte(strings.TrimSpace(block[:end])), &provenance); err != nil {
+		return nil, fmt.Errorf("the provenance block is malformed: %v", err)
+	}
+	return &provenance, nil
+}
+
+// ReadProvenance extracts the provenance embedded in a built schema's query type description.
+func ReadProvenance(schema graphql.Schema) (*Provenance, error) {
+	if schema.QueryType() == nil {
+		return nil, ErrNoProvenance
+	}
+	// graphql.Object's Description method always returns an empty string.
+	return extractProvenance(schema.QueryType().PrivateDescription)
+}
+
+// ReadProvenanceSDL extracts the provenance embedded in a type description of the given SDL.
+func ReadProvenanceSDL(sdl []byte) (*Provenance, error) {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: sdl,
+			Name: "SDL",
+		}),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, definition := range document.Definitions {
+		if definition, ok := definition.(ast.DescribableNode); ok && definition.GetDescription() != nil {
+			if provenance, err := extractProvenance(definition.GetDescription().Value); err != ErrNoProvenance {
+				return provenance, err
+			}
+		}
+	}
+	return nil, ErrNoProvenance
+}
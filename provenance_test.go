@@ -0,0 +1,77 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestProvenanceRoundTrip(t *testing.T) {
+	input := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "Query",
+			Description: "The root query type.",
+			Fields: graphql.Fields{
+				"id":     &graphql.Field{Type: graphql.ID},
+				"secret": BetaField(&graphql.Field{Type: graphql.String}),
+			},
+		}),
+	}
+	generatedAt := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	config := &PreprocessorConfig{
+		EmbedProvenance: true,
+		ProvenanceTimestamp: func() time.Time {
+			return generatedAt
+		},
+	}
+
+	var printed []string
+	for i := 0; i < 2; i++ {
+		result, err := PreprocessSchemaConfigE(input, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sdl, err := SchemaConfigToSDL(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		printed = append(printed, sdl)
+
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fromSchema, err := ReadProvenance(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Query.secret is gated, and String is no longer used.
+		if fromSchema.GatedElements != 2 || fromSchema.GeneratedAt == nil || !fromSchema.GeneratedAt.Equal(generatedAt) || fromSchema.ConfigFingerprint != configFingerprint(config) {
+			t.Errorf("unexpected provenance %+v", fromSchema)
+		}
+		fromSDL, err := ReadProvenanceSDL([]byte(sdl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(fromSDL, fromSchema) {
+			t.Errorf("the SDL's provenance %+v differs from the schema's %+v", fromSDL, fromSchema)
+		}
+	}
+	if printed[0] != printed[1] {
+		t.Errorf("the provenance isn't deterministic:\n%v\n%v", printed[0], printed[1])
+	}
+
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadProvenance(schema); err != ErrNoProvenance {
+		t.Errorf("expected ErrNoProvenance, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// preprocessReachableTypes preprocesses the types that are reachable from the preprocessed roots.
+// Objects that implement reachable interfaces are considered reachable, since they may only be
+// referenced via ResolveType. The remaining types aren't preprocessed at all.
+func (p *preprocessor) preprocessReachableTypes(types []graphql.Type, roots graphql.SchemaConfig) []graphql.Type {
+	reachable := map[string]bool{}
+	addReachable := func(config graphql.SchemaConfig) {
+		for _, t := range schemaTypes(config) {
+			reachable[t.Name()] = true
+		}
+	}
+	addReachable(roots)
+
+	var result []graphql.Type
+	for pending := types; ; {
+		var unreachable []graphql.Type
+		for _, t := range pending {
+			underlying := namedType(unwrapConditionals(t))
+			if !reachable[underlying.Name()] && !implementsReachable(underlying, reachable) {
+				unreachable = append(unreachable, t)
+				continue
+			}
+			if newType, ok := p.preprocessType(t); ok {
+				result = append(result, newType)
+				addReachable(graphql.SchemaConfig{
+					Types: []graphql.Type{newType},
+				})
+			} else {
+				p.removed("type", underlying.Name(), "")
+			}
+		}
+		if len(unreachable) == len(pending) {
+			return result
+		}
+		pending = unreachable
+	}
+}
+
+// implementsReachable reports whether t is an object implementing a reachable interface. Reading
+// its interfaces also evaluates its fields thunk, since graphql-go's object thunks share state.
+func implementsReachable(t graphql.Type, reachable map[string]bool) bool {
+	if obj, ok := t.(*graphql.Object); ok {
+		evaluateThunks(obj)
+		for _, iface := range obj.Interfaces() {
+			if reachable[iface.Name()] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// omittedRootTypes returns the names of the input's types that are only reachable from the omitted
+// roots. As with PruneUnreachable, objects implementing reachable interfaces are considered
+// reachable, as are types in the input's Types that the omitted roots don't reach.
+func omittedRootTypes(input graphql.SchemaConfig, omitted []*graphql.Object) map[string]bool {
+	if len(omitted) == 0 {
+		return nil
+	}
+	fromOmitted := map[string]bool{}
+	for _, obj := range omitted {
+		for _, t := range schemaTypes(graphql.SchemaConfig{Query: obj}) {
+			fromOmitted[t.Name()] = true
+		}
+	}
+
+	isOmitted := func(obj *graphql.Object) bool {
+		for _, o := range omitted {
+			if o == obj {
+				return true
+			}
+		}
+		return false
+	}
+	kept := graphql.SchemaConfig{Query: input.Query}
+	if !isOmitted(input.Mutation) {
+		kept.Mutation = input.Mutation
+	}
+	if !isOmitted(input.Subscription) {
+		kept.Subscription = input.Subscription
+	}
+	for _, d := range input.Directives {
+		for _, arg := range d.Args {
+			kept.Types = append(kept.Types, arg.Type)
+		}
+	}
+	var pending []graphql.Type
+	for _, t := range input.Types {
+		if fromOmitted[namedType(unwrapConditionals(t)).Name()] {
+			pending = append(pending, t)
+		} else {
+			kept.Types = append(kept.Types, t)
+		}
+	}
+	reachable := map[string]bool{}
+	for _, t := range schemaTypes(kept) {
+		reachable[t.Name()] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, t := range pending {
+			underlying := namedType(unwrapConditionals(t))
+			if !reachable[underlying.Name()] && implementsReachable(underlying, reachable) {
+				for _, t := range schemaTypes(graphql.SchemaConfig{Types: []graphql.Type{t}}) {
+					reachable[t.Name()] = true
+				}
+				changed = true
+			}
+		}
+	}
+
+	exclusive := map[string]bool{}
+	for name := range fromOmitted {
+		if !reachable[name] {
+			exclusive[name] = true
+		}
+	}
+	return exclusive
+}
@@ -0,0 +1,82 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// Rebuilder rebuilds the schema for an input when its config changes, e.g. because flags were
+// toggled at runtime. It publishes schemas via an ActiveSchema, but rebuilds reuse the
+// condition-independent types preprocessed by earlier ones, as PreprocessSchemaConfigs does, and
+// are subject to the same assumptions about how configs differ.
+//
+// Requests that are executing when a schema is replaced keep using the old one. Resolvers of
+// shared types identify the variant being executed by its query type, which is tracked for the
+// current and previous schemas. Requests that outlive two swaps may see the flags of a newer
+// variant in their contexts unless the flags were added with WithFlags.
+type Rebuilder struct {
+	active  *ActiveSchema
+	sharing *typeSharing
+
+	// The query types of the current and previous schemas. They're only modified while the active
+	// schema's mutex is held.
+	query, previous *graphql.Object
+}
+
+func NewRebuilder(input graphql.SchemaConfig) *Rebuilder {
+	checkInputLimits(input, nil)
+	r := &Rebuilder{
+		active: &ActiveSchema{
+			input: input,
+		},
+		sharing: newTypeSharing(),
+	}
+	r.active.newSchema = r.newSchema
+	r.active.swapped = r.swapped
+	return r
+}
+
+func (r *Rebuilder) newSchema(config *PreprocessorConfig) (graphql.SchemaConfig, graphql.Schema, error) {
+	input := r.active.input
+	result, err := r.sharing.preprocess(input, config, shareableTypes(input, []*PreprocessorConfig{config}))
+	if err != nil {
+		return graphql.SchemaConfig{}, graphql.Schema{}, err
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		r.sharing.forget(result.Query)
+		return graphql.SchemaConfig{}, graphql.Schema{}, err
+	}
+	return result, schema, nil
+}
+
+func (r *Rebuilder) swapped(_, variant *activeVariant) {
+	if r.previous != nil {
+		r.sharing.forget(r.previous)
+	}
+	r.query, r.previous = variant.schema.QueryType(), r.query
+}
+
+// Swap builds the schema for the config and publishes it. If the build fails, the current schema
+// is left as is.
+func (r *Rebuilder) Swap(config *PreprocessorConfig) (graphql.Schema, error) {
+	variant, err := r.active.swap(config)
+	if err != nil {
+		return graphql.Schema{}, err
+	}
+	return *variant.schema, nil
+}
+
+// Current returns the most recently published schema, or the zero schema if Swap hasn't succeeded
+// yet. It's safe to call concurrently with Swap.
+func (r *Rebuilder) Current() graphql.Schema {
+	if variant, ok := r.active.current.Load().(*activeVariant); ok {
+		return *variant.schema
+	}
+	return graphql.Schema{}
+}
+
+// Execute executes the request against the current schema using the config it was built with, as
+// ActiveSchema.Execute does. Swap must have succeeded first.
+func (r *Rebuilder) Execute(params graphql.Params) *graphql.Result {
+	return r.active.Execute(params)
+}
@@ -0,0 +1,98 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func rebuildTestInput() graphql.SchemaConfig {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.ID},
+			"secret": &graphql.Field{Type: Flag("secrets", graphql.String)},
+		},
+	})
+	return testInput(graphql.Fields{
+		"widget": &graphql.Field{
+			Type: widget,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1", "secret": "s"}, nil
+			},
+		},
+	})
+}
+
+func TestRebuilderSwap(t *testing.T) {
+	r := NewRebuilder(rebuildTestInput())
+	old, err := r.Swap(&PreprocessorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := r.Current()
+	if current.QueryType() != old.QueryType() {
+		t.Fatal("the swapped schema wasn't published")
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			response := graphql.Do(graphql.Params{Schema: r.Current(), RequestString: "{ widget { id } }"})
+			if len(response.Errors) > 0 {
+				t.Error(response.Errors)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if _, err := r.Swap(&PreprocessorConfig{Flags: map[string]bool{"secrets": i%2 == 0}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	updated, err := r.Swap(&PreprocessorConfig{Flags: map[string]bool{"secrets": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal, differences := SchemaConfigsEqual(graphql.SchemaConfig{Query: old.QueryType()}, graphql.SchemaConfig{Query: updated.QueryType()})
+	// String is only referenced by the gated field.
+	if equal || !reflect.DeepEqual(differences, []string{"String: only in b", "Widget.secret: only in b"}) {
+		t.Errorf("unexpected differences %q", differences)
+	}
+
+	// The old schema is unaffected by the swap.
+	response := graphql.Do(graphql.Params{Schema: old, RequestString: "{ widget { id secret } }"})
+	if len(response.Errors) != 1 {
+		t.Errorf("unexpected response from the old schema %v", response)
+	}
+	response = graphql.Do(graphql.Params{Schema: r.Current(), RequestString: "{ widget { id secret } }"})
+	if expected := map[string]interface{}{"widget": map[string]interface{}{"id": "1", "secret": "s"}}; len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("unexpected response from the new schema %v", response)
+	}
+
+	response = r.Execute(graphql.Params{RequestString: "{ widget { secret } }"})
+	if expected := map[string]interface{}{"widget": map[string]interface{}{"secret": "s"}}; len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("unexpected response from Execute %v", response)
+	}
+
+	// A failed build leaves the current schema in place.
+	if _, err := r.Swap(&PreprocessorConfig{StageSuffixes: map[string]string{"beta": "β"}}); err == nil {
+		t.Error("expected an error")
+	}
+	if current := r.Current(); current.QueryType() != updated.QueryType() {
+		t.Error("a failed swap replaced the current schema")
+	}
+}
@@ -0,0 +1,169 @@
+package graphqlapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// RegistryFormatVersion is the version written to new registry entries.
+const RegistryFormatVersion = 1
+
+// RegistryEntry records a preprocessed schema variant as of a release. Entries are stored as JSON
+// and unknown fields are ignored when loading, so newer entries can still be read.
+type RegistryEntry struct {
+	FormatVersion int       `json:"formatVersion"`
+	Release       string    `json:"release"`
+	ReleasedAt    time.Time `json:"releasedAt"`
+	Variant       string    `json:"variant"`
+	Fingerprint   string    `json:"fingerprint"`
+
+	// Elements maps the coordinate of each type, field, argument, input field, and enum value to
+	// its kind or type.
+	Elements map[string]string `json:"elements"`
+
+	SDL string `json:"sdl,omitempty"`
+}
+
+// NewRegistryEntry creates an entry for the given preprocessed schema config.
+func NewRegistryEntry(release string, releasedAt time.Time, variant string, config graphql.SchemaConfig) *RegistryEntry {
+	elements := schemaCoordinates(config)
+	return &RegistryEntry{
+		FormatVersion: RegistryFormatVersion,
+		Release:       release,
+		ReleasedAt:    releasedAt,
+		Variant:       variant,
+		Fingerprint:   fingerprintElements(elements),
+		Elements:      elements,
+	}
+}
+
+func fingerprintElements(elements map[string]string) string {
+	coordinates := make([]string, 0, len(elements))
+	for coordinate := range elements {
+		coordinates = append(coordinates, coordinate)
+	}
+	sort.Strings(coordinates)
+	h := sha256.New()
+	for _, coordinate := range coordinates {
+		fmt.Fprintf(h, "%v\t%v\n", coordinate, elements[coordinate])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func registryEntryFilename(release, variant string) string {
+	return release + "-" + variant + ".json"
+}
+
+// WriteRegistryEntry writes the entry to the directory, one file per release and variant.
+func WriteRegistryEntry(dir string, entry *RegistryEntry) error {
+	buf, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, registryEntryFilename(entry.Release, entry.Variant)), append(buf, '\n'), 0644)
+}
+
+func LoadRegistryEntry(path string) (*RegistryEntry, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry RegistryEntry
+	if err := json.Unmarshal(buf, &entry); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	if entry.FormatVersion < 1 {
+		return nil, fmt.Errorf("%v: missing or invalid format version", path)
+	}
+	return &entry, nil
+}
+
+// History answers questions about how a variant's schema changed across releases.
+type History struct {
+	entries map[string][]*RegistryEntry
+}
+
+// LoadHistory loads every registry entry in the directory.
+func LoadHistory(dir string) (*History, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	h := &History{
+		entries: map[string][]*RegistryEntry{},
+	}
+	for _, path := range paths {
+		entry, err := LoadRegistryEntry(path)
+		if err != nil {
+			return nil, err
+		}
+		h.entries[entry.Variant] = append(h.entries[entry.Variant], entry)
+	}
+	for _, entries := range h.entries {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].ReleasedAt.Before(entries[j].ReleasedAt)
+		})
+	}
+	return h, nil
+}
+
+// FirstAppeared returns the earliest release in which the coordinate was present in the variant.
+func (h *History) FirstAppeared(variant, coordinate string) (string, bool) {
+	for _, entry := range h.entries[variant] {
+		if _, ok := entry.Elements[coordinate]; ok {
+			return entry.Release, true
+		}
+	}
+	return "", false
+}
+
+func (h *History) entry(variant, release string) (*RegistryEntry, error) {
+	for _, entry := range h.entries[variant] {
+		if entry.Release == release {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("no entry for release %v of variant %v", release, variant)
+}
+
+// ChangedBetween returns the sorted coordinates that were added, removed, or changed type between
+// two releases of the variant.
+func (h *History) ChangedBetween(variant, from, to string) (added, removed, changed []string, err error) {
+	a, err := h.entry(variant, from)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	b, err := h.entry(variant, to)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	added, removed, changed = diffElements(a.Elements, b.Elements)
+	return added, removed, changed, nil
+}
+
+func diffElements(a, b map[string]string) (added, removed, changed []string) {
+	for coordinate, t := range b {
+		if old, ok := a[coordinate]; !ok {
+			added = append(added, coordinate)
+		} else if old != t {
+			changed = append(changed, coordinate)
+		}
+	}
+	for coordinate := range a {
+		if _, ok := b[coordinate]; !ok {
+			removed = append(removed, coordinate)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
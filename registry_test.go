@@ -0,0 +1,110 @@
+package graphqlapi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// registryTestInput returns the schema as of the given release (1 to 3). Widget.name is added in
+// release 2 behind the beta flag, and Widget.weight changes type in release 3.
+func registryTestInput(release int) graphql.SchemaConfig {
+	fields := graphql.Fields{
+		"id":     &graphql.Field{Type: graphql.ID},
+		"weight": &graphql.Field{Type: graphql.Int},
+	}
+	if release >= 2 {
+		fields["name"] = BetaField(&graphql.Field{Type: graphql.String})
+	}
+	if release >= 3 {
+		fields["weight"] = &graphql.Field{Type: graphql.Float}
+	}
+	return testInput(graphql.Fields{
+		"widget": &graphql.Field{
+			Type: graphql.NewObject(graphql.ObjectConfig{
+				Name:   "Widget",
+				Fields: fields,
+			}),
+		},
+	})
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	releasedAt := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	for release, name := range []string{"v1", "v2", "v3"} {
+		for variant, config := range map[string]*PreprocessorConfig{
+			"public":   {},
+			"internal": {BetaFeaturesEnabled: true},
+		} {
+			preprocessed, err := PreprocessSchemaConfigE(registryTestInput(release+1), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := NewRegistryEntry(name, releasedAt.AddDate(0, release, 0), variant, preprocessed)
+			if err := WriteRegistryEntry(dir, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	history, err := LoadHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release, ok := history.FirstAppeared("internal", "Widget.name"); !ok || release != "v2" {
+		t.Errorf("Widget.name first appeared internally in %q", release)
+	}
+	if release, ok := history.FirstAppeared("public", "Widget.name"); ok {
+		t.Errorf("Widget.name first appeared publicly in %q", release)
+	}
+	if release, ok := history.FirstAppeared("public", "Widget.id"); !ok || release != "v1" {
+		t.Errorf("Widget.id first appeared publicly in %q", release)
+	}
+
+	added, removed, changed, err := history.ChangedBetween("internal", "v1", "v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(added, []string{"Float", "String", "Widget.name"}) || !reflect.DeepEqual(removed, []string{"Int"}) || !reflect.DeepEqual(changed, []string{"Widget.weight"}) {
+		t.Errorf("unexpected changes: added %v, removed %v, changed %v", added, removed, changed)
+	}
+	if _, _, _, err := history.ChangedBetween("public", "v1", "v4"); err == nil {
+		t.Error("expected an error for a missing release")
+	}
+}
+
+func TestLoadRegistryEntryCompatibility(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newer := filepath.Join(dir, "newer.json")
+	if err := ioutil.WriteFile(newer, []byte(`{"formatVersion": 2, "release": "v4", "owners": ["api"], "elements": {"Query": "object"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := LoadRegistryEntry(newer); err != nil || entry.Release != "v4" || entry.Elements["Query"] != "object" {
+		t.Errorf("unexpected entry %+v, %v", entry, err)
+	}
+
+	unversioned := filepath.Join(dir, "unversioned.json")
+	if err := ioutil.WriteFile(unversioned, []byte(`{"release": "v0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRegistryEntry(unversioned); err == nil || !strings.Contains(err.Error(), "format version") {
+		t.Errorf("expected a format version error, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package graphqlapi
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Removal describes an element removed by preprocessing.
+type Removal struct {
+	// One of "type", "field", "argument", "input field", "enum value", or "interface". Interface
+	// removals have coordinates of the form "Object implements Interface".
+	Kind string
+
+	Coordinate string
+
+	// The coordinate of the element's parent, or "" for types.
+	Parent string
+
+	// What removed the element, e.g. "conditional WidgetBeta" or "policy internal". Types that are
+	// removed because nothing references them anymore have the cause "unreachable".
+	Cause string
+
+	// The flags read by the condition that removed the element.
+	Flags []string
+}
+
+// Substitution describes a type that was replaced by preprocessing.
+type Substitution struct {
+	Original    string
+	Replacement string
+}
+
+// Deprecation describes an element that was deprecated by preprocessing instead of being
+// removed. See DeprecateWhenDisabled.
+type Deprecation struct {
+	// One of "field", "argument", "input field", or "enum value".
+	Kind string
+
+	Coordinate string
+	Parent     string
+	Reason     string
+
+	// What deprecated the element, e.g. "conditional Widget".
+	Cause string
+
+	// The flags read by the condition that deprecated the element.
+	Flags []string
+}
+
+// Report lists the removals, deprecations, and substitutions made by a Preprocessor.
+type Report struct {
+	Removals      []Removal
+	Deprecations  []Deprecation
+	Substitutions []Substitution
+}
+
+// Preprocessor preprocesses schema configs like PreprocessSchemaConfig, and reports what was
+// removed or substituted. It can also preprocess individual types, in which case results are
+// cached across calls. It's safe for concurrent use, subject to the same conditions as
+// PreprocessSchemaConfig. The config must not be modified after the Preprocessor is first used.
+type Preprocessor struct {
+	Config *PreprocessorConfig
+
+	mutex       sync.Mutex
+	report      *Report
+	incremental *preprocessor
+}
+
+func NewPreprocessor(config *PreprocessorConfig) *Preprocessor {
+	return &Preprocessor{
+		Config: config,
+	}
+}
+
+// Preprocess preprocesses the input, replacing the report. It doesn't use or affect the cache used
+// by Type, Object and Field. Unlike PreprocessSchemaConfig, the
+// result's thunks are evaluated before it returns so that the report is complete.
+func (p *Preprocessor) Preprocess(input graphql.SchemaConfig) graphql.SchemaConfig {
+	report := &Report{}
+	result := preprocessSchemaConfig(input, p.Config, report)
+	after := schemaCoordinates(result)
+
+	// Types in input.Types may be removed by a conditional but still be reachable elsewhere.
+	removals := report.Removals[:0]
+	removed := map[string]bool{}
+	for _, removal := range report.Removals {
+		if _, ok := after[removal.Coordinate]; !ok {
+			removals = append(removals, removal)
+			removed[removal.Coordinate] = true
+		}
+	}
+	report.Removals = removals
+	for _, t := range schemaTypes(input) {
+		if _, ok := after[t.Name()]; !ok && !removed[t.Name()] {
+			report.Removals = append(report.Removals, Removal{
+				Kind:       "type",
+				Coordinate: t.Name(),
+				Cause:      "unreachable",
+			})
+		}
+	}
+
+	sort.Slice(report.Removals, func(i, j int) bool {
+		return report.Removals[i].Coordinate < report.Removals[j].Coordinate
+	})
+	sort.Slice(report.Deprecations, func(i, j int) bool {
+		return report.Deprecations[i].Coordinate < report.Deprecations[j].Coordinate
+	})
+	p.mutex.Lock()
+	p.report = report
+	p.mutex.Unlock()
+	return result
+}
+
+// Report returns the report of the most recently completed call to Preprocess, or nil if there
+// hasn't been one.
+func (p *Preprocessor) Report() *Report {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.report
+}
+
+// setCause records the cause of a removal that's about to be reported.
+func (p *preprocessor) setCause(cause string, condition func(*PreprocessorConfig) bool, name string) {
+	if p.report == nil && p.Config.OnDrop == nil {
+		return
+	}
+	p.cause = &Removal{
+		Cause: cause,
+		Flags: conditionFlags(p.Config, condition, name),
+	}
+}
+
+func (p *preprocessor) removed(kind, coordinate, parent string) {
+	if p.report == nil && p.Config.OnDrop == nil {
+		return
+	}
+	removal := Removal{
+		Kind:       kind,
+		Coordinate: coordinate,
+		Parent:     parent,
+	}
+	if p.cause != nil {
+		removal.Cause, removal.Flags = p.cause.Cause, p.cause.Flags
+	}
+	if p.report != nil {
+		p.report.Removals = append(p.report.Removals, removal)
+	}
+	if p.Config.OnDrop != nil {
+		p.Config.OnDrop(coordinate, removal.Cause)
+	}
+}
+
+func (p *preprocessor) deprecated(kind, coordinate, parent string, deprecation *Deprecation) {
+	if p.report == nil {
+		return
+	}
+	d := *deprecation
+	d.Kind, d.Coordinate, d.Parent = kind, coordinate, parent
+	p.report.Deprecations = append(p.report.Deprecations, d)
+}
+
+func (p *preprocessor) kept(coordinate string) {
+	if p.Config.OnKeep != nil {
+		p.Config.OnKeep(coordinate)
+	}
+}
+
+func (p *preprocessor) substituted(original, replacement string) {
+	if p.report == nil {
+		return
+	}
+	for _, s := range p.report.Substitutions {
+		if s.Original == original && s.Replacement == replacement {
+			return
+		}
+	}
+	p.report.Substitutions = append(p.report.Substitutions, Substitution{
+		Original:    original,
+		Replacement: replacement,
+	})
+}
@@ -0,0 +1,134 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func reportTestInput() graphql.SchemaConfig {
+	gadget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Gadget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Filter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"fuzzy": BetaInputField(&graphql.InputObjectFieldConfig{Type: graphql.Boolean}),
+		},
+	})
+	color := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Color",
+		Values: graphql.EnumValueConfigMap{
+			"RED":  &graphql.EnumValueConfig{Value: "red"},
+			"BLUE": BetaEnum(&graphql.EnumValueConfig{Value: "blue"}),
+		},
+	})
+	return testInput(graphql.Fields{
+		"color":     &graphql.Field{Type: color},
+		"createdAt": &graphql.Field{Type: graphql.DateTime},
+		"gadget":    &graphql.Field{Type: Beta(gadget)},
+		"price":     BetaField(&graphql.Field{Type: graphql.Float}),
+		"search": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: filter},
+				"limit":  BetaArg(&graphql.ArgumentConfig{Type: graphql.Int}),
+			},
+		},
+	})
+}
+
+func TestReport(t *testing.T) {
+	p := NewPreprocessor(&PreprocessorConfig{})
+	if p.Report() != nil {
+		t.Error("the report exists before preprocessing")
+	}
+	p.Preprocess(reportTestInput())
+	report := p.Report()
+
+	betaRemovals := map[string]Removal{}
+	for _, removal := range report.Removals {
+		if reflect.DeepEqual(removal.Flags, []string{"beta"}) {
+			betaRemovals[removal.Coordinate] = removal
+		}
+	}
+	expected := map[string]Removal{
+		"Color.BLUE":           {Kind: "enum value", Coordinate: "Color.BLUE", Parent: "Color", Cause: "conditional enum value", Flags: []string{"beta"}},
+		"Filter.fuzzy":         {Kind: "input field", Coordinate: "Filter.fuzzy", Parent: "Filter", Cause: "conditional BooleanBeta", Flags: []string{"beta"}},
+		"Query.gadget":         {Kind: "field", Coordinate: "Query.gadget", Parent: "Query", Cause: "conditional GadgetBeta", Flags: []string{"beta"}},
+		"Query.price":          {Kind: "field", Coordinate: "Query.price", Parent: "Query", Cause: "conditional FloatBeta", Flags: []string{"beta"}},
+		"Query.search(limit:)": {Kind: "argument", Coordinate: "Query.search(limit:)", Parent: "Query.search", Cause: "conditional IntBeta", Flags: []string{"beta"}},
+	}
+	if !reflect.DeepEqual(betaRemovals, expected) {
+		t.Errorf("unexpected beta removals %v", betaRemovals)
+	}
+	unreachable := map[string]bool{}
+	for _, removal := range report.Removals {
+		if removal.Cause == "unreachable" {
+			unreachable[removal.Coordinate] = true
+		}
+	}
+	if !unreachable["Gadget"] || unreachable["Color"] || unreachable["Filter"] {
+		t.Errorf("unexpected unreachable types %v", unreachable)
+	}
+	if substitutions := []Substitution{{"DateTime", "a DateTime scalar that parses string literals"}}; !reflect.DeepEqual(report.Substitutions, substitutions) {
+		t.Errorf("unexpected substitutions %v", report.Substitutions)
+	}
+
+	// Nothing is removed or substituted with beta enabled and DateTime kept.
+	p = NewPreprocessor(&PreprocessorConfig{BetaFeaturesEnabled: true, KeepDateTime: true})
+	p.Preprocess(reportTestInput())
+	if report := p.Report(); len(report.Removals) > 0 || len(report.Substitutions) > 0 {
+		t.Errorf("unexpected report with beta enabled %v", report)
+	}
+}
+
+func TestOnDropAndOnKeep(t *testing.T) {
+	var events []string
+	config := &PreprocessorConfig{
+		OnDrop: func(coordinate, reason string) {
+			events = append(events, "drop "+coordinate+": "+reason)
+		},
+		OnKeep: func(coordinate string) {
+			events = append(events, "keep "+coordinate)
+		},
+	}
+	// Fields are decided lazily, so the schema is built before checking the events.
+	result, err := PreprocessSchemaConfigE(reportTestInput(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graphql.NewSchema(result); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, event := range events {
+		seen[event] = true
+	}
+	for _, event := range []string{
+		"keep Query.color",
+		"keep Query.search",
+		"keep Query.search(filter:)",
+		"keep Filter.name",
+		"keep Color.RED",
+		"drop Query.price: conditional FloatBeta",
+		"drop Query.gadget: conditional GadgetBeta",
+		"drop Query.search(limit:): conditional IntBeta",
+		"drop Filter.fuzzy: conditional BooleanBeta",
+		"drop Color.BLUE: conditional enum value",
+	} {
+		if !seen[event] {
+			t.Errorf("missing event %q in %q", event, events)
+		}
+	}
+
+	// The callbacks are optional.
+	if _, err := PreprocessSchemaConfigE(reportTestInput(), &PreprocessorConfig{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,46 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// HasRole returns whether the role is in the config's Roles. Conditions should check roles via
+// HasRole so that ReadFlags can tell which roles a schema depends on. Reads are reported as
+// "@role:<role>".
+func (c *PreprocessorConfig) HasRole(role string) bool {
+	c.read(roleDependencyPrefix + role)
+	return c.hasRole(role)
+}
+
+func (c *PreprocessorConfig) hasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// roleDependencyPrefix prefixes the names under which reads of roles are tracked alongside flags.
+const roleDependencyPrefix = "@role:"
+
+func requireRole(role string) func(*PreprocessorConfig) bool {
+	return func(cfg *PreprocessorConfig) bool {
+		return cfg.HasRole(role)
+	}
+}
+
+// RequireRole returns a conditional that's only present if the config has the role.
+func RequireRole(role string, t graphql.Type) *Conditional {
+	return &Conditional{
+		OfType:    t,
+		Suffix:    "_role_" + role,
+		Condition: requireRole(role),
+		callsite:  callsite(1),
+	}
+}
+
+// RequireRoleEnum returns an enum value that's only present if the config has the role.
+func RequireRoleEnum(role string, value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return NewConditionalEnumValue(value, requireRole(role))
+}
@@ -0,0 +1,105 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func roleTestInput() graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"id":    &graphql.Field{Type: graphql.ID},
+		"audit": &graphql.Field{Type: RequireRole("admin", graphql.String)},
+	})
+}
+
+func TestRequireRole(t *testing.T) {
+	for _, roles := range [][]string{nil, {"admin"}} {
+		result := PreprocessSchemaConfig(roleTestInput(), &PreprocessorConfig{Roles: roles})
+		if _, ok := result.Query.Fields()["audit"]; ok != (len(roles) > 0) {
+			t.Errorf("roles %v: audit present: %v", roles, ok)
+		}
+	}
+}
+
+func TestReadFlagsIncludesRoles(t *testing.T) {
+	flags := ReadFlags(roleTestInput(), &PreprocessorConfig{})
+	if !reflect.DeepEqual(flags, []string{"@role:admin"}) {
+		t.Errorf("unexpected flags %v", flags)
+	}
+}
+
+func TestRequireRoleRemovesReferences(t *testing.T) {
+	report := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AuditReport",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	adminReport := RequireRole("admin", report)
+	// Gated by both the role and the beta flag.
+	betaAdminReport := Beta(RequireRole("admin", report))
+	input := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"id":      &graphql.Field{Type: graphql.ID},
+				"reports": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(adminReport)))},
+				"search": &graphql.Field{
+					Type: graphql.String,
+					Args: graphql.FieldConfigArgument{
+						"query":     &graphql.ArgumentConfig{Type: graphql.String},
+						"reportIds": &graphql.ArgumentConfig{Type: graphql.NewList(RequireRole("admin", graphql.ID))},
+					},
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"touch":         &graphql.Field{Type: graphql.Boolean},
+				"purgeReport":   &graphql.Field{Type: adminReport},
+				"previewReport": &graphql.Field{Type: graphql.NewList(betaAdminReport)},
+			},
+		}),
+		Types: []graphql.Type{adminReport},
+	}
+	for _, tc := range []struct {
+		roles    []string
+		beta     bool
+		expected []string
+	}{
+		{nil, false, []string{"Query.id", "Query.search", "Query.search(query:)", "Mutation.touch"}},
+		{nil, true, []string{"Query.id", "Query.search", "Query.search(query:)", "Mutation.touch"}},
+		{[]string{"admin"}, false, []string{"Query.id", "Query.reports", "Query.search", "Query.search(query:)", "Query.search(reportIds:)", "Mutation.touch", "Mutation.purgeReport"}},
+		{[]string{"admin"}, true, []string{"Query.id", "Query.reports", "Query.search", "Query.search(query:)", "Query.search(reportIds:)", "Mutation.touch", "Mutation.purgeReport", "Mutation.previewReport"}},
+	} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{Roles: tc.roles, BetaFeaturesEnabled: tc.beta})
+		if err != nil {
+			t.Fatalf("roles %v, beta %v: %v", tc.roles, tc.beta, err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("roles %v, beta %v: %v", tc.roles, tc.beta, err)
+		}
+		var coordinates []string
+		for _, obj := range []*graphql.Object{result.Query, result.Mutation} {
+			for _, name := range fieldNames(obj.Fields()) {
+				coordinates = append(coordinates, obj.Name()+"."+name)
+				for _, arg := range sortedArgs(obj.Fields()[name].Args) {
+					coordinates = append(coordinates, obj.Name()+"."+name+"("+arg.Name()+":)")
+				}
+			}
+		}
+		sort.Strings(coordinates)
+		expected := append([]string(nil), tc.expected...)
+		sort.Strings(expected)
+		if !reflect.DeepEqual(coordinates, expected) {
+			t.Errorf("roles %v, beta %v: unexpected fields %v", tc.roles, tc.beta, coordinates)
+		}
+		if hasReport := len(result.Types) > 0; hasReport != (len(tc.roles) > 0) {
+			t.Errorf("roles %v, beta %v: unexpected types %v", tc.roles, tc.beta, result.Types)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/kinds"
+	"github.com/graphql-go/graphql/language/visitor"
+)
+
+func reportValidationError(context *graphql.ValidationContext, message string, nodes ...ast.Node) {
+	context.ReportError(gqlerrors.NewError(message, nodes, "", nil, []int{}, nil))
+}
+
+// DepthLimitRule returns a validation rule that rejects operations whose selections are nested
+// more than max levels deep. Fragments are expanded when computing depth.
+func DepthLimitRule(max int) graphql.ValidationRuleFn {
+	return func(context *graphql.ValidationContext) *graphql.ValidationRuleInstance {
+		var depth func(selectionSet *ast.SelectionSet, visited map[string]bool) int
+		depth = func(selectionSet *ast.SelectionSet, visited map[string]bool) int {
+			if selectionSet == nil {
+				return 0
+			}
+			deepest := 0
+			for _, selection := range selectionSet.Selections {
+				d := 0
+				switch selection := selection.(type) {
+				case *ast.Field:
+					d = 1 + depth(selection.SelectionSet, visited)
+				case *ast.InlineFragment:
+					d = depth(selection.SelectionSet, visited)
+				case *ast.FragmentSpread:
+					name := selection.Name.Value
+					if fragment := context.Fragment(name); fragment != nil && !visited[name] {
+						visited[name] = true
+						d = depth(fragment.SelectionSet, visited)
+						delete(visited, name)
+					}
+				}
+				if d > deepest {
+					deepest = d
+				}
+			}
+			return deepest
+		}
+
+		return &graphql.ValidationRuleInstance{
+			VisitorOpts: &visitor.VisitorOptions{
+				KindFuncMap: map[string]visitor.NamedVisitFuncs{
+					kinds.OperationDefinition: {
+						Kind: func(p visitor.VisitFuncParams) (string, interface{}) {
+							if operation, ok := p.Node.(*ast.OperationDefinition); ok && operation != nil {
+								if d := depth(operation.SelectionSet, map[string]bool{}); d > max {
+									reportValidationError(context, fmt.Sprintf("Operation has depth %v, which exceeds the maximum of %v.", d, max), operation)
+								}
+							}
+							return visitor.ActionSkip, nil
+						},
+					},
+				},
+			},
+		}
+	}
+}
+
+// DisableIntrospectionRule is a validation rule that rejects queries for __schema or __type.
+func DisableIntrospectionRule(context *graphql.ValidationContext) *graphql.ValidationRuleInstance {
+	return &graphql.ValidationRuleInstance{
+		VisitorOpts: &visitor.VisitorOptions{
+			KindFuncMap: map[string]visitor.NamedVisitFuncs{
+				kinds.Field: {
+					Kind: func(p visitor.VisitFuncParams) (string, interface{}) {
+						if field, ok := p.Node.(*ast.Field); ok && field != nil && field.Name != nil {
+							if name := field.Name.Value; name == "__schema" || name == "__type" {
+								reportValidationError(context, fmt.Sprintf("Introspection is disabled, so %v cannot be queried.", name), field)
+							}
+						}
+						return visitor.ActionNoChange, nil
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package graphqlapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func rulesTestConfig(internal bool) *PreprocessorConfig {
+	return &PreprocessorConfig{
+		InternalFeaturesEnabled: internal,
+		ValidationRules: func(cfg *PreprocessorConfig) []graphql.ValidationRuleFn {
+			if cfg.InternalFeaturesEnabled {
+				return []graphql.ValidationRuleFn{DepthLimitRule(15)}
+			}
+			return []graphql.ValidationRuleFn{DepthLimitRule(8), DisableIntrospectionRule}
+		},
+	}
+}
+
+func TestValidationRules(t *testing.T) {
+	var node *graphql.Object
+	node = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Node",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+				"child": &graphql.Field{
+					Type: node,
+					Resolve: func(graphql.ResolveParams) (interface{}, error) {
+						return map[string]interface{}{"id": "1"}, nil
+					},
+				},
+			}
+		}),
+	})
+	input := testInput(graphql.Fields{
+		"node": &graphql.Field{
+			Type: node,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1"}, nil
+			},
+		},
+	})
+	// The fragment counts towards the depth of the selection that spreads it.
+	deep := `{ node { child { child { child { child { child { child { ...Leaf } } } } } } } }
+		fragment Leaf on Node { child { child { id } } }`
+
+	for _, internal := range []bool{false, true} {
+		config := rulesTestConfig(internal)
+		preprocessed, err := PreprocessSchemaConfigE(input, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(preprocessed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := Execute(config, graphql.Params{Schema: schema, RequestString: deep})
+		if internal && len(result.Errors) > 0 {
+			t.Errorf("internal: unexpected errors %v", result.Errors)
+		} else if !internal && (len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "depth 10, which exceeds the maximum of 8")) {
+			t.Errorf("public: expected a depth error, got %v", result.Errors)
+		}
+
+		result = Execute(config, graphql.Params{Schema: schema, RequestString: `{ __schema { queryType { name } } }`})
+		if internal && len(result.Errors) > 0 {
+			t.Errorf("internal: unexpected errors %v", result.Errors)
+		} else if !internal && (len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "Introspection is disabled")) {
+			t.Errorf("public: expected an introspection error, got %v", result.Errors)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ErrFieldNotAvailable is returned by fields whose runtime flags are disabled for the request. See
+// RuntimeFlag.
+var ErrFieldNotAvailable = errors.New("this field is not available")
+
+// RuntimeFlag returns a conditional that's always present, but whose fields are guarded at
+// execution time: when the config's RuntimeFlagChecker reports that the named flag is disabled
+// for the request's context, they resolve to ErrFieldNotAvailable instead of invoking their
+// resolvers. Like any resolver error, the error propagates to the nearest nullable parent if the
+// field is non-null. Runtime flags are only honored on field types.
+func RuntimeFlag(name string, ofType graphql.Type) *Conditional {
+	return &Conditional{
+		OfType:      ofType,
+		Condition:   func(*PreprocessorConfig) bool { return true },
+		RuntimeFlag: name,
+		callsite:    callsite(1),
+	}
+}
+
+// runtimeFlags returns the names of the runtime flags in the type or the types it wraps.
+func runtimeFlags(t graphql.Type) (flags []string) {
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.List:
+			t = wrapper.OfType
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		case *Conditional:
+			if wrapper.RuntimeFlag != "" {
+				flags = append(flags, wrapper.RuntimeFlag)
+			}
+			t = wrapper.OfType
+		default:
+			return flags
+		}
+	}
+}
+
+// runtimeFlagGuard wraps the resolver of a field with runtime flags so that it's only invoked if
+// every flag is enabled.
+func (p *preprocessor) runtimeFlagGuard(coordinate string, flags []string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	checker := p.Config.RuntimeFlagChecker
+	if checker == nil {
+		panic(fmt.Errorf("%v uses runtime flag %q, but there's no RuntimeFlagChecker", coordinate, flags[0]))
+	}
+	if resolve == nil {
+		resolve = graphql.DefaultResolveFn
+	}
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		ctx := params.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		for _, flag := range flags {
+			if !checker(ctx, flag) {
+				return nil, ErrFieldNotAvailable
+			}
+		}
+		return resolve(params)
+	}
+}
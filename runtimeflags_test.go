@@ -0,0 +1,113 @@
+package graphqlapi
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+type runtimeFlagsTestKey struct{}
+
+func runtimeFlagsTestInput() graphql.SchemaConfig {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.ID},
+			"price": &graphql.Field{Type: RuntimeFlag("pricing", graphql.NewNonNull(graphql.Float))},
+		},
+	})
+	return testInput(graphql.Fields{
+		"widget": &graphql.Field{
+			Type: widget,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return map[string]interface{}{"id": "1", "price": 2.5}, nil
+			},
+		},
+		"discount": &graphql.Field{
+			Type: RuntimeFlag("pricing", graphql.String),
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "10%", nil
+			},
+		},
+	})
+}
+
+func TestRuntimeFlag(t *testing.T) {
+	result, err := PreprocessSchemaConfigE(runtimeFlagsTestInput(), &PreprocessorConfig{
+		RuntimeFlagChecker: func(ctx context.Context, name string) bool {
+			return name == "pricing" && ctx.Value(runtimeFlagsTestKey{}) == true
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Introspection shows the superset schema regardless of the request's flags.
+	response := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ __type(name: "Widget") { name fields { name } } }`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	expected := map[string]interface{}{
+		"__type": map[string]interface{}{
+			"name": "Widget",
+			"fields": []interface{}{
+				map[string]interface{}{"name": "id"},
+				map[string]interface{}{"name": "price"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(response.Data, expected) {
+		t.Errorf("unexpected introspection %v", response.Data)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		response := graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: `{ widget { id price } discount }`,
+			Context:       context.WithValue(context.Background(), runtimeFlagsTestKey{}, enabled),
+		})
+		if enabled {
+			if len(response.Errors) > 0 {
+				t.Fatal(response.Errors)
+			}
+			expected := map[string]interface{}{
+				"widget":   map[string]interface{}{"id": "1", "price": 2.5},
+				"discount": "10%",
+			}
+			if !reflect.DeepEqual(response.Data, expected) {
+				t.Errorf("unexpected data %v", response.Data)
+			}
+			continue
+		}
+
+		// The non-null price propagates its error to the nullable widget field.
+		expected := map[string]interface{}{"widget": nil, "discount": nil}
+		if !reflect.DeepEqual(response.Data, expected) {
+			t.Errorf("unexpected data %v", response.Data)
+		}
+		if len(response.Errors) != 2 {
+			t.Fatalf("expected two errors, got %v", response.Errors)
+		}
+		for _, err := range response.Errors {
+			if err.Message != ErrFieldNotAvailable.Error() {
+				t.Errorf("unexpected error %v", err)
+			}
+		}
+	}
+}
+
+func TestRuntimeFlagWithoutChecker(t *testing.T) {
+	if _, err := PreprocessSchemaConfigE(runtimeFlagsTestInput(), &PreprocessorConfig{}); err == nil || !strings.Contains(err.Error(), `uses runtime flag "pricing", but there's no RuntimeFlagChecker`) {
+		t.Errorf("expected an error about the missing checker, got %v", err)
+	}
+}
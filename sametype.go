@@ -0,0 +1,114 @@
+package graphqlapi
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+)
+
+// SameType reports whether the resolved field's return type is the given type. Preprocessing
+// creates new type instances, so resolvers must not compare info.ReturnType against original types
+// with ==. Use SameType instead, e.g. SameType(info, myType). Existing comparisons can be found
+// with PreprocessorConfig.OnTypeIdentityDependence.
+func SameType(info graphql.ResolveInfo, t graphql.Type) bool {
+	markTypeIdentityCheck(info)
+	return sameType(info.ReturnType, t)
+}
+
+// SameParentType reports whether the resolved field's parent type is the given type.
+func SameParentType(info graphql.ResolveInfo, t graphql.Type) bool {
+	markTypeIdentityCheck(info)
+	return sameType(info.ParentType, t)
+}
+
+// sameType compares a preprocessed type with an original one. Wrappers must match, and named
+// types are compared by name since names are unique within a schema.
+func sameType(preprocessed, original graphql.Type) bool {
+	for {
+		switch o := original.(type) {
+		case *Conditional:
+			original = o.OfType
+			continue
+		case *FallbackType:
+			return sameType(preprocessed, o.Flagged) || sameType(preprocessed, o.Fallback)
+		}
+		if preprocessed == nil || original == nil {
+			return preprocessed == original
+		}
+		switch p := preprocessed.(type) {
+		case *graphql.List:
+			o, ok := original.(*graphql.List)
+			if !ok {
+				return false
+			}
+			preprocessed, original = p.OfType, o.OfType
+		case *graphql.NonNull:
+			o, ok := original.(*graphql.NonNull)
+			if !ok {
+				return false
+			}
+			preprocessed, original = p.OfType, o.OfType
+		default:
+			switch original.(type) {
+			case *graphql.List, *graphql.NonNull:
+				return false
+			}
+			return preprocessed == original || preprocessed.Name() == original.Name()
+		}
+	}
+}
+
+// typeIdentityChecks tracks the audited resolver invocations, keyed by their *graphql.ResponsePath.
+// SameType and SameParentType mark the invocation's *int32 to show that it doesn't depend on type
+// identity. See auditTypeIdentity.
+var typeIdentityChecks sync.Map
+
+func markTypeIdentityCheck(info graphql.ResolveInfo) {
+	if info.Path == nil {
+		return
+	}
+	if checked, ok := typeIdentityChecks.Load(info.Path); ok {
+		atomic.StoreInt32(checked.(*int32), 1)
+	}
+}
+
+// auditTypeIdentity wraps a resolver so that invocations with a return or parent type that
+// preprocessing replaced, which identity comparisons against the original types can never match,
+// are reported once if they don't consult SameType or SameParentType. The resolver is invoked
+// exactly once per invocation of the wrapper. See OnTypeIdentityDependence.
+func (p *preprocessor) auditTypeIdentity(coordinate string, parentType graphql.Type, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	if p.unauditedRoots[parentType] {
+		return resolve
+	}
+	hook := p.Config.OnTypeIdentityDependence
+	var once sync.Once
+	return func(params graphql.ResolveParams) (interface{}, error) {
+		info := params.Info
+		if info.Path == nil || !p.replacedType(info.ReturnType) && !p.replacedType(info.ParentType) {
+			return resolve(params)
+		}
+		checked := new(int32)
+		typeIdentityChecks.Store(info.Path, checked)
+		defer func() {
+			typeIdentityChecks.Delete(info.Path)
+			if atomic.LoadInt32(checked) == 0 {
+				once.Do(func() {
+					hook(coordinate)
+				})
+			}
+		}()
+		return resolve(params)
+	}
+}
+
+// replacedType reports whether the named type of a preprocessed type differs from its original
+// instance.
+func (p *preprocessor) replacedType(t graphql.Type) bool {
+	named := namedType(t)
+	if named == nil {
+		return false
+	}
+	original, ok := p.originalType(named.Name())
+	return ok && original != named
+}
@@ -0,0 +1,137 @@
+package graphqlapi
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestSameType(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	identicalCalls := 0
+	var query *graphql.Object
+	query = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"identical": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					identicalCalls++
+					return p.Info.ParentType == query, nil
+				},
+			},
+			"same": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return SameParentType(p.Info, query), nil
+				},
+			},
+			"widget": &graphql.Field{
+				Type: Beta(widget),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if !SameType(p.Info, widget) {
+						return nil, nil
+					}
+					return map[string]interface{}{"id": "1"}, nil
+				},
+			},
+		},
+	})
+
+	var reported []string
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{
+		BetaFeaturesEnabled: true,
+		OnTypeIdentityDependence: func(coordinate string) {
+			reported = append(reported, coordinate)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		response := graphql.Do(graphql.Params{
+			Schema:        schema,
+			RequestString: `{ identical same widget { id } }`,
+		})
+		if len(response.Errors) > 0 {
+			t.Fatal(response.Errors)
+		}
+		data := response.Data.(map[string]interface{})
+		if data["identical"] != false {
+			t.Errorf("the identity comparison unexpectedly matched")
+		}
+		if data["same"] != true || data["widget"] == nil {
+			t.Errorf("SameType didn't match: %v", data)
+		}
+	}
+	if identicalCalls != 2 {
+		t.Errorf("the audited resolver was invoked %v times in 2 requests", identicalCalls)
+	}
+	if len(reported) != 1 || reported[0] != "Query.identical" {
+		t.Errorf("unexpected reported coordinates: %v", reported)
+	}
+}
+
+func TestTypeIdentityAuditSkipsMutations(t *testing.T) {
+	calls := 0
+	var mutation *graphql.Object
+	mutation = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"increment": &graphql.Field{
+				Type: graphql.Boolean,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					calls++
+					return p.Info.ParentType == mutation, nil
+				},
+			},
+		},
+	})
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"count": &graphql.Field{
+				Type: graphql.Int,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return calls, nil
+				},
+			},
+		},
+	})
+
+	var reported []string
+	result, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query, Mutation: mutation}, &PreprocessorConfig{
+		OnTypeIdentityDependence: func(coordinate string) {
+			reported = append(reported, coordinate)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := graphql.NewSchema(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `mutation { increment }`,
+	})
+	if len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	if calls != 1 {
+		t.Errorf("the mutation resolver was invoked %v times", calls)
+	}
+	if len(reported) != 0 {
+		t.Errorf("unexpected reported coordinates: %v", reported)
+	}
+}
@@ -0,0 +1,53 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ScalarVariants is a scalar that's preprocessed into one of two concrete scalars with the same
+// name. The embedded scalar is used unless the condition is true.
+type ScalarVariants struct {
+	*graphql.Scalar
+
+	Alternate *graphql.Scalar
+	Condition func(*PreprocessorConfig) bool
+
+	callsite string
+}
+
+// ConditionalScalar returns a scalar that's preprocessed into the alternate scalar if the
+// condition is true and the public one otherwise. Fields using it don't need to fork, since both
+// scalars must have the same name.
+func ConditionalScalar(public, alternate *graphql.Scalar, condition func(*PreprocessorConfig) bool) *ScalarVariants {
+	return &ScalarVariants{
+		Scalar:    public,
+		Alternate: alternate,
+		Condition: condition,
+		callsite:  callsite(1),
+	}
+}
+
+func (s *ScalarVariants) declaration() string {
+	return fmt.Sprintf("conditional scalar %v (declared at %v)", s.Name(), s.callsite)
+}
+
+func (p *preprocessor) preprocessScalarVariants(s *ScalarVariants) *graphql.Scalar {
+	if s.Alternate == nil {
+		panic(fmt.Errorf("%v has no alternate", s.declaration()))
+	}
+	if s.Alternate.Name() != s.Name() {
+		panic(fmt.Errorf("%v has an alternate named %v", s.declaration(), s.Alternate.Name()))
+	}
+	for _, scalar := range []*graphql.Scalar{s.Scalar, s.Alternate} {
+		if err := scalar.Error(); err != nil {
+			panic(fmt.Errorf("%v is invalid: %v", s.declaration(), err))
+		}
+	}
+	if p.evaluateCondition(s.declaration(), s.Condition, "") {
+		p.substituted(s.Name(), "the alternate of "+s.declaration())
+		return s.Alternate
+	}
+	return s.Scalar
+}
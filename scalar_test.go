@@ -0,0 +1,171 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+type scalarTestMoney struct {
+	Cents    int
+	Currency string
+}
+
+func scalarTestMoneyScalar(name string, serialize graphql.SerializeFn) *graphql.Scalar {
+	return graphql.NewScalar(graphql.ScalarConfig{
+		Name:       name,
+		Serialize:  serialize,
+		ParseValue: func(value interface{}) interface{} { return nil },
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			return nil
+		},
+	})
+}
+
+func scalarTestInput(money graphql.Type) graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"price": &graphql.Field{
+			Type: money,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return scalarTestMoney{Cents: 1234, Currency: "USD"}, nil
+			},
+		},
+		"total": &graphql.Field{Type: money},
+	})
+}
+
+func TestConditionalScalar(t *testing.T) {
+	public := scalarTestMoneyScalar("Money", func(value interface{}) interface{} {
+		money := value.(scalarTestMoney)
+		return fmt.Sprintf("%v.%02d %v", money.Cents/100, money.Cents%100, money.Currency)
+	})
+	internal := scalarTestMoneyScalar("Money", func(value interface{}) interface{} {
+		money := value.(scalarTestMoney)
+		return map[string]interface{}{"cents": money.Cents, "currency": money.Currency}
+	})
+	input := scalarTestInput(ConditionalScalar(public, internal, func(cfg *PreprocessorConfig) bool {
+		return cfg.InternalFeaturesEnabled
+	}))
+
+	for internal, expected := range map[bool]interface{}{
+		false: "12.34 USD",
+		true:  map[string]interface{}{"cents": 1234, "currency": "USD"},
+	} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{InternalFeaturesEnabled: internal})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: "{ price }"})
+		if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, map[string]interface{}{"price": expected}) {
+			t.Errorf("internal %v: unexpected response %v", internal, response)
+		}
+
+		sdl, err := SchemaConfigToSDL(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count := strings.Count(sdl, "scalar Money"); count != 1 {
+			t.Errorf("internal %v: Money is declared %v times:\n%v", internal, count, sdl)
+		}
+	}
+}
+
+func TestConditionalScalarValidation(t *testing.T) {
+	public := scalarTestMoneyScalar("Money", func(value interface{}) interface{} { return value })
+	for alternate, expected := range map[*graphql.Scalar]string{
+		nil: "has no alternate",
+		scalarTestMoneyScalar("Amount", func(value interface{}) interface{} { return value }): "has an alternate named Amount",
+	} {
+		_, err := PreprocessSchemaConfigE(scalarTestInput(ConditionalScalar(public, alternate, func(*PreprocessorConfig) bool { return true })), &PreprocessorConfig{})
+		if err == nil || !strings.Contains(err.Error(), "conditional scalar Money (declared at ") || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestScalarOverrides(t *testing.T) {
+	serialize := func(value interface{}) interface{} { return value }
+	broken := graphql.NewScalar(graphql.ScalarConfig{
+		Name:         "UUID",
+		Serialize:    serialize,
+		ParseValue:   serialize,
+		ParseLiteral: func(ast.Value) interface{} { return nil },
+	})
+	fixed := graphql.NewScalar(graphql.ScalarConfig{
+		Name:       "UUID",
+		Serialize:  serialize,
+		ParseValue: serialize,
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			if value, ok := valueAST.(*ast.StringValue); ok {
+				return value.Value
+			}
+			return nil
+		},
+	})
+	input := testInput(graphql.Fields{
+		"echo": &graphql.Field{
+			Type: broken,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: broken},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Args["id"], nil
+			},
+		},
+		"at": &graphql.Field{Type: graphql.DateTime},
+	})
+
+	for name, overrides := range map[string]map[string]*graphql.Scalar{
+		"without overrides": nil,
+		"with overrides":    {"UUID": fixed, "DateTime": graphql.DateTime},
+	} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{ScalarOverrides: overrides})
+		if err != nil {
+			t.Fatal(err)
+		}
+		echo := result.Query.Fields()["echo"]
+		if overrides != nil {
+			if echo.Type != fixed || echo.Args[0].Type != fixed {
+				t.Errorf("%v: the override isn't used everywhere", name)
+			}
+			// Overrides are consulted before the DateTime substitution.
+			if result.Query.Fields()["at"].Type != graphql.DateTime {
+				t.Errorf("%v: DateTime was substituted", name)
+			}
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ echo(id: "abc") }`})
+		if overrides == nil {
+			// The broken scalar fails validation of the literal.
+			if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, `Argument "id" has invalid value "abc"`) {
+				t.Errorf("%v: unexpected response %v", name, response)
+			}
+		} else if len(response.Errors) > 0 || !reflect.DeepEqual(response.Data, map[string]interface{}{"echo": "abc"}) {
+			t.Errorf("%v: unexpected response %v", name, response)
+		}
+	}
+
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{KeepDateTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Query.Fields()["at"].Type != graphql.DateTime {
+		t.Error("DateTime was substituted despite KeepDateTime")
+	}
+
+	_, err = PreprocessSchemaConfigE(input, &PreprocessorConfig{ScalarOverrides: map[string]*graphql.Scalar{"UUID": graphql.String}})
+	if err == nil || !strings.Contains(err.Error(), "the override for scalar UUID is named String") {
+		t.Errorf("expected an error about the override's name, got %v", err)
+	}
+}
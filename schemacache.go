@@ -0,0 +1,252 @@
+package graphqlapi
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// SchemaCache memoizes the schemas built from variants of an input. Schemas are keyed by the
+// config's environment, release stages, API version, roles, flags, and stage suffixes. Other
+// options, such as policies and hooks, should be the same for every call, e.g. by deriving each
+// config from a shared base.
+type SchemaCache struct {
+	input graphql.SchemaConfig
+
+	// If true, schemas are instead keyed by the dependencies their conditions actually read (see
+	// ReadFlags), so configs that only differ in flags, roles, or an API version that the schema
+	// doesn't depend on share a cached schema. The environment and stage suffixes are always part
+	// of the key. This is only safe if every condition reads the config via IsEnabled, Version, and
+	// HasRole. Conditions that read fields such as BetaFeaturesEnabled directly aren't tracked.
+	KeyByDependencies bool
+
+	// If positive, at most MaxEntries schemas are retained, evicting the least recently used.
+	MaxEntries int
+
+	// If positive, the least recently used schemas are evicted while the total estimated Bytes of
+	// the cached schemas exceeds MaxTotalWeight. The schema that was just built is retained even
+	// if it exceeds the limit by itself. See EstimateSchemaWeight.
+	MaxTotalWeight int
+
+	// If given, OnWeightChange is invoked with the total weight of the cached schemas whenever
+	// schemas are added or evicted. It may be invoked concurrently.
+	OnWeightChange func(total SchemaWeight)
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List
+	weight  SchemaWeight
+
+	// building maps the fingerprints of the configs whose schemas are being built to the builds.
+	building map[string]*schemaBuild
+
+	// dependencies holds each distinct set of dependencies read while building a schema.
+	dependencies [][]string
+}
+
+type schemaCacheEntry struct {
+	key    string
+	schema graphql.Schema
+	weight SchemaWeight
+}
+
+type schemaBuild struct {
+	done   chan struct{}
+	schema graphql.Schema
+	err    error
+}
+
+func NewSchemaCache(input graphql.SchemaConfig) *SchemaCache {
+	return &SchemaCache{
+		input:    input,
+		entries:  map[string]*list.Element{},
+		building: map[string]*schemaBuild{},
+	}
+}
+
+// Get returns the schema for the config, building it if it isn't cached. Concurrent calls for the
+// same config wait for a single build. Failed builds aren't cached.
+func (c *SchemaCache) Get(config *PreprocessorConfig) (graphql.Schema, error) {
+	schema, _, err := c.get(config)
+	return schema, err
+}
+
+// Execute is like the package-level Execute, but executes the request against the config's cached
+// schema, building it if necessary. params.Schema is ignored. If the config's AddVariantExtensions
+// option is set, the result's extensions also report whether the schema was cached.
+func (c *SchemaCache) Execute(config *PreprocessorConfig, params graphql.Params) *graphql.Result {
+	start := time.Now()
+	schema, hit, err := c.get(config)
+	if err != nil {
+		return &graphql.Result{
+			Errors: gqlerrors.FormatErrors(err),
+		}
+	}
+	return execute(config, params, func(*ast.Document) graphql.Schema {
+		return schema
+	}, VariantStats{
+		UsedCache: true,
+		CacheHit:  hit,
+		Duration:  time.Since(start),
+	})
+}
+
+// get implements Get, also reporting whether the schema was cached.
+func (c *SchemaCache) get(config *PreprocessorConfig) (schema graphql.Schema, hit bool, err error) {
+	fingerprint := configFingerprint(config)
+	c.mutex.Lock()
+	if element, ok := c.lookup(config, fingerprint); ok {
+		c.lru.MoveToFront(element)
+		c.mutex.Unlock()
+		return element.Value.(*schemaCacheEntry).schema, true, nil
+	}
+	if build, ok := c.building[fingerprint]; ok {
+		c.mutex.Unlock()
+		<-build.done
+		return build.schema, false, build.err
+	}
+	build := &schemaBuild{
+		done: make(chan struct{}),
+	}
+	c.building[fingerprint] = build
+	c.mutex.Unlock()
+
+	defer close(build.done)
+	cfg := *config
+	cfg.readFlags = newReadSet()
+	result, err := PreprocessSchemaConfigE(c.input, &cfg)
+	if err == nil {
+		build.schema, err = graphql.NewSchema(result)
+	}
+	if err != nil {
+		build.err = err
+		c.mutex.Lock()
+		delete(c.building, fingerprint)
+		c.mutex.Unlock()
+		return build.schema, false, build.err
+	}
+
+	weight := EstimateSchemaWeight(build.schema)
+	dependencies := cfg.readFlags.sorted()
+	c.mutex.Lock()
+	delete(c.building, fingerprint)
+	key := fingerprint
+	if c.KeyByDependencies {
+		key = dependencyKey(config, dependencies)
+	}
+	if _, ok := c.entries[key]; ok {
+		// An equivalent config was built concurrently.
+		c.mutex.Unlock()
+		return build.schema, false, nil
+	}
+	if c.KeyByDependencies {
+		c.addDependencies(dependencies)
+	}
+	element := c.lru.PushFront(&schemaCacheEntry{
+		key:    key,
+		schema: build.schema,
+		weight: weight,
+	})
+	c.entries[key] = element
+	c.weight = c.weight.Add(weight)
+	for c.MaxEntries > 0 && c.lru.Len() > c.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+	for e := c.lru.Back(); e != nil && c.MaxTotalWeight > 0 && c.weight.Bytes > c.MaxTotalWeight; {
+		previous := e.Prev()
+		if e != element {
+			c.remove(e)
+		}
+		e = previous
+	}
+	total := c.weight
+	c.mutex.Unlock()
+	c.weightChanged(total)
+	return build.schema, false, nil
+}
+
+// lookup returns the cached schema for the config, if any. The mutex must be held.
+func (c *SchemaCache) lookup(config *PreprocessorConfig, fingerprint string) (*list.Element, bool) {
+	if !c.KeyByDependencies {
+		element, ok := c.entries[fingerprint]
+		return element, ok
+	}
+	for _, dependencies := range c.dependencies {
+		if element, ok := c.entries[dependencyKey(config, dependencies)]; ok {
+			return element, true
+		}
+	}
+	return nil, false
+}
+
+// addDependencies records a set of dependencies that schemas may be keyed by.
+func (c *SchemaCache) addDependencies(dependencies []string) {
+	for _, existing := range c.dependencies {
+		if reflect.DeepEqual(existing, dependencies) {
+			return
+		}
+	}
+	c.dependencies = append(c.dependencies, dependencies)
+}
+
+// dependencyKey describes the config's values for the given dependencies. Conditions are
+// deterministic, so a config with the same values reads the same dependencies and yields the same
+// schema. The environment is always included since it determines flags that are on by default.
+func dependencyKey(config *PreprocessorConfig, dependencies []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "environment\t%v\n", config.Environment)
+	for _, stage := range []string{"alpha", "beta", "experimental", "internal"} {
+		fmt.Fprintf(&b, "suffix:%v\t%v\n", stage, config.stageSuffix(stage))
+	}
+	for _, dependency := range dependencies {
+		switch {
+		case dependency == versionDependency:
+			fmt.Fprintf(&b, "%v\t%v\n", dependency, config.APIVersion)
+		case strings.HasPrefix(dependency, roleDependencyPrefix):
+			fmt.Fprintf(&b, "%v\t%v\n", dependency, config.hasRole(strings.TrimPrefix(dependency, roleDependencyPrefix)))
+		default:
+			enabled, explicit := config.Flags[dependency]
+			stage := false
+			if field, ok := stages[dependency]; ok {
+				stage = *field(config)
+			}
+			fmt.Fprintf(&b, "flag:%v\t%v\t%v\t%v\n", dependency, explicit, enabled, stage)
+		}
+	}
+	return b.String()
+}
+
+// Len returns the number of cached schemas, excluding any that are being built.
+func (c *SchemaCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+// Weight returns the total estimated weight of the cached schemas. Schemas that are being built
+// don't count towards it.
+func (c *SchemaCache) Weight() SchemaWeight {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.weight
+}
+
+func (c *SchemaCache) weightChanged(total SchemaWeight) {
+	if c.OnWeightChange != nil {
+		c.OnWeightChange(total)
+	}
+}
+
+func (c *SchemaCache) remove(element *list.Element) {
+	entry := element.Value.(*schemaCacheEntry)
+	c.lru.Remove(element)
+	delete(c.entries, entry.key)
+	c.weight = c.weight.Sub(entry.weight)
+}
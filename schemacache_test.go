@@ -0,0 +1,203 @@
+package graphqlapi
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func schemaCacheTestInput() graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"id": &graphql.Field{Type: graphql.ID},
+		"a":  &graphql.Field{Type: Flag("a", graphql.String)},
+		"b":  &graphql.Field{Type: Flag("b", graphql.String)},
+	})
+}
+
+func TestSchemaCacheWeight(t *testing.T) {
+	configs := []*PreprocessorConfig{
+		{Flags: map[string]bool{"a": true}},
+		{Flags: map[string]bool{"b": true}},
+		{Flags: map[string]bool{"a": true, "b": true}},
+	}
+
+	cache := NewSchemaCache(schemaCacheTestInput())
+	var reported []SchemaWeight
+	cache.OnWeightChange = func(total SchemaWeight) {
+		reported = append(reported, total)
+	}
+	var weights []SchemaWeight
+	var previous SchemaWeight
+	for _, config := range configs {
+		schema, err := cache.Get(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		weights = append(weights, EstimateSchemaWeight(schema))
+		if total := cache.Weight(); total.Bytes <= previous.Bytes {
+			t.Errorf("the total didn't grow: %v", total)
+		} else {
+			previous = total
+		}
+	}
+	if len(reported) != 3 || reported[2] != cache.Weight() {
+		t.Fatalf("unexpected reported totals: %v", reported)
+	}
+
+	// Only the two most recently used schemas fit.
+	cache = NewSchemaCache(schemaCacheTestInput())
+	cache.MaxTotalWeight = weights[1].Bytes + weights[2].Bytes
+	cache.OnWeightChange = func(total SchemaWeight) {
+		reported = append(reported, total)
+	}
+	for _, config := range configs {
+		if _, err := cache.Get(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("%v schemas are cached", cache.Len())
+	}
+	if total := cache.Weight(); total != weights[1].Add(weights[2]) {
+		t.Errorf("unexpected total after eviction: %v", total)
+	}
+	if total := reported[len(reported)-1]; total != cache.Weight() {
+		t.Errorf("the reported total %v doesn't match %v", total, cache.Weight())
+	}
+
+	// A schema that exceeds the limit by itself is still retained.
+	cache.MaxTotalWeight = 1
+	if _, err := cache.Get(configs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 1 || cache.Weight() != weights[0] {
+		t.Errorf("unexpected cache state: %v schemas weighing %v", cache.Len(), cache.Weight())
+	}
+}
+
+func TestSchemaCacheKeysOnReadFlags(t *testing.T) {
+	var builds int32
+	input := testInput(graphql.Fields{
+		"id": &graphql.Field{Type: graphql.ID},
+		"a": &graphql.Field{
+			Type: &Conditional{
+				OfType: graphql.String,
+				Condition: func(cfg *PreprocessorConfig) bool {
+					atomic.AddInt32(&builds, 1)
+					return cfg.IsEnabled("a")
+				},
+			},
+		},
+	})
+	cache := NewSchemaCache(input)
+	cache.KeyByDependencies = true
+	get := func(config *PreprocessorConfig) graphql.Schema {
+		schema, err := cache.Get(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return schema
+	}
+
+	first := get(&PreprocessorConfig{Flags: map[string]bool{"a": true, "unreferenced": false}})
+	second := get(&PreprocessorConfig{Flags: map[string]bool{"a": true, "unreferenced": true}})
+	if builds != 1 || cache.Len() != 1 || first.QueryType() != second.QueryType() {
+		t.Errorf("configs differing in an unreferenced flag weren't cached together: %v builds", builds)
+	}
+	get(&PreprocessorConfig{Flags: map[string]bool{"a": false, "unreferenced": true}})
+	if builds != 2 || cache.Len() != 2 {
+		t.Errorf("configs differing in a referenced flag were cached together: %v builds", builds)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Get(&PreprocessorConfig{Environment: "staging"})
+		}()
+	}
+	wg.Wait()
+	if builds := atomic.LoadInt32(&builds); builds != 3 {
+		t.Errorf("concurrent calls built %v schemas", builds-2)
+	}
+}
+
+func TestSchemaCacheKeysOnFullConfig(t *testing.T) {
+	input := testInput(graphql.Fields{
+		"id": &graphql.Field{Type: graphql.ID},
+		"secret": &graphql.Field{
+			Type: NewConditional(graphql.String, "Raw", func(cfg *PreprocessorConfig) bool {
+				return cfg.BetaFeaturesEnabled
+			}),
+		},
+	})
+	cache := NewSchemaCache(input)
+	for _, beta := range []bool{false, true} {
+		schema, err := cache.Get(&PreprocessorConfig{BetaFeaturesEnabled: beta})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := schema.QueryType().Fields()["secret"]; ok != beta {
+			t.Errorf("beta %v: Query.secret present: %v", beta, ok)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("configs differing in a field read directly were cached together")
+	}
+}
+
+func TestSchemaCacheMaxEntries(t *testing.T) {
+	cache := NewSchemaCache(schemaCacheTestInput())
+	cache.MaxEntries = 2
+	get := func(flags map[string]bool) graphql.Schema {
+		schema, err := cache.Get(&PreprocessorConfig{Flags: flags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return schema
+	}
+
+	a := get(map[string]bool{"a": true})
+	if again := get(map[string]bool{"a": true}); again.QueryType() != a.QueryType() {
+		t.Error("an equal config didn't return the cached schema")
+	}
+	get(map[string]bool{"b": true})
+	get(map[string]bool{"a": true})
+	get(map[string]bool{"a": true, "b": true})
+	if cache.Len() != 2 {
+		t.Errorf("%v schemas are cached", cache.Len())
+	}
+
+	// The schema for "b" was the least recently used, so it was evicted rather than "a".
+	if again := get(map[string]bool{"a": true}); again.QueryType() != a.QueryType() {
+		t.Error("the most recently used schema was evicted")
+	}
+}
+
+func BenchmarkSchemaCacheGet(b *testing.B) {
+	config := &PreprocessorConfig{Flags: map[string]bool{"a": true}}
+	b.Run("Cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewSchemaCache(schemaCacheTestInput()).Get(config); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Warm", func(b *testing.B) {
+		cache := NewSchemaCache(schemaCacheTestInput())
+		if _, err := cache.Get(config); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := cache.Get(config); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
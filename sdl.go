@@ -0,0 +1,310 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// sdlGate is the condition declared by an @beta or @feature directive. The name refers to a
+// condition in the config's ConditionRegistry, e.g. "beta" or "flag:payments".
+type sdlGate struct {
+	name      string
+	callsite  string
+	condition func(*PreprocessorConfig) bool
+}
+
+func (g sdlGate) conditional(t graphql.Type) *Conditional {
+	stage := strings.TrimPrefix(g.name, "stage:")
+	if _, ok := stages[stage]; ok {
+		return newStage(stage, t, g.callsite)
+	}
+	return &Conditional{
+		OfType:        t,
+		Suffix:        "_" + sdlSuffixRegexp.ReplaceAllString(strings.TrimPrefix(g.name, "flag:"), "_"),
+		ConditionName: g.name,
+		callsite:      g.callsite,
+	}
+}
+
+func (g sdlGate) enumValue(value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return NewConditionalEnumValue(value, g.condition)
+}
+
+// sdlSuffixRegexp matches the characters of condition names that aren't valid in type names.
+var sdlSuffixRegexp = regexp.MustCompile(`[^_a-zA-Z0-9]`)
+
+type sdlConverter struct {
+	source *source.Source
+	gates  map[string]sdlGate
+}
+
+// PreprocessSDL builds a schema config from SDL and preprocesses it. Types, fields, arguments,
+// input fields, and enum values annotated with @beta or @feature(name: "x") are gated like their
+// programmatic equivalents, and the directives are stripped. The name given to @feature refers to
+// a condition in the config's ConditionRegistry, e.g. "flag:payments", and unknown names are
+// errors. Resolvers are left nil, and custom scalars get stub coercion as with FromIntrospection.
+func PreprocessSDL(sdl string, config *PreprocessorConfig) (result graphql.SchemaConfig, err error) {
+	src := source.NewSource(&source.Source{
+		Body: []byte(sdl),
+		Name: "SDL",
+	})
+	document, err := parser.Parse(parser.ParseParams{
+		Source: src,
+	})
+	if err != nil {
+		return graphql.SchemaConfig{}, err
+	}
+	c := &sdlConverter{
+		source: src,
+		gates:  map[string]sdlGate{},
+	}
+	schema, err := c.convert(document)
+	if err != nil {
+		return graphql.SchemaConfig{}, err
+	}
+	if err := c.resolveGates(config.Conditions); err != nil {
+		return graphql.SchemaConfig{}, err
+	}
+	input, err := buildSchemaConfig(schema, c.gates, config.OnWarning)
+	if err != nil {
+		return graphql.SchemaConfig{}, err
+	}
+	return PreprocessSchemaConfigE(input, config)
+}
+
+func (c *sdlConverter) convert(document *ast.Document) (schema *introspectionSchema, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			schema, err = nil, fmt.Errorf("invalid SDL: %v", r)
+		}
+	}()
+
+	schema = &introspectionSchema{}
+	roots := map[string]string{
+		"query":        "Query",
+		"mutation":     "Mutation",
+		"subscription": "Subscription",
+	}
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.SchemaDefinition:
+			for _, operation := range definition.OperationTypes {
+				roots[operation.Operation] = operation.Type.Name.Value
+			}
+		case *ast.ScalarDefinition:
+			c.directives(definition.Name.Value, definition.Directives)
+			schema.Types = append(schema.Types, introspectionType{
+				Kind:        "SCALAR",
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+			})
+		case *ast.ObjectDefinition:
+			c.directives(definition.Name.Value, definition.Directives)
+			t := introspectionType{
+				Kind:        "OBJECT",
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Fields:      c.fields(definition.Name.Value, definition.Fields),
+			}
+			for _, iface := range definition.Interfaces {
+				t.Interfaces = append(t.Interfaces, introspectionTypeRef{Name: iface.Name.Value})
+			}
+			schema.Types = append(schema.Types, t)
+		case *ast.InterfaceDefinition:
+			c.directives(definition.Name.Value, definition.Directives)
+			schema.Types = append(schema.Types, introspectionType{
+				Kind:        "INTERFACE",
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Fields:      c.fields(definition.Name.Value, definition.Fields),
+			})
+		case *ast.UnionDefinition:
+			c.directives(definition.Name.Value, definition.Directives)
+			t := introspectionType{
+				Kind:        "UNION",
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+			}
+			for _, member := range definition.Types {
+				t.PossibleTypes = append(t.PossibleTypes, introspectionTypeRef{Name: member.Name.Value})
+			}
+			schema.Types = append(schema.Types, t)
+		case *ast.EnumDefinition:
+			c.directives(definition.Name.Value, definition.Directives)
+			t := introspectionType{
+				Kind:        "ENUM",
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+			}
+			for _, value := range definition.Values {
+				t.EnumValues = append(t.EnumValues, introspectionEnumValue{
+					Name:              value.Name.Value,
+					Description:       description(value.Description),
+					DeprecationReason: c.directives(definition.Name.Value+"."+value.Name.Value, value.Directives),
+				})
+			}
+			schema.Types = append(schema.Types, t)
+		case *ast.InputObjectDefinition:
+			c.directives(definition.Name.Value, definition.Directives)
+			schema.Types = append(schema.Types, introspectionType{
+				Kind:        "INPUT_OBJECT",
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				InputFields: c.inputValues(definition.Name.Value+".", "", definition.Fields),
+			})
+		case *ast.DirectiveDefinition:
+			// Directive definitions are stripped along with their uses.
+		default:
+			return nil, fmt.Errorf("unsupported definition at %v", c.position(definition))
+		}
+	}
+
+	defined := map[string]bool{}
+	for _, t := range schema.Types {
+		defined[t.Name] = true
+	}
+	for operation, name := range roots {
+		if !defined[name] {
+			continue
+		}
+		ref := &introspectionNamedRef{Name: name}
+		switch operation {
+		case "query":
+			schema.QueryType = ref
+		case "mutation":
+			schema.MutationType = ref
+		case "subscription":
+			schema.SubscriptionType = ref
+		}
+	}
+	if schema.QueryType == nil {
+		return nil, fmt.Errorf("the SDL doesn't define a query type")
+	}
+	return schema, nil
+}
+
+func description(value *ast.StringValue) string {
+	if value == nil {
+		return ""
+	}
+	return value.Value
+}
+
+// resolveGates looks up the conditions named by the gates, failing if any is unknown.
+func (c *sdlConverter) resolveGates(conditions *ConditionRegistry) error {
+	coordinates := make([]string, 0, len(c.gates))
+	for coordinate := range c.gates {
+		coordinates = append(coordinates, coordinate)
+	}
+	sort.Strings(coordinates)
+	for _, coordinate := range coordinates {
+		gate := c.gates[coordinate]
+		condition, ok := conditions.Lookup(gate.name)
+		if !ok {
+			return fmt.Errorf("%v at %v references unknown condition %q", coordinate, gate.callsite, gate.name)
+		}
+		gate.condition = condition
+		c.gates[coordinate] = gate
+	}
+	return nil
+}
+
+func (c *sdlConverter) position(node ast.Node) string {
+	if loc := node.GetLoc(); loc != nil {
+		l := location.GetLocation(c.source, loc.Start)
+		return fmt.Sprintf("%v:%v:%v", c.source.Name, l.Line, l.Column)
+	}
+	return c.source.Name
+}
+
+// directives records any gate on the element at the coordinate and returns its deprecation
+// reason.
+func (c *sdlConverter) directives(coordinate string, directives []*ast.Directive) (deprecationReason string) {
+	for _, directive := range directives {
+		switch directive.Name.Value {
+		case "beta":
+			c.gates[coordinate] = sdlGate{
+				name:     "beta",
+				callsite: c.position(directive),
+			}
+		case "feature":
+			for _, arg := range directive.Arguments {
+				if name, ok := arg.Value.(*ast.StringValue); ok && arg.Name.Value == "name" {
+					c.gates[coordinate] = sdlGate{
+						name:     name.Value,
+						callsite: c.position(directive),
+					}
+				}
+			}
+			if _, ok := c.gates[coordinate]; !ok {
+				panic(fmt.Errorf("@feature at %v has no name", c.position(directive)))
+			}
+		case "deprecated":
+			deprecationReason = graphql.DefaultDeprecationReason
+			for _, arg := range directive.Arguments {
+				if reason, ok := arg.Value.(*ast.StringValue); ok && arg.Name.Value == "reason" {
+					deprecationReason = reason.Value
+				}
+			}
+		}
+	}
+	return deprecationReason
+}
+
+func (c *sdlConverter) fields(parent string, definitions []*ast.FieldDefinition) []introspectionField {
+	var fields []introspectionField
+	for _, definition := range definitions {
+		coordinate := parent + "." + definition.Name.Value
+		fields = append(fields, introspectionField{
+			Name:              definition.Name.Value,
+			Description:       description(definition.Description),
+			Args:              c.inputValues(coordinate+"(", ":)", definition.Arguments),
+			Type:              typeRef(definition.Type),
+			DeprecationReason: c.directives(coordinate, definition.Directives),
+		})
+	}
+	return fields
+}
+
+// inputValues converts arguments or input fields, whose coordinates are the names wrapped in the
+// prefix and suffix.
+func (c *sdlConverter) inputValues(prefix, suffix string, definitions []*ast.InputValueDefinition) []introspectionInputValue {
+	var values []introspectionInputValue
+	for _, definition := range definitions {
+		c.directives(prefix+definition.Name.Value+suffix, definition.Directives)
+		value := introspectionInputValue{
+			Name:        definition.Name.Value,
+			Description: description(definition.Description),
+			Type:        typeRef(definition.Type),
+		}
+		if definition.DefaultValue != nil {
+			literal := fmt.Sprint(printer.Print(definition.DefaultValue))
+			value.DefaultValue = &literal
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func typeRef(t ast.Type) introspectionTypeRef {
+	switch t := t.(type) {
+	case *ast.List:
+		ofType := typeRef(t.Type)
+		return introspectionTypeRef{Kind: "LIST", OfType: &ofType}
+	case *ast.NonNull:
+		ofType := typeRef(t.Type)
+		return introspectionTypeRef{Kind: "NON_NULL", OfType: &ofType}
+	case *ast.Named:
+		return introspectionTypeRef{Name: t.Name.Value}
+	}
+	panic(fmt.Errorf("unknown type %T", t))
+}
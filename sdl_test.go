@@ -0,0 +1,98 @@
+package graphqlapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+const sdlTestInput = `
+type Query {
+	widget: Widget
+	gadget: Gadget
+	price(currency: Currency @feature(name: "flag:payments")): Float @feature(name: "flag:payments")
+}
+
+type Widget {
+	id: ID
+	name: String @beta
+}
+
+type Gadget @beta {
+	id: ID
+}
+
+enum Currency {
+	USD
+	EUR @feature(name: "flag:payments")
+}
+`
+
+func TestPreprocessSDL(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		result, err := PreprocessSDL(sdlTestInput, &PreprocessorConfig{
+			BetaFeaturesEnabled: enabled,
+			Flags:               map[string]bool{"payments": enabled},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("enabled %v: %v", enabled, err)
+		}
+		for _, d := range result.Directives {
+			if d.Name == "beta" || d.Name == "feature" {
+				t.Errorf("enabled %v: the schema has the directive @%v", enabled, d.Name)
+			}
+		}
+		if sdl, err := SchemaConfigToSDL(result); err != nil {
+			t.Fatal(err)
+		} else if strings.Contains(sdl, "@beta") || strings.Contains(sdl, "@feature") {
+			t.Errorf("enabled %v: the directives weren't stripped:\n%v", enabled, sdl)
+		}
+		fields := result.Query.Fields()
+		if _, ok := fields["gadget"]; ok != enabled {
+			t.Errorf("enabled %v: type-level gate: Query.gadget present: %v", enabled, ok)
+		}
+		if _, ok := fields["widget"].Type.(*graphql.Object).Fields()["name"]; ok != enabled {
+			t.Errorf("enabled %v: field-level gate: Widget.name present: %v", enabled, ok)
+		}
+		price, ok := fields["price"]
+		if ok != enabled {
+			t.Errorf("enabled %v: Query.price present: %v", enabled, ok)
+		}
+		if !ok {
+			continue
+		}
+		if len(price.Args) != 1 {
+			t.Fatalf("Query.price has args %v", price.Args)
+		}
+		if values := price.Args[0].Type.(*graphql.Enum).Values(); len(values) != 2 {
+			t.Errorf("Currency has %v values", len(values))
+		}
+	}
+}
+
+func TestPreprocessSDLConditionRegistry(t *testing.T) {
+	conditions := NewConditionRegistry()
+	conditions.Register("payments", func(cfg *PreprocessorConfig) bool {
+		return cfg.IsEnabled("payments")
+	})
+	sdl := strings.Replace(sdlTestInput, "flag:payments", "payments", -1)
+	result, err := PreprocessSDL(sdl, &PreprocessorConfig{
+		Conditions: conditions,
+		Flags:      map[string]bool{"payments": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Query.Fields()["price"]; !ok {
+		t.Errorf("Query.price was removed")
+	}
+
+	_, err = PreprocessSDL(sdl, &PreprocessorConfig{})
+	if err == nil || !strings.Contains(err.Error(), `Currency.EUR at SDL:19:6 references unknown condition "payments"`) {
+		t.Errorf("expected an unknown condition error, got %v", err)
+	}
+}
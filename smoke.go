@@ -0,0 +1,244 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+type SmokeOptions struct {
+	// MaxDepth limits how deeply selections are nested. Composite fields at the maximum depth only
+	// select __typename. If zero, the limit is 5.
+	MaxDepth int
+
+	// MaxFieldsPerDocument limits the number of fields selected by each document. If zero, the
+	// limit is 100.
+	MaxFieldsPerDocument int
+
+	// Values maps type names to GraphQL literals used for required arguments. Built-in scalars,
+	// enums, and input objects get placeholders if they aren't present.
+	Values map[string]string
+
+	// Coordinate patterns (e.g. "Query.expensive*") of fields that must not be queried.
+	SideEffecting []string
+}
+
+type smokeGenerator struct {
+	schema  graphql.Schema
+	opts    SmokeOptions
+	covered map[string]bool
+	budget  int
+}
+
+// GenerateSmokeQueries preprocesses the input and generates query documents that select every
+// field reachable from the query type at least once. Fields whose required arguments have no
+// placeholder value are skipped.
+func GenerateSmokeQueries(input graphql.SchemaConfig, cfg *PreprocessorConfig, opts SmokeOptions) ([]string, error) {
+	schema, err := graphql.NewSchema(PreprocessSchemaConfig(input, cfg))
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = 5
+	}
+	if opts.MaxFieldsPerDocument == 0 {
+		opts.MaxFieldsPerDocument = 100
+	}
+	g := &smokeGenerator{
+		schema:  schema,
+		opts:    opts,
+		covered: map[string]bool{},
+	}
+
+	var documents []string
+	for {
+		g.budget = opts.MaxFieldsPerDocument
+		selection := g.selection(schema.QueryType(), 1)
+		if selection == "" {
+			break
+		}
+		document := fmt.Sprintf("query Smoke%v %v", len(documents)+1, selection)
+		if err := validateSmokeQuery(&schema, document); err != nil {
+			return nil, fmt.Errorf("generated an invalid smoke query: %v\n%v", err, document)
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func validateSmokeQuery(schema *graphql.Schema, document string) error {
+	ast, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{
+			Body: []byte(document),
+			Name: "GraphQL request",
+		}),
+	})
+	if err != nil {
+		return err
+	}
+	if result := graphql.ValidateDocument(schema, ast, graphql.SpecifiedRules); !result.IsValid {
+		return result.Errors[0]
+	}
+	return nil
+}
+
+// selection returns a selection set that covers at least one new field of the type, or an empty
+// string if there's nothing new to cover.
+func (g *smokeGenerator) selection(t graphql.Type, depth int) string {
+	switch t := t.(type) {
+	case *graphql.Object:
+		return g.objectSelection(t, depth)
+	case graphql.Abstract:
+		possibleTypes := g.schema.PossibleTypes(t)
+		sort.Slice(possibleTypes, func(i, j int) bool {
+			return possibleTypes[i].Name() < possibleTypes[j].Name()
+		})
+		for _, obj := range possibleTypes {
+			if selection := g.objectSelection(obj, depth); selection != "" {
+				return "{ __typename ... on " + obj.Name() + " " + selection + " }"
+			}
+		}
+	}
+	return ""
+}
+
+func (g *smokeGenerator) objectSelection(obj *graphql.Object, depth int) string {
+	fields := obj.Fields()
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var selections []string
+	for _, name := range names {
+		if g.budget <= 0 {
+			break
+		}
+		coordinate := obj.Name() + "." + name
+		if g.sideEffecting(coordinate) {
+			continue
+		}
+		def := fields[name]
+		args, ok := g.arguments(def)
+		if !ok {
+			continue
+		}
+		var sub string
+		switch t := namedType(def.Type).(type) {
+		case *graphql.Scalar, *graphql.Enum:
+			if g.covered[coordinate] {
+				continue
+			}
+			g.budget--
+		default:
+			// Reserve this field's share of the budget before descending.
+			g.budget--
+			if depth < g.opts.MaxDepth {
+				sub = g.selection(t, depth+1)
+			}
+			if sub == "" {
+				if g.covered[coordinate] {
+					g.budget++
+					continue
+				}
+				sub = "{ __typename }"
+			}
+			sub = " " + sub
+		}
+		g.covered[coordinate] = true
+		selections = append(selections, name+args+sub)
+	}
+	if len(selections) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(selections, " ") + " }"
+}
+
+func (g *smokeGenerator) sideEffecting(coordinate string) bool {
+	for _, pattern := range g.opts.SideEffecting {
+		if matchCoordinate(pattern, coordinate) {
+			return true
+		}
+	}
+	return false
+}
+
+// arguments returns placeholders for the field's required arguments.
+func (g *smokeGenerator) arguments(def *graphql.FieldDefinition) (string, bool) {
+	var args []string
+	for _, arg := range def.Args {
+		if _, ok := arg.Type.(*graphql.NonNull); !ok || arg.DefaultValue != nil {
+			continue
+		}
+		value, ok := g.placeholder(arg.Type, map[string]bool{})
+		if !ok {
+			return "", false
+		}
+		args = append(args, arg.Name()+": "+value)
+	}
+	if len(args) == 0 {
+		return "", true
+	}
+	sort.Strings(args)
+	return "(" + strings.Join(args, ", ") + ")", true
+}
+
+func (g *smokeGenerator) placeholder(t graphql.Type, building map[string]bool) (string, bool) {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return g.placeholder(t.OfType, building)
+	case *graphql.List:
+		value, ok := g.placeholder(t.OfType, building)
+		return "[" + value + "]", ok
+	}
+	if value, ok := g.opts.Values[t.Name()]; ok {
+		return value, true
+	}
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		switch t.Name() {
+		case "String", "ID":
+			return `"smoke"`, true
+		case "Int":
+			return "1", true
+		case "Float":
+			return "1.5", true
+		case "Boolean":
+			return "true", true
+		}
+	case *graphql.Enum:
+		var names []string
+		for _, value := range t.Values() {
+			names = append(names, value.Name)
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			return names[0], true
+		}
+	case *graphql.InputObject:
+		if building[t.Name()] {
+			return "", false
+		}
+		building[t.Name()] = true
+		defer delete(building, t.Name())
+		var fields []string
+		for name, f := range t.Fields() {
+			if _, ok := f.Type.(*graphql.NonNull); !ok || f.DefaultValue != nil {
+				continue
+			}
+			value, ok := g.placeholder(f.Type, building)
+			if !ok {
+				return "", false
+			}
+			fields = append(fields, name+": "+value)
+		}
+		sort.Strings(fields)
+		return "{" + strings.Join(fields, ", ") + "}", true
+	}
+	return "", false
+}
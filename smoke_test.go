@@ -0,0 +1,148 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func smokeTestInput() graphql.SchemaConfig {
+	var user *graphql.Object
+	node := graphql.NewInterface(graphql.InterfaceConfig{
+		Name: "Node",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		},
+		ResolveType: func(graphql.ResolveTypeParams) *graphql.Object {
+			return user
+		},
+	})
+	user = graphql.NewObject(graphql.ObjectConfig{
+		Name:       "User",
+		Interfaces: []*graphql.Interface{node},
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"name":    &graphql.Field{Type: graphql.String},
+				"friends": &graphql.Field{Type: graphql.NewList(user)},
+				"karma":   BetaField(&graphql.Field{Type: graphql.Int}),
+			}
+		}),
+	})
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"limit": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		},
+	})
+	cursor := graphql.NewScalar(graphql.ScalarConfig{
+		Name:       "Cursor",
+		Serialize:  func(value interface{}) interface{} { return value },
+		ParseValue: func(value interface{}) interface{} { return value },
+		ParseLiteral: func(valueAST ast.Value) interface{} {
+			if value, ok := valueAST.(*ast.StringValue); ok {
+				return value.Value
+			}
+			return nil
+		},
+	})
+	return testInput(graphql.Fields{
+		"node": &graphql.Field{
+			Type: node,
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+		},
+		"users": &graphql.Field{
+			Type: graphql.NewList(user),
+			Args: graphql.FieldConfigArgument{
+				"filter": &graphql.ArgumentConfig{Type: graphql.NewNonNull(filter)},
+				"after":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(cursor)},
+			},
+		},
+		"resetCaches": &graphql.Field{Type: graphql.Boolean},
+	})
+}
+
+// smokeTestCoverage returns the field coordinates selected by the documents, validating each one.
+func smokeTestCoverage(t *testing.T, input graphql.SchemaConfig, cfg *PreprocessorConfig, documents []string) []string {
+	schema, err := graphql.NewSchema(PreprocessSchemaConfig(input, cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	covered := map[string]bool{}
+	for _, document := range documents {
+		if err := validateSmokeQuery(&schema, document); err != nil {
+			t.Errorf("invalid document %v: %v", document, err)
+		}
+		parsed, err := parser.Parse(parser.ParseParams{Source: document})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, coordinates := range responseCoordinates(parsed, &schema) {
+			for _, coordinate := range coordinates {
+				covered[coordinate] = true
+			}
+		}
+	}
+	var result []string
+	for coordinate := range covered {
+		if !strings.HasSuffix(coordinate, ".__typename") {
+			result = append(result, coordinate)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestGenerateSmokeQueries(t *testing.T) {
+	opts := SmokeOptions{
+		Values:        map[string]string{"Cursor": `"c1"`},
+		SideEffecting: []string{"Query.reset*"},
+	}
+	for _, beta := range []bool{false, true} {
+		cfg := &PreprocessorConfig{BetaFeaturesEnabled: beta}
+		documents, err := GenerateSmokeQueries(smokeTestInput(), cfg, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"Query.node", "Query.users", "User.friends", "User.id", "User.name"}
+		if beta {
+			expected = append(expected, "User.karma")
+			sort.Strings(expected)
+		}
+		if covered := smokeTestCoverage(t, smokeTestInput(), cfg, documents); !reflect.DeepEqual(covered, expected) {
+			t.Errorf("beta %v: unexpected coverage %v of %q", beta, covered, documents)
+		}
+		if joined := strings.Join(documents, "\n"); !strings.Contains(joined, `users(after: "c1", filter: {name: "smoke"})`) {
+			t.Errorf("beta %v: missing placeholder arguments in %v", beta, joined)
+		}
+	}
+}
+
+func TestGenerateSmokeQueriesSplitsDocuments(t *testing.T) {
+	cfg := &PreprocessorConfig{}
+	opts := SmokeOptions{
+		MaxDepth:             2,
+		MaxFieldsPerDocument: 2,
+		Values:               map[string]string{"Cursor": `"c1"`},
+		SideEffecting:        []string{"Query.reset*"},
+	}
+	documents, err := GenerateSmokeQueries(smokeTestInput(), cfg, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) < 2 {
+		t.Errorf("expected the queries to be split, got %q", documents)
+	}
+	expected := []string{"Query.node", "Query.users", "User.friends", "User.id", "User.name"}
+	if covered := smokeTestCoverage(t, smokeTestInput(), cfg, documents); !reflect.DeepEqual(covered, expected) {
+		t.Errorf("unexpected coverage %v of %q", covered, documents)
+	}
+}
@@ -0,0 +1,29 @@
+package graphqlapi
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql"
+)
+
+// ErrFeatureDisabled is returned by fields that are soft-disabled. See PreprocessorConfig.SoftDisable.
+var ErrFeatureDisabled = errors.New("this field's feature is disabled")
+
+func resolveFeatureDisabled(p graphql.ResolveParams) (interface{}, error) {
+	return nil, ErrFeatureDisabled
+}
+
+// stripConditionals returns the type with its conditional wrappers removed.
+func stripConditionals(t graphql.Type) graphql.Type {
+	switch t := t.(type) {
+	case *graphql.List:
+		return graphql.NewList(stripConditionals(t.OfType))
+	case *graphql.NonNull:
+		return graphql.NewNonNull(stripConditionals(t.OfType))
+	case *Conditional:
+		return stripConditionals(t.OfType)
+	case *FallbackType:
+		return stripConditionals(t.Flagged)
+	}
+	return t
+}
@@ -0,0 +1,107 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestSoftDisable(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	resolveWidget := func(graphql.ResolveParams) (interface{}, error) {
+		return map[string]interface{}{"id": "w"}, nil
+	}
+	input := testInput(graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.ID,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return "1", nil
+			},
+		},
+		"price": BetaField(&graphql.Field{
+			Type: graphql.Float,
+			Resolve: func(graphql.ResolveParams) (interface{}, error) {
+				return 9.5, nil
+			},
+		}),
+		// Type-level conditionals soft-disable every field returning the type.
+		"widget":  &graphql.Field{Type: Beta(widget), Resolve: resolveWidget},
+		"widgets": &graphql.Field{Type: graphql.NewList(Beta(widget)), Resolve: resolveWidget},
+	})
+	for _, beta := range []bool{false, true} {
+		config := &PreprocessorConfig{BetaFeaturesEnabled: beta, SoftDisable: true}
+		result, err := PreprocessSchemaConfigE(input, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := graphql.NewSchema(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Introspection shows the soft-disabled fields.
+		response := Execute(config, graphql.Params{
+			Schema:        schema,
+			RequestString: `{ __type(name: "Query") { fields { name } } }`,
+		})
+		if data, _ := json.Marshal(response.Data); string(data) != `{"__type":{"fields":[{"name":"id"},{"name":"price"},{"name":"widget"},{"name":"widgets"}]}}` {
+			t.Errorf("beta %v: unexpected introspection %s", beta, data)
+		}
+
+		response = Execute(config, graphql.Params{
+			Schema:        schema,
+			RequestString: `{ id price widget { id } }`,
+		})
+		data, _ := json.Marshal(response.Data)
+		if beta {
+			if len(response.Errors) > 0 || string(data) != `{"id":"1","price":9.5,"widget":{"id":"w"}}` {
+				t.Errorf("unexpected result %s %v", data, response.Errors)
+			}
+			continue
+		}
+		if string(data) != `{"id":"1","price":null,"widget":null}` {
+			t.Errorf("unexpected data %s", data)
+		}
+		paths := map[string]bool{}
+		for _, err := range response.Errors {
+			if err.Message != ErrFeatureDisabled.Error() || len(err.Path) != 1 {
+				t.Errorf("unexpected error %v", err)
+				continue
+			}
+			paths[err.Path[0].(string)] = true
+		}
+		if len(response.Errors) != 2 || !paths["price"] || !paths["widget"] {
+			t.Errorf("unexpected errors %v", response.Errors)
+		}
+	}
+}
+
+func TestSoftDisableKeepsRemovingArguments(t *testing.T) {
+	input := testInput(graphql.Fields{
+		"search": &graphql.Field{
+			Type: graphql.String,
+			Args: graphql.FieldConfigArgument{
+				"query": &graphql.ArgumentConfig{Type: graphql.String},
+				"fuzzy": BetaArg(&graphql.ArgumentConfig{Type: graphql.Boolean}),
+			},
+		},
+	})
+	result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{SoftDisable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdl, err := SchemaConfigToSDL(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sdl, "search(query: String): String") {
+		t.Errorf("unexpected SDL:\n%v", sdl)
+	}
+}
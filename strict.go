@@ -0,0 +1,40 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// checkFieldRemoval fails in strict mode if a field was removed because its type was removed
+// from within a non-null wrapper, e.g. a field of type NonNull(Beta(X)) or [X!]. It must be called
+// right after the field's type is preprocessed, since it uses the decisions made then.
+func (p *preprocessor) checkFieldRemoval(coordinate string) {
+	if p.Config.Strict && p.removedWithinNonNull {
+		panic(fmt.Errorf("the type of %v was removed within a non-null wrapper", coordinate))
+	}
+}
+
+// checkInputRemoval fails in strict mode if a non-null argument or input field was removed,
+// including one whose type is a conditional wrapping a non-null type. Like checkFieldRemoval, it
+// must be called right after the type is preprocessed.
+func (p *preprocessor) checkInputRemoval(coordinate string, t graphql.Type) {
+	if p.Config.Strict && (hasNonNull(t) || p.removedWithinNonNull) {
+		panic(fmt.Errorf("%v is non-null, but was removed", coordinate))
+	}
+}
+
+func hasNonNull(t graphql.Type) bool {
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.NonNull:
+			return true
+		case *graphql.List:
+			t = wrapper.OfType
+		case *Conditional:
+			t = wrapper.OfType
+		default:
+			return false
+		}
+	}
+}
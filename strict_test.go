@@ -0,0 +1,103 @@
+package graphqlapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestStrictNonNullRemovals(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	input := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "WidgetInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"color": &graphql.InputObjectFieldConfig{Type: Beta(graphql.NewNonNull(graphql.String))},
+		},
+	})
+	for name, tc := range map[string]struct {
+		field    *graphql.Field
+		expected string
+	}{
+		"non-null field": {
+			field:    &graphql.Field{Type: graphql.NewNonNull(Beta(widget))},
+			expected: "the type of Query.f was removed within a non-null wrapper",
+		},
+		"list of non-null": {
+			field:    &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(Beta(widget)))},
+			expected: "the type of Query.f was removed within a non-null wrapper",
+		},
+		"non-null argument": {
+			field: &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: Beta(graphql.NewNonNull(graphql.ID))},
+				},
+			},
+			expected: "Query.f(id:) is non-null, but was removed",
+		},
+		"non-null input field": {
+			field: &graphql.Field{
+				Type: graphql.String,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: input},
+				},
+			},
+			expected: "WidgetInput.color is non-null, but was removed",
+		},
+		"nullable field": {
+			field: &graphql.Field{Type: Beta(graphql.NewNonNull(widget))},
+		},
+	} {
+		query := graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"id": &graphql.Field{Type: graphql.ID},
+				"f":  tc.field,
+			},
+		})
+		_, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{Strict: true})
+		if tc.expected == "" {
+			if err != nil {
+				t.Errorf("%v: %v", name, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%v: expected an error containing %q, got %v", name, tc.expected, err)
+		}
+
+		if _, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{}); err != nil {
+			t.Errorf("%v: non-strict: %v", name, err)
+		}
+	}
+}
+
+func TestStrictDoesNotReevaluateConditions(t *testing.T) {
+	evaluations := 0
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+			"f": &graphql.Field{
+				Type: graphql.NewList(&Conditional{
+					OfType: graphql.String,
+					Condition: func(*PreprocessorConfig) bool {
+						evaluations++
+						return false
+					},
+				}),
+			},
+		},
+	})
+	if _, err := PreprocessSchemaConfigE(graphql.SchemaConfig{Query: query}, &PreprocessorConfig{Strict: true}); err != nil {
+		t.Fatal(err)
+	}
+	if evaluations != 1 {
+		t.Errorf("the condition was evaluated %v times", evaluations)
+	}
+}
@@ -0,0 +1,51 @@
+package graphqlapi
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+func sinceVersion(n int) func(*PreprocessorConfig) bool {
+	return func(cfg *PreprocessorConfig) bool {
+		return cfg.Version() >= n
+	}
+}
+
+func untilVersion(n int) func(*PreprocessorConfig) bool {
+	return func(cfg *PreprocessorConfig) bool {
+		return cfg.Version() <= n
+	}
+}
+
+// SinceVersion returns a conditional that's only present if the config's APIVersion is at least n.
+func SinceVersion(n int, t graphql.Type) *Conditional {
+	return &Conditional{
+		OfType:    t,
+		Suffix:    fmt.Sprintf("_since_v%v", n),
+		Condition: sinceVersion(n),
+		callsite:  callsite(1),
+	}
+}
+
+// UntilVersion returns a conditional that's only present if the config's APIVersion is at most n.
+func UntilVersion(n int, t graphql.Type) *Conditional {
+	return &Conditional{
+		OfType:    t,
+		Suffix:    fmt.Sprintf("_until_v%v", n),
+		Condition: untilVersion(n),
+		callsite:  callsite(1),
+	}
+}
+
+// SinceVersionEnum returns an enum value that's only present if the config's APIVersion is at
+// least n.
+func SinceVersionEnum(n int, value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return NewConditionalEnumValue(value, sinceVersion(n))
+}
+
+// UntilVersionEnum returns an enum value that's only present if the config's APIVersion is at most
+// n.
+func UntilVersionEnum(n int, value *graphql.EnumValueConfig) *graphql.EnumValueConfig {
+	return NewConditionalEnumValue(value, untilVersion(n))
+}
@@ -0,0 +1,98 @@
+package graphqlapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+func versionTestInput() graphql.SchemaConfig {
+	return testInput(graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.ID},
+		"newer":    &graphql.Field{Type: SinceVersion(2, graphql.String)},
+		"latest":   &graphql.Field{Type: SinceVersion(3, graphql.String)},
+		"legacy":   &graphql.Field{Type: UntilVersion(1, graphql.String)},
+		"retiring": &graphql.Field{Type: UntilVersion(2, graphql.String)},
+	})
+}
+
+func TestVersionConditionals(t *testing.T) {
+	for version, expected := range map[int][]string{
+		1: {"id", "legacy", "retiring"},
+		2: {"id", "newer", "retiring"},
+		3: {"id", "latest", "newer"},
+	} {
+		result := PreprocessSchemaConfig(versionTestInput(), &PreprocessorConfig{APIVersion: version})
+		if names := fieldNames(result.Query.Fields()); !reflect.DeepEqual(names, expected) {
+			t.Errorf("version %v has fields %v", version, names)
+		}
+	}
+}
+
+func TestReadFlagsIncludesVersion(t *testing.T) {
+	flags := ReadFlags(versionTestInput(), &PreprocessorConfig{APIVersion: 1})
+	if !reflect.DeepEqual(flags, []string{versionDependency}) {
+		t.Errorf("unexpected flags %v", flags)
+	}
+}
+
+func TestVersionConditionalsSharingTypes(t *testing.T) {
+	widget := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Widget",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{Type: graphql.ID},
+		},
+	})
+	status := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Status",
+		Values: graphql.EnumValueConfigMap{
+			"ACTIVE":   &graphql.EnumValueConfig{Value: "active"},
+			"ARCHIVED": SinceVersionEnum(2, &graphql.EnumValueConfig{Value: "archived"}),
+			"DELETED":  UntilVersionEnum(2, &graphql.EnumValueConfig{Value: "deleted"}),
+		},
+	})
+	input := testInput(graphql.Fields{
+		"status":   &graphql.Field{Type: status},
+		"widget":   &graphql.Field{Type: UntilVersion(1, widget)},
+		"widgets":  &graphql.Field{Type: SinceVersion(2, graphql.NewList(widget))},
+		"required": &graphql.Field{Type: SinceVersion(3, graphql.NewNonNull(widget))},
+		"legacy":   &graphql.Field{Type: UntilVersion(2, widget)},
+	})
+	for version, expected := range map[int]struct {
+		fields, values []string
+	}{
+		1: {[]string{"legacy", "status", "widget"}, []string{"ACTIVE", "DELETED"}},
+		2: {[]string{"legacy", "status", "widgets"}, []string{"ACTIVE", "ARCHIVED", "DELETED"}},
+		3: {[]string{"required", "status", "widgets"}, []string{"ACTIVE", "ARCHIVED"}},
+	} {
+		result, err := PreprocessSchemaConfigE(input, &PreprocessorConfig{APIVersion: version})
+		if err != nil {
+			t.Fatalf("version %v: %v", version, err)
+		}
+		if _, err := graphql.NewSchema(result); err != nil {
+			t.Fatalf("version %v: %v", version, err)
+		}
+		fields := result.Query.Fields()
+		if names := fieldNames(fields); !reflect.DeepEqual(names, expected.fields) {
+			t.Errorf("version %v has fields %v", version, names)
+		}
+		var values []string
+		for _, value := range sortedValues(fields["status"].Type.(*graphql.Enum).Values()) {
+			values = append(values, value.Name)
+		}
+		if !reflect.DeepEqual(values, expected.values) {
+			t.Errorf("version %v has values %v", version, values)
+		}
+		// Every conditional wraps the same preprocessed Widget.
+		var widgets []graphql.Type
+		for _, name := range expected.fields {
+			if t := namedType(fields[name].Type); t.Name() == "Widget" {
+				widgets = append(widgets, t)
+			}
+		}
+		if len(widgets) != 2 || widgets[0] != widgets[1] {
+			t.Errorf("version %v: unexpected widget types %v", version, widgets)
+		}
+	}
+}
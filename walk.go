@@ -0,0 +1,194 @@
+package graphqlapi
+
+import (
+	"sort"
+
+	"github.com/graphql-go/graphql"
+)
+
+// schemaTypes returns every named type reachable from the given schema config in discovery order.
+// Fields and arguments are visited in order of name, so the order is stable across runs. Object and
+// interface field thunks are forced along the way.
+func schemaTypes(config graphql.SchemaConfig) []graphql.Type {
+	return walkSchemaTypes(config, nil)
+}
+
+// walkSchemaTypes is like schemaTypes, but if enter is non-nil, it's called with each newly
+// discovered type and the number discovered so far. The function it returns is called once the
+// types the type references have been visited.
+func walkSchemaTypes(config graphql.SchemaConfig, enter func(t graphql.Type, count int) func()) []graphql.Type {
+	var types []graphql.Type
+	seen := map[string]bool{}
+
+	var visit func(t graphql.Type)
+	visitFields := func(defs graphql.FieldDefinitionMap) {
+		for _, name := range fieldNames(defs) {
+			visit(defs[name].Type)
+			for _, arg := range sortedArgs(defs[name].Args) {
+				visit(arg.Type)
+			}
+		}
+	}
+	visit = func(t graphql.Type) {
+		switch t := t.(type) {
+		case nil:
+			return
+		case *graphql.List:
+			visit(t.OfType)
+			return
+		case *graphql.NonNull:
+			visit(t.OfType)
+			return
+		case *Conditional:
+			visit(t.OfType)
+			return
+		case *FallbackType:
+			visit(t.Flagged)
+			visit(t.Fallback)
+			return
+		}
+		if seen[t.Name()] {
+			return
+		}
+		seen[t.Name()] = true
+		types = append(types, t)
+		if enter != nil {
+			defer enter(t, len(types))()
+		}
+		evaluateThunks(t)
+
+		switch t := t.(type) {
+		case *graphql.Object:
+			for _, iface := range t.Interfaces() {
+				visit(iface)
+			}
+			visitFields(t.Fields())
+		case *graphql.Interface:
+			visitFields(t.Fields())
+		case *graphql.Union:
+			for _, obj := range t.Types() {
+				visit(obj)
+			}
+		case *graphql.InputObject:
+			fields := t.Fields()
+			for _, name := range inputFieldNames(fields) {
+				visit(fields[name].Type)
+			}
+		}
+	}
+
+	if config.Query != nil {
+		visit(config.Query)
+	}
+	if config.Mutation != nil {
+		visit(config.Mutation)
+	}
+	if config.Subscription != nil {
+		visit(config.Subscription)
+	}
+	for _, t := range config.Types {
+		visit(t)
+	}
+	return types
+}
+
+// namedType unwraps any List and NonNull wrappers.
+func namedType(t graphql.Type) graphql.Type {
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.List:
+			t = wrapper.OfType
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		default:
+			return t
+		}
+	}
+}
+
+// schemaCoordinates returns the coordinates of every type, field, argument, input field, and enum
+// value reachable from the schema config, mapped to a description of their kind or type.
+func schemaCoordinates(config graphql.SchemaConfig) map[string]string {
+	coordinates := map[string]string{}
+	addFields := func(parent string, fields graphql.FieldDefinitionMap) {
+		for name, def := range fields {
+			coordinates[parent+"."+name] = def.Type.String()
+			for _, arg := range def.Args {
+				coordinates[parent+"."+name+"("+arg.Name()+":)"] = arg.Type.String()
+			}
+		}
+	}
+	for _, t := range schemaTypes(config) {
+		switch t := t.(type) {
+		case *graphql.Object:
+			coordinates[t.Name()] = "object"
+			addFields(t.Name(), t.Fields())
+		case *graphql.Interface:
+			coordinates[t.Name()] = "interface"
+			addFields(t.Name(), t.Fields())
+		case *graphql.Union:
+			coordinates[t.Name()] = "union"
+		case *graphql.InputObject:
+			coordinates[t.Name()] = "input"
+			for name, f := range t.Fields() {
+				coordinates[t.Name()+"."+name] = f.Type.String()
+			}
+		case *graphql.Enum:
+			coordinates[t.Name()] = "enum"
+			for _, value := range t.Values() {
+				coordinates[t.Name()+"."+value.Name] = "enum value"
+			}
+		case *graphql.Scalar, *ScalarVariants:
+			coordinates[t.Name()] = "scalar"
+		}
+	}
+	return coordinates
+}
+
+// fieldNames returns the names of the fields in order. Preprocessing iterates in this order rather
+// than map order so that repeated runs produce identical results.
+func fieldNames(fields graphql.FieldDefinitionMap) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func inputFieldNames(fields graphql.InputObjectFieldMap) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func inputConfigFieldNames(fields graphql.InputObjectConfigFieldMap) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedArgs returns a copy of the arguments ordered by name. graphql-go builds them from maps, so
+// their original order varies between runs.
+func sortedArgs(args []*graphql.Argument) []*graphql.Argument {
+	sorted := append([]*graphql.Argument(nil), args...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name() < sorted[j].Name()
+	})
+	return sorted
+}
+
+// sortedValues is like sortedArgs, but for enum values. Nil values are ordered first.
+func sortedValues(values []*graphql.EnumValueDefinition) []*graphql.EnumValueDefinition {
+	sorted := append([]*graphql.EnumValueDefinition(nil), values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j] != nil && (sorted[i] == nil || sorted[i].Name < sorted[j].Name)
+	})
+	return sorted
+}
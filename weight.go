@@ -0,0 +1,75 @@
+package graphqlapi
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// SchemaWeight is an approximation of a built schema's size.
+type SchemaWeight struct {
+	Types  int
+	Fields int
+
+	// Bytes is a rough estimate of the memory retained by the schema's definitions. It's not
+	// exact, but it grows monotonically with the size of the schema.
+	Bytes int
+}
+
+func (w SchemaWeight) Add(other SchemaWeight) SchemaWeight {
+	return SchemaWeight{
+		Types:  w.Types + other.Types,
+		Fields: w.Fields + other.Fields,
+		Bytes:  w.Bytes + other.Bytes,
+	}
+}
+
+func (w SchemaWeight) Sub(other SchemaWeight) SchemaWeight {
+	return SchemaWeight{
+		Types:  w.Types - other.Types,
+		Fields: w.Fields - other.Fields,
+		Bytes:  w.Bytes - other.Bytes,
+	}
+}
+
+// Approximate per-definition overheads used by EstimateSchemaWeight.
+const (
+	typeWeight      = 512
+	fieldWeight     = 256
+	argumentWeight  = 128
+	enumValueWeight = 96
+)
+
+// EstimateSchemaWeight cheaply estimates the size of a built schema.
+func EstimateSchemaWeight(schema graphql.Schema) SchemaWeight {
+	var w SchemaWeight
+	addField := func(def *graphql.FieldDefinition) {
+		w.Fields++
+		w.Bytes += fieldWeight + len(def.Name) + len(def.Description) + len(def.DeprecationReason)
+		for _, arg := range def.Args {
+			w.Bytes += argumentWeight + len(arg.Name()) + len(arg.Description())
+		}
+	}
+	for name, t := range schema.TypeMap() {
+		w.Types++
+		w.Bytes += typeWeight + len(name) + len(t.Description())
+		switch t := t.(type) {
+		case *graphql.Object:
+			for _, def := range t.Fields() {
+				addField(def)
+			}
+		case *graphql.Interface:
+			for _, def := range t.Fields() {
+				addField(def)
+			}
+		case *graphql.InputObject:
+			for name, f := range t.Fields() {
+				w.Fields++
+				w.Bytes += fieldWeight + len(name) + len(f.Description())
+			}
+		case *graphql.Enum:
+			for _, value := range t.Values() {
+				w.Bytes += enumValueWeight + len(value.Name) + len(value.Description) + len(value.DeprecationReason)
+			}
+		}
+	}
+	return w
+}